@@ -0,0 +1,546 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+	"text/tabwriter"
+
+	tfgenerator "tenant-terraform-generator/tf-generator"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(config *common.Config) int
+	// withoutConfig commands do not need a DuploCloud portal. They are run with a nil config.
+	withoutConfig bool
+}
+
+var commands = []command{
+	{
+		name:        "generate",
+		description: "Generate terraform projects for the tenant. This is the default command.",
+		run:         runGenerate,
+	},
+	{
+		name:        "import",
+		description: "Generate terraform projects and import the resources. Uses import blocks when --generate-import-blocks is set.",
+		run:         runImport,
+	},
+	{
+		name:        "update",
+		description: "Regenerate terraform projects and merge them into the generated code, keeping the changes made to it since the last generation.",
+		run:         runUpdate,
+	},
+	{
+		name:        "list-resources",
+		description: "List the terraform resource addresses which would be generated for the tenant.",
+		run:         runListResources,
+	},
+	{
+		name:        "validate",
+		description: "Validate the configuration and the access to the DuploCloud portal and tenant.",
+		run:         runValidate,
+	},
+	{
+		name:        "diff",
+		description: "Generate into a temporary folder and show differences with the already generated projects.",
+		run:         runDiff,
+	},
+	{
+		name:        "drift",
+		description: "Generate into a temporary folder and report the resources changed in DuploCloud since the code was generated. Exits with 3 on drift.",
+		run:         runDrift,
+	},
+	{
+		name:          "list-generators",
+		description:   "List the registered generators with their project, resource types and dependencies.",
+		run:           runListGenerators,
+		withoutConfig: true,
+	},
+}
+
+func runCommand(args []string) int {
+	name := "generate"
+	if len(args) > 0 {
+		switch {
+		case args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help":
+			printUsage()
+			return 0
+		case !strings.HasPrefix(args[0], "-"):
+			name = args[0]
+			args = args[1:]
+		}
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage()
+		return 2
+	}
+
+	flagSet := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flagValidator := common.FlagValidator{}
+	flagValidator.RegisterFlags(flagSet)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", filepath.Base(os.Args[0]), cmd.name, cmd.description)
+		flagSet.PrintDefaults()
+	}
+	err := flagSet.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flagSet.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n", strings.Join(flagSet.Args(), " "))
+		flagSet.Usage()
+		return 2
+	}
+	if cmd.withoutConfig {
+		return cmd.run(nil)
+	}
+	flagValidator.Collect(flagSet)
+	config, err := flagValidator.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return cmd.run(config)
+}
+
+func printUsage() {
+	out := os.Stderr
+	fmt.Fprintf(out, "Usage: %s <command> [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(out, "  %-16s %s\n", "help", "Show this help.")
+	fmt.Fprintf(out, "\nEvery flag falls back to the env variable shown in its help. Run '%s <command> --help' to list the flags.\n", filepath.Base(os.Args[0]))
+}
+
+func runGenerate(config *common.Config) int {
+	_, code := generateTenants(config)
+	return code
+}
+
+// generateTenants generates every tenant of the run and returns their resolved configs.
+func generateTenants(config *common.Config) ([]*common.Config, int) {
+	client, configs, err := initialize(config)
+	if err != nil {
+		log.Printf("[TRACE] - %s", err)
+		return nil, 1
+	}
+	if config.UpdateMode {
+		err = generateUpdate(configs, client)
+	} else {
+		err = generate(configs, client)
+	}
+	if finishErr := finishClient(config, client); finishErr != nil {
+		log.Printf("[TRACE] - %s", finishErr)
+		if err == nil {
+			err = finishErr
+		}
+	}
+	if err != nil {
+		log.Printf("[TRACE] - %s", err)
+		return nil, 1
+	}
+	return configs, 0
+}
+
+func runImport(config *common.Config) int {
+	if !config.GenerateImportBlocks {
+		config.GenerateTfState = true
+	}
+	return runGenerate(config)
+}
+
+func runUpdate(config *common.Config) int {
+	config.UpdateMode = true
+	if config.GenerateTfState {
+		fmt.Fprintln(os.Stderr, "update can not run terraform import, use --generate-import-blocks instead")
+		return 1
+	}
+	return runGenerate(config)
+}
+
+func runValidate(config *common.Config) int {
+	client, configs, err := initialize(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = finishClient(config, client)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Configuration is valid.\n")
+	for _, tenantConfig := range configs {
+		fmt.Printf("  Tenant      : %s (%s)\n", tenantConfig.TenantName, tenantConfig.TenantId)
+		fmt.Printf("  Plan        : %s (%s)\n", tenantConfig.DuploPlanId, tenantConfig.DuploPlanRegion)
+		fmt.Printf("  AWS account : %s\n", tenantConfig.AccountID)
+		fmt.Printf("  Output      : %s\n", filepath.Join(tenantConfig.TargetDir, tenantConfig.CustomerName, tenantConfig.TenantName))
+	}
+	return 0
+}
+
+func runListResources(config *common.Config) int {
+	tmpDir, err := os.MkdirTemp("", "tf-generator-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	config.TargetDir = tmpDir
+	config.ValidateTf = false
+	config.GenerateTfState = false
+	config.UpdateMode = false
+	config.GenerateImportBlocks = false
+	config.SecretScan = common.SECRET_SCAN_OFF
+
+	configs, code := generateQuietly(config)
+	if code != 0 {
+		return code
+	}
+	for _, tenantConfig := range configs {
+		for _, project := range generatedProjects(tenantConfig) {
+			addresses, err := common.ListResourceAddresses(project.dir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			for _, address := range addresses {
+				fmt.Printf("%s\t%s\n", project.name, address)
+			}
+		}
+	}
+	return 0
+}
+
+// DRIFT_EXIT_CODE tells drift apart from a failure, which exits with 1.
+const DRIFT_EXIT_CODE = 3
+
+// DIFF_EXIT_CODE tells differences found by diff apart from a failure, which exits with 1.
+const DIFF_EXIT_CODE = 3
+
+func runDrift(config *common.Config) int {
+	tmpDir, err := os.MkdirTemp("", "tf-generator-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	targetDir := config.TargetDir
+	config.TargetDir = tmpDir
+	// The live state is compared, not the responses cached on disk by an earlier run.
+	config.APICacheFile = ""
+	config.ValidateTf = false
+	config.GenerateTfState = false
+	config.UpdateMode = false
+	config.GenerateImportBlocks = false
+	// A resource missing because its generator failed would be reported as removed from DuploCloud.
+	config.ContinueOnError = false
+	// The generation is only compared. Redacting still keeps it comparable with redacted code.
+	if config.SecretScan == common.SECRET_SCAN_FAIL {
+		config.SecretScan = common.SECRET_SCAN_REPORT
+	}
+
+	configs, code := generateQuietly(config)
+	if code != 0 {
+		return code
+	}
+	drifts := []tfgenerator.ResourceDrift{}
+	for _, tenantConfig := range configs {
+		for _, project := range generatedProjects(tenantConfig) {
+			rel, err := filepath.Rel(tmpDir, project.dir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			varsRel, err := filepath.Rel(tmpDir, project.varsFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			projectDrifts, err := tfgenerator.DetectDrift(project.name, filepath.Join(targetDir, rel), project.dir, filepath.Join(targetDir, varsRel), project.varsFile)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			drifts = append(drifts, projectDrifts...)
+		}
+	}
+
+	if config.OutputFormat == common.OUTPUT_FORMAT_JSON {
+		content, err := json.MarshalIndent(map[string]interface{}{"drift": len(drifts) > 0, "count": len(drifts), "resources": drifts}, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(content))
+	} else if len(drifts) == 0 {
+		fmt.Println("No drift found.")
+	} else {
+		for _, drift := range drifts {
+			fmt.Println(drift)
+			for _, attribute := range drift.Attributes {
+				fmt.Printf("    %s\n", attribute)
+			}
+		}
+		fmt.Printf("\n%d resource(s) drifted.\n", len(drifts))
+	}
+	if len(drifts) > 0 {
+		return DRIFT_EXIT_CODE
+	}
+	return 0
+}
+
+type generatedProject struct {
+	// name is like dev01/app or admin-infra.
+	name string
+	dir  string
+	// varsFile is the tfvars.json file of the project.
+	varsFile string
+}
+
+// generatedProjects returns the folders of the projects generated for a tenant.
+func generatedProjects(tenantConfig *common.Config) []generatedProject {
+	projects := []generatedProject{}
+	add := func(dir string, skipped bool) {
+		if !skipped {
+			project := filepath.Base(dir)
+			projects = append(projects, generatedProject{name: filepath.Join(tenantConfig.TenantName, project), dir: dir, varsFile: filepath.Join(tenantConfig.ConfigVars, project+".tfvars.json")})
+		}
+	}
+	add(tenantConfig.AdminTenantDir, tenantConfig.SkipAdminTenant)
+	add(tenantConfig.AwsServicesDir, tenantConfig.SkipAwsServices)
+	add(tenantConfig.AppDir, tenantConfig.SkipApp)
+	if !tenantConfig.SkipAdminInfra {
+		projects = append(projects, generatedProject{name: "admin-infra", dir: tenantConfig.AdminInfraDir, varsFile: filepath.Join(tenantConfig.AdminInfraPath, "config", tenantConfig.DuploPlanId, filepath.Base(tenantConfig.AdminInfraDir)+".tfvars.json")})
+	}
+	return projects
+}
+
+func runDiff(config *common.Config) int {
+	tmpDir, err := os.MkdirTemp("", "tf-generator-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+	targetDir := config.TargetDir
+	config.TargetDir = tmpDir
+	// The live state is compared, not the responses cached on disk by an earlier run.
+	config.APICacheFile = ""
+	config.GenerateTfState = false
+	config.UpdateMode = false
+	// The generation is only compared. Redacting still keeps it comparable with redacted code.
+	if config.SecretScan == common.SECRET_SCAN_FAIL {
+		config.SecretScan = common.SECRET_SCAN_REPORT
+	}
+
+	configs, code := generateQuietly(config)
+	if code != 0 {
+		return code
+	}
+	dirs := []string{}
+	for _, tenantConfig := range configs {
+		dirs = append(dirs, filepath.Join(config.CustomerName, tenantConfig.TenantName))
+	}
+	if !config.SkipAdminInfra {
+		dirs = append(dirs, filepath.Join(config.CustomerName, "admin-infra"))
+	}
+	changed := false
+	for _, dir := range dirs {
+		dirChanged, err := diffTrees(filepath.Join(targetDir, dir), filepath.Join(tmpDir, dir), dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		changed = changed || dirChanged
+	}
+	if changed {
+		return DIFF_EXIT_CODE
+	}
+	fmt.Println("No differences found.")
+	return 0
+}
+
+func runListGenerators(config *common.Config) int {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tPROJECT\tENABLED\tDEPENDS ON\tRESOURCE TYPES")
+	for _, g := range tfgenerator.Registry.All() {
+		enabled := "no"
+		if g.EnabledByDefault {
+			enabled = "yes"
+		}
+		if g.S3Backend {
+			enabled += " (backend)"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", g.Name, g.Project, enabled, listOrDash(g.DependsOn), listOrDash(g.ResourceTypes))
+	}
+	writer.Flush()
+	err := tfgenerator.Registry.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func listOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+// generateQuietly runs the generation while sending its console output to stderr, so that
+// stdout only carries the command result.
+func generateQuietly(config *common.Config) ([]*common.Config, int) {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() {
+		os.Stdout = stdout
+	}()
+	return generateTenants(config)
+}
+
+// diffTrees prints the files which differ between two generated folders and reports whether any did.
+func diffTrees(oldDir, newDir, label string) (bool, error) {
+	oldFiles, err := readTree(oldDir)
+	if err != nil {
+		return false, err
+	}
+	newFiles, err := readTree(newDir)
+	if err != nil {
+		return false, err
+	}
+	paths := []string{}
+	for path := range oldFiles {
+		paths = append(paths, path)
+	}
+	for path := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changed := false
+	for _, path := range paths {
+		oldContent, inOld := oldFiles[path]
+		newContent, inNew := newFiles[path]
+		switch {
+		case !inOld:
+			fmt.Printf("+ %s\n", filepath.Join(label, path))
+		case !inNew:
+			fmt.Printf("- %s\n", filepath.Join(label, path))
+		case oldContent != newContent:
+			fmt.Printf("~ %s\n", filepath.Join(label, path))
+			for _, line := range diffLines(strings.Split(oldContent, "\n"), strings.Split(newContent, "\n")) {
+				fmt.Printf("    %s\n", line)
+			}
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// readTree reads all files of a folder keyed by their relative path, Terraform working files are ignored.
+func readTree(root string) (map[string]string, error) {
+	files := map[string]string{}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Terraform files, the generation base and the reports written along the code are not compared.
+		if d.IsDir() {
+			if path != root && (d.Name() == ".terraform" || d.Name() == ".tfgen") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch d.Name() {
+		case ".terraform.lock.hcl", tfgenerator.SECRET_SCAN_REPORT_FILE, tfgenerator.SECRET_SCAN_REPORT_JSON_FILE,
+			common.SECRETS_REPORT_FILE, common.SECRETS_REPORT_JSON_FILE:
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = string(content)
+		return nil
+	})
+	return files, err
+}
+
+// diffLines returns the removed and added lines between two versions of a file, based on
+// their longest common subsequence.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	lines := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+	return lines
+}
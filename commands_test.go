@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"tenant-terraform-generator/duplosdk"
+	"testing"
+)
+
+const testFixtures = "tf-generator/testdata/duplo-api.json"
+
+func runFixtureCommand(t *testing.T, name, fixtures, targetDir string) int {
+	t.Helper()
+	return runCommand([]string{name,
+		"-replay-fixtures", fixtures,
+		"-tenant-name", "dev01",
+		"-customer-name", "acme",
+		"-target-dir", targetDir,
+		"-validate-tf=false",
+	})
+}
+
+// TestDriftFailsOnAPIErrors checks that a resource which can not be read from DuploCloud is not
+// reported as removed.
+func TestDriftFailsOnAPIErrors(t *testing.T) {
+	targetDir := t.TempDir()
+	if code := runFixtureCommand(t, "generate", testFixtures, targetDir); code != 0 {
+		t.Fatalf("generate exited with %d", code)
+	}
+	if code := runFixtureCommand(t, "drift", testFixtures, targetDir); code != 0 {
+		t.Fatalf("drift of an unchanged tenant exited with %d", code)
+	}
+
+	content, err := os.ReadFile(testFixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"/v2/subscriptions/2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01/K8ConfigMapApiV2",
+		"/subscriptions/2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01/GetAllK8Secrets",
+		"/subscriptions/2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01/GetMinions",
+		"/subscriptions/2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01/GetWafInLb/orders-api",
+	} {
+		t.Run(path, func(t *testing.T) {
+			bundle := duplosdk.FixtureBundle{}
+			if err := json.Unmarshal(content, &bundle); err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for i := range bundle.Responses {
+				if strings.TrimLeft(bundle.Responses[i].Path, "/") == strings.TrimLeft(path, "/") {
+					bundle.Responses[i].Status = 500
+					bundle.Responses[i].Body = nil
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("no fixture response for %s", path)
+			}
+			broken, err := json.Marshal(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixtures := filepath.Join(t.TempDir(), "duplo-api.json")
+			if err := os.WriteFile(fixtures, broken, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if code := runFixtureCommand(t, "drift", fixtures, targetDir); code != 1 {
+				t.Errorf("drift exited with %d, want 1", code)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package duplosdk
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -15,6 +16,7 @@ type clientError struct {
 	status   int
 	url      string
 	response map[string]interface{}
+	attempts int
 }
 
 func (e clientError) Error() string {
@@ -37,12 +39,31 @@ func (e clientError) Response() map[string]interface{} {
 	return e.response
 }
 
+func (e clientError) Attempts() int {
+	return e.attempts
+}
+
 type ClientError interface {
 	Error() string
 	Status() int
 	PossibleMissingAPI() bool
 	URL() string
 	Response() map[string]interface{}
+	// Attempts is the number of times the request was sent. It is zero when it was never sent.
+	Attempts() int
+}
+
+// withAttempts records how many times a failed request was sent.
+func withAttempts(err ClientError, attempts int) ClientError {
+	e, ok := err.(clientError)
+	if !ok {
+		return err
+	}
+	e.attempts = attempts
+	if attempts > 1 {
+		e.message = fmt.Sprintf("%s (after %d attempts)", e.message, attempts)
+	}
+	return e
 }
 
 func newHttpError(req *http.Request, status int, message string) ClientError {
@@ -104,7 +125,11 @@ func responseHttpError(req *http.Request, res *http.Response) ClientError {
 	message = fmt.Sprintf("url: %s, status: %d, message: %s", url, status, message)
 	log.Printf("[TRACE] duplo-responseHttpError: %s", message)
 
-	// Handle responses that are missing a message - or a JSON parse failure
+	// Handle responses that are missing a message - or a JSON parse failure. A "null" body leaves
+	// no map at all.
+	if response == nil {
+		response = map[string]interface{}{}
+	}
 	if _, ok := response["Message"]; !ok {
 		response["Message"] = message
 	}
@@ -117,6 +142,11 @@ type Client struct {
 	HTTPClient *http.Client
 	HostURL    string
 	Token      string
+	Retry      RetryPolicy
+	// Limiter spaces out the requests. Nil means no limit.
+	Limiter *RateLimiter
+	// Cache memoizes the GET responses. Nil means every read is sent to the portal.
+	Cache *ResponseCache
 }
 
 // NewClient creates a new Duplo API client
@@ -127,6 +157,7 @@ func NewClient(host, token string) (*Client, error) {
 			HTTPClient: &http.Client{Timeout: 20 * time.Second},
 			HostURL:    host,
 			Token:      tokenBearer,
+			Retry:      DefaultRetryPolicy,
 		}
 		return &c, nil
 	}
@@ -137,16 +168,38 @@ func (c *Client) doRequestWithStatus(req *http.Request, expectedStatus int) ([]b
 	req.Header.Set("Authorization", c.Token)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	res, err := c.HTTPClient.Do(req)
+	attempts := c.Retry.attempts(req.Method)
+	var res *http.Response
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		c.Limiter.Wait()
+		res, err = c.HTTPClient.Do(req)
+		if attempt >= attempts {
+			break
+		}
+		delay, retry := c.Retry.retryDelay(attempt, res, err)
+		if !retry {
+			break
+		}
+		if err != nil {
+			log.Printf("[TRACE] duplo-doRequest: %s %s attempt %d failed: %s, retrying in %s", req.Method, req.URL, attempt, err, delay)
+		} else {
+			log.Printf("[TRACE] duplo-doRequest: %s %s attempt %d got status %d, retrying in %s", req.Method, req.URL, attempt, res.StatusCode, delay)
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		time.Sleep(delay)
+	}
 
 	// Handle I/O errors
 	if err != nil {
-		return nil, ioHttpError(req, err)
+		return nil, withAttempts(ioHttpError(req, err), attempt)
 	}
 
 	// Pass through HTTP errors, unexpected redirects, or unexpected status codes.
 	if res.StatusCode > 300 || (expectedStatus > 0 && expectedStatus != res.StatusCode) {
-		return nil, responseHttpError(req, res)
+		return nil, withAttempts(responseHttpError(req, res), attempt)
 	}
 
 	// Othterwise, we have a response that needs reading.
@@ -187,8 +240,14 @@ func (c *Client) doAPI(verb string, apiName string, apiPath string, rp interface
 		return nil
 	}
 
-	// Call the API and get the response.
-	body, httpErr := c.doRequest(req)
+	// Call the API and get the response. Reads are served from the cache when there is one.
+	var body []byte
+	var httpErr ClientError
+	if verb == "GET" && c.Cache != nil {
+		body, httpErr = c.Cache.get(apiPath, func() ([]byte, ClientError) { return c.doRequest(req) })
+	} else {
+		body, httpErr = c.doRequest(req)
+	}
 	if httpErr != nil {
 		log.Printf("[TRACE] %s: failed: %s", apiName, httpErr.Error())
 		return httpErr
@@ -0,0 +1,172 @@
+package duplosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+const FIXTURE_BUNDLE_VERSION = 1
+
+// FixtureBundle holds canned responses of the Duplo API, keyed by method and request path.
+type FixtureBundle struct {
+	Version   int               `json:"version"`
+	Responses []FixtureResponse `json:"responses"`
+}
+
+type FixtureResponse struct {
+	Method string `json:"method"`
+	// Path is the request path along with the query, without the host.
+	Path string `json:"path"`
+	// Request is the body of the request, set for the APIs which are read with a POST. A response
+	// without a request body is served for any body.
+	Request json.RawMessage `json:"request,omitempty"`
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// LoadFixtureBundle reads a fixture bundle written as json.
+func LoadFixtureBundle(path string) (*FixtureBundle, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture bundle %s: %s", path, err)
+	}
+	bundle := &FixtureBundle{}
+	err = json.Unmarshal(content, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fixture bundle %s: %s", path, err)
+	}
+	if bundle.Version != FIXTURE_BUNDLE_VERSION {
+		return nil, fmt.Errorf("fixture bundle %s has version %d, expected %d", path, bundle.Version, FIXTURE_BUNDLE_VERSION)
+	}
+	return bundle, nil
+}
+
+// Write stores the bundle as indented json. Responses are sorted so that the file diffs well.
+func (b *FixtureBundle) Write(path string) error {
+	sort.SliceStable(b.Responses, func(i, j int) bool {
+		if b.Responses[i].Path != b.Responses[j].Path {
+			return b.Responses[i].Path < b.Responses[j].Path
+		}
+		if b.Responses[i].Method != b.Responses[j].Method {
+			return b.Responses[i].Method < b.Responses[j].Method
+		}
+		return string(b.Responses[i].Request) < string(b.Responses[j].Request)
+	})
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// FixtureTransport serves Duplo API requests from a fixture bundle instead of the network. It can
+// be plugged into Client.HTTPClient. Requests without a fixture get a 404 and are recorded as missing.
+type FixtureTransport struct {
+	responses map[string][]FixtureResponse
+	mutex     sync.Mutex
+	missing   map[string]bool
+}
+
+func NewFixtureTransport(bundle *FixtureBundle) *FixtureTransport {
+	transport := &FixtureTransport{
+		responses: map[string][]FixtureResponse{},
+		missing:   map[string]bool{},
+	}
+	for _, response := range bundle.Responses {
+		key := fixtureKey(response.Method, response.Path)
+		transport.responses[key] = append(transport.responses[key], response)
+	}
+	return transport
+}
+
+func fixtureKey(method, path string) string {
+	return method + " " + path
+}
+
+// canonicalJSON makes request bodies comparable whatever their spacing and key order. A body which
+// is not json is kept as it is.
+func canonicalJSON(body []byte) string {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return string(body)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(content)
+}
+
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := fixtureKey(req.Method, req.URL.RequestURI())
+	requestBody := ""
+	if req.Body != nil {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		requestBody = canonicalJSON(content)
+	}
+	response, ok := t.find(key, requestBody)
+	if !ok {
+		log.Printf("[TRACE] duplo-fixture: no fixture for %s", key)
+		t.mutex.Lock()
+		t.missing[key] = true
+		t.mutex.Unlock()
+		response = FixtureResponse{Status: http.StatusNotFound, Body: json.RawMessage(fmt.Sprintf("%q", "no fixture for "+key))}
+	}
+	body := response.Body
+	if body == nil {
+		body = json.RawMessage("null")
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// find prefers the response recorded for the same request body over one without a body.
+func (t *FixtureTransport) find(key, requestBody string) (FixtureResponse, bool) {
+	var fallback *FixtureResponse
+	for i, response := range t.responses[key] {
+		if len(response.Request) == 0 {
+			if fallback == nil {
+				fallback = &t.responses[key][i]
+			}
+		} else if canonicalJSON(response.Request) == requestBody {
+			return response, true
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return FixtureResponse{}, false
+}
+
+// Missing returns the sorted requests which had no fixture.
+func (t *FixtureTransport) Missing() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	missing := []string{}
+	for key := range t.missing {
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,219 @@
+package duplosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const REDACTED_FIXTURE_VALUE = "REDACTED"
+
+// DefaultRedactedFixtureKeys are the response fields holding credentials or secret values. Every
+// string below them is redacted from recorded fixtures.
+var DefaultRedactedFixtureKeys = []string{
+	"Password",
+	"MasterPassword",
+	"AuthToken",
+	"Token",
+	"SessionToken",
+	"AccessKeyId",
+	"SecretAccessKey",
+	"Privatekey",
+	"SecretData",
+	"ClientSecret",
+	// The environment variables of lambda functions.
+	"Variables",
+}
+
+// fixtureEnvKeys are the fields holding a list of environment variables with a Name and a Value.
+// Only the values are redacted, so the names still show in fixtures.
+var fixtureEnvKeys = map[string]bool{"env": true, "environment": true}
+
+// fixtureEmbeddedKeys are the string fields holding a json document, such as the docker config of
+// a service. The document is redacted like a body.
+var fixtureEmbeddedKeys = map[string]bool{"otherdockerconfig": true}
+
+// FixtureRecorder records the responses of the Duplo API into a fixture bundle while passing the
+// requests on to Transport. The bundle can be replayed with FixtureTransport. GET requests are
+// recorded along with POST requests since some Duplo APIs are read with a POST.
+type FixtureRecorder struct {
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// RedactKeys are matched case insensitively against the json fields of the bodies.
+	RedactKeys []string
+	mutex      sync.Mutex
+	responses  map[string]FixtureResponse
+}
+
+func NewFixtureRecorder(transport http.RoundTripper) *FixtureRecorder {
+	return &FixtureRecorder{
+		Transport:  transport,
+		RedactKeys: DefaultRedactedFixtureKeys,
+		responses:  map[string]FixtureResponse{},
+	}
+}
+
+func (r *FixtureRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		return transport.RoundTrip(req)
+	}
+	requestBody := []byte{}
+	if req.Body != nil {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		requestBody = content
+		req.Body = io.NopCloser(bytes.NewReader(content))
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	response := FixtureResponse{
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Status: res.StatusCode,
+		Body:   RedactFixtureBody(body, r.RedactKeys),
+	}
+	if len(requestBody) > 0 {
+		response.Request = RedactFixtureBody(requestBody, r.RedactKeys)
+	}
+	r.mutex.Lock()
+	r.responses[fixtureKey(response.Method, response.Path)+" "+canonicalJSON(response.Request)] = response
+	r.mutex.Unlock()
+	return res, nil
+}
+
+// Bundle returns the responses recorded so far.
+func (r *FixtureRecorder) Bundle() *FixtureBundle {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	bundle := &FixtureBundle{Version: FIXTURE_BUNDLE_VERSION, Responses: []FixtureResponse{}}
+	for _, response := range r.responses {
+		bundle.Responses = append(bundle.Responses, response)
+	}
+	return bundle
+}
+
+// Write stores the recorded responses as a fixture bundle.
+func (r *FixtureRecorder) Write(path string) error {
+	bundle := r.Bundle()
+	err := bundle.Write(path)
+	if err != nil {
+		return err
+	}
+	log.Printf("[TRACE] duplo-fixture: recorded %d responses into %s", len(bundle.Responses), path)
+	return nil
+}
+
+// RedactFixtureBody replaces the strings below the redacted keys of a json body, along with the
+// value of SecureString SSM parameters and of environment variables. A body which is not json is
+// stored as a json string.
+func RedactFixtureBody(body []byte, keys []string) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		content, _ := json.Marshal(string(body))
+		return content
+	}
+	redactKeys := map[string]bool{}
+	for _, key := range keys {
+		redactKeys[strings.ToLower(key)] = true
+	}
+	content, err := json.Marshal(redactValue(value, redactKeys, false))
+	if err != nil {
+		return json.RawMessage(body)
+	}
+	return content
+}
+
+func redactValue(value interface{}, keys map[string]bool, redact bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		secureString := v["Type"] == "SecureString"
+		for key, item := range v {
+			lower := strings.ToLower(key)
+			switch {
+			case fixtureEnvKeys[lower]:
+				v[key] = redactEnv(item, keys, redact)
+			case fixtureEmbeddedKeys[lower]:
+				v[key] = redactEmbedded(item, keys, redact)
+			default:
+				v[key] = redactValue(item, keys, redact || keys[lower] || (secureString && key == "Value"))
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, keys, redact)
+		}
+		return v
+	case string:
+		if redact && len(v) > 0 {
+			return REDACTED_FIXTURE_VALUE
+		}
+	}
+	return value
+}
+
+// redactEnv redacts the values of a list of environment variables. Other environment fields, such
+// as the one of lambda functions, are redacted as usual.
+func redactEnv(value interface{}, keys map[string]bool, redact bool) interface{} {
+	list, ok := value.([]interface{})
+	if !ok {
+		return redactValue(value, keys, redact)
+	}
+	for i, item := range list {
+		variable, ok := item.(map[string]interface{})
+		if !ok {
+			list[i] = redactValue(item, keys, redact)
+			continue
+		}
+		for key, field := range variable {
+			variable[key] = redactValue(field, keys, redact || strings.EqualFold(key, "Value"))
+		}
+	}
+	return list
+}
+
+// redactEmbedded redacts a string holding a json document and writes it back as a string. A string
+// which is not json is redacted entirely.
+func redactEmbedded(value interface{}, keys map[string]bool, redact bool) interface{} {
+	text, ok := value.(string)
+	if !ok || redact {
+		return redactValue(value, keys, redact)
+	}
+	var document interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return redactValue(value, keys, true)
+	}
+	content, err := json.Marshal(redactValue(document, keys, false))
+	if err != nil {
+		return redactValue(value, keys, true)
+	}
+	return string(content)
+}
@@ -0,0 +1,100 @@
+package duplosdk
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const recorderTenantId = "2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01"
+
+// recorderPortal serves a few Duplo APIs. Task definitions are looked up with a POST body.
+func recorderPortal(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscriptions/"+recorderTenantId+"/GetAllK8Secrets", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"SecretName":"orders-db","SecretType":"Opaque","SecretData":{"password":"s3cr3t","nested":{"key":"value"}}}]`)
+	})
+	mux.HandleFunc("/v3/subscriptions/"+recorderTenantId+"/aws/ssmParameter/db-password", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Name":"db-password","Type":"SecureString","Value":"s3cr3t"}`)
+	})
+	mux.HandleFunc("/v3/subscriptions/"+recorderTenantId+"/aws/ssmParameter/db-host", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Name":"db-host","Type":"String","Value":"orders-db.internal"}`)
+	})
+	mux.HandleFunc("/v2/subscriptions/"+recorderTenantId+"/FindEcsTaskDefinition", func(w http.ResponseWriter, r *http.Request) {
+		rq := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&rq)
+		family := rq["Arn"][strings.LastIndex(rq["Arn"], "/")+1:]
+		json.NewEncoder(w).Encode(map[string]string{"Family": family, "Cpu": "256"})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFixtureRecorderReplay(t *testing.T) {
+	portal := recorderPortal(t)
+	defer portal.Close()
+
+	client, err := NewClient(portal.URL, "live-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewFixtureRecorder(nil)
+	client.HTTPClient.Transport = recorder
+	live := readRecorderApis(t, client)
+
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	err = recorder.Write(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := LoadFixtureBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayClient, err := NewClient("https://replay.duplocloud.invalid", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := NewFixtureTransport(bundle)
+	replayClient.HTTPClient.Transport = transport
+	replayed := readRecorderApis(t, replayClient)
+	if len(transport.Missing()) > 0 {
+		t.Errorf("replay missed %v", transport.Missing())
+	}
+
+	expected := live
+	expected["secret"] = `{"SecretName":"orders-db","SecretType":"Opaque","SecretData":{"nested":{"key":"REDACTED"},"password":"REDACTED"}}`
+	expected["secure-string"] = `{"Name":"db-password","Type":"SecureString","Value":"REDACTED","Description":""}`
+	for name, value := range expected {
+		if replayed[name] != value {
+			t.Errorf("%s replayed as %s, expected %s", name, replayed[name], value)
+		}
+	}
+}
+
+func readRecorderApis(t *testing.T, client *Client) map[string]string {
+	t.Helper()
+	results := map[string]string{}
+	set := func(name string, value interface{}, err ClientError) {
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		content, _ := json.Marshal(value)
+		results[name] = string(content)
+	}
+	secrets, err := client.K8SecretGetList(recorderTenantId)
+	set("secret", (*secrets)[0], err)
+	secureString, err := client.SsmParameterGet(recorderTenantId, "db-password")
+	set("secure-string", secureString, err)
+	str, err := client.SsmParameterGet(recorderTenantId, "db-host")
+	set("string", str, err)
+	orders, err := client.EcsTaskDefinitionGet(recorderTenantId, "arn:aws:ecs:us-west-2:123456789012:task-definition/orders:1")
+	set("orders", orders.Family, err)
+	reports, err := client.EcsTaskDefinitionGet(recorderTenantId, "arn:aws:ecs:us-west-2:123456789012:task-definition/reports:3")
+	set("reports", reports.Family, err)
+	return results
+}
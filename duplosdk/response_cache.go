@@ -0,0 +1,170 @@
+package duplosdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const RESPONSE_CACHE_VERSION = 1
+
+// ResponseCache memoizes the GET responses of a run keyed by API path. It is safe for concurrent
+// use. Concurrent reads of a path share a single request. Failed requests are not cached.
+type ResponseCache struct {
+	mutex     sync.Mutex
+	responses map[string][]byte
+	inflight  map[string]*cacheCall
+	hits      int
+	requests  int
+	// created is kept when the cache is saved again so that a reused cache still expires.
+	created time.Time
+	// host is the portal the responses were read from. API paths are the same on every portal.
+	host string
+}
+
+// cacheCall is a request in flight. The callers waiting for it read the result once done is closed.
+type cacheCall struct {
+	done chan struct{}
+	body []byte
+	err  ClientError
+}
+
+// responseCacheFile is the on disk format of a ResponseCache.
+type responseCacheFile struct {
+	Version   int                        `json:"version"`
+	Created   time.Time                  `json:"created"`
+	Host      string                     `json:"host"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{
+		responses: map[string][]byte{},
+		inflight:  map[string]*cacheCall{},
+		created:   time.Now().UTC(),
+	}
+}
+
+// LoadResponseCache reads a cache saved by Save for the portal at host. A missing file, one saved
+// for another portal or one older than maxAge gives an empty cache. A maxAge of zero or less never
+// expires.
+func LoadResponseCache(path string, host string, maxAge time.Duration) (*ResponseCache, error) {
+	cache := NewResponseCache()
+	cache.host = host
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := responseCacheFile{}
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response cache %s: %s", path, err)
+	}
+	if file.Version != RESPONSE_CACHE_VERSION {
+		log.Printf("[TRACE] duplo-cache: ignoring %s with version %d, expected %d", path, file.Version, RESPONSE_CACHE_VERSION)
+		return cache, nil
+	}
+	if file.Host != host {
+		log.Printf("[TRACE] duplo-cache: ignoring %s saved for %s, expected %s", path, file.Host, host)
+		return cache, nil
+	}
+	if maxAge > 0 && time.Since(file.Created) > maxAge {
+		log.Printf("[TRACE] duplo-cache: ignoring %s created at %s, older than %s", path, file.Created.Format(time.RFC3339), maxAge)
+		return cache, nil
+	}
+	cache.created = file.Created
+	for key, body := range file.Responses {
+		cache.responses[key] = body
+	}
+	log.Printf("[TRACE] duplo-cache: loaded %d responses from %s", len(cache.responses), path)
+	return cache, nil
+}
+
+// Save writes the cached responses to a file readable only by the owner. The responses hold secret
+// values as returned by the portal.
+func (c *ResponseCache) Save(path string) error {
+	c.mutex.Lock()
+	file := responseCacheFile{
+		Version:   RESPONSE_CACHE_VERSION,
+		Created:   c.created,
+		Host:      c.host,
+		Responses: map[string]json.RawMessage{},
+	}
+	for key, body := range c.responses {
+		// Only json bodies can be stored as they are. Others are fetched again by the next run.
+		if json.Valid(body) {
+			file.Responses[key] = body
+		}
+	}
+	c.mutex.Unlock()
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return err
+	}
+	log.Printf("[TRACE] duplo-cache: saved %d responses into %s", len(file.Responses), path)
+	return nil
+}
+
+// Stats returns the number of reads served from the cache and the number of requests sent.
+func (c *ResponseCache) Stats() (int, int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.requests
+}
+
+// get returns the cached response of a path, or calls fetch once for all the concurrent callers.
+func (c *ResponseCache) get(apiPath string, fetch func() ([]byte, ClientError)) ([]byte, ClientError) {
+	key := responseCacheKey(apiPath)
+	c.mutex.Lock()
+	if body, ok := c.responses[key]; ok {
+		c.hits++
+		c.mutex.Unlock()
+		return body, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.hits++
+		c.mutex.Unlock()
+		<-call.done
+		return call.body, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.requests++
+	c.mutex.Unlock()
+
+	// The callers waiting for the response are released even when fetch panics, as the generators
+	// recover from panics and go on.
+	fetched := false
+	defer func() {
+		if !fetched {
+			call.err = newClientError(fmt.Sprintf("request for %s failed", apiPath))
+		}
+		c.mutex.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.responses[key] = call.body
+		}
+		c.mutex.Unlock()
+		close(call.done)
+	}()
+	call.body, call.err = fetch()
+	fetched = true
+	return call.body, call.err
+}
+
+// responseCacheKey drops the leading slash some API paths are written with.
+func responseCacheKey(apiPath string) string {
+	return strings.TrimLeft(apiPath, "/")
+}
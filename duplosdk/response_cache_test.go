@@ -0,0 +1,225 @@
+package duplosdk
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func cachedClient(t *testing.T, url string) *Client {
+	t.Helper()
+	client := retryClient(t, url)
+	client.Cache = NewResponseCache()
+	return client
+}
+
+func TestResponseCacheServesRepeatedReads(t *testing.T) {
+	portal, calls := flakyPortal(t)
+	defer portal.Close()
+
+	client := cachedClient(t, portal.URL)
+	for i := 0; i < 3; i++ {
+		prefix, err := client.GetDuploServicesPrefix(recorderTenantId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prefix != "duploservices-dev01" {
+			t.Errorf("got prefix %s, expected duploservices-dev01", prefix)
+		}
+	}
+	hits, requests := client.Cache.Stats()
+	if *calls != 1 || hits != 2 || requests != 1 {
+		t.Errorf("got %d calls, %d hits and %d requests, expected 1 call, 2 hits and 1 request", *calls, hits, requests)
+	}
+}
+
+func TestResponseCacheSharesConcurrentReads(t *testing.T) {
+	calls := new(int32)
+	release := make(chan struct{})
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		<-release
+		io.WriteString(w, `[{"TenantId":"2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01","AccountName":"dev01"}]`)
+	}))
+	defer portal.Close()
+
+	client := cachedClient(t, portal.URL)
+	wait := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			list, err := client.ListTenantsForUser()
+			if err != nil || len(*list) != 1 {
+				t.Errorf("got %v, %v", list, err)
+			}
+		}()
+	}
+	for {
+		hits, _ := client.Cache.Stats()
+		if hits == 7 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wait.Wait()
+	if *calls != 1 {
+		t.Errorf("got %d calls, expected 1", *calls)
+	}
+}
+
+func TestResponseCacheSkipsFailures(t *testing.T) {
+	portal, calls := flakyPortal(t, http.StatusNotFound)
+	defer portal.Close()
+
+	client := cachedClient(t, portal.URL)
+	_, err := client.ListTenantsForUser()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	_, err = client.ListTenantsForUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 {
+		t.Errorf("got %d calls, expected the failure to be requested again", *calls)
+	}
+}
+
+func TestResponseCacheNullErrorBody(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "null")
+	}))
+	defer portal.Close()
+
+	client := cachedClient(t, portal.URL)
+	for i := 0; i < 2; i++ {
+		_, err := client.ListTenantsForUser()
+		if err == nil || err.Status() != http.StatusInternalServerError {
+			t.Fatalf("got %v, expected a 500 error", err)
+		}
+	}
+}
+
+func TestResponseCacheReleasesWaitersOnPanic(t *testing.T) {
+	cache := NewResponseCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() { recover() }()
+		cache.get("/adminproxy/GetTenantNames", func() ([]byte, ClientError) {
+			close(started)
+			<-release
+			panic("malformed response")
+		})
+	}()
+	<-started
+	done := make(chan ClientError)
+	go func() {
+		_, err := cache.get("/adminproxy/GetTenantNames", func() ([]byte, ClientError) { return []byte("[]"), nil })
+		done <- err
+	}()
+	for {
+		hits, _ := cache.Stats()
+		if hits == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the waiting read to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting read was not released")
+	}
+}
+
+func TestResponseCacheSaveAndLoad(t *testing.T) {
+	portal, calls := flakyPortal(t)
+	defer portal.Close()
+
+	client := retryClient(t, portal.URL)
+	path := filepath.Join(t.TempDir(), "cache.json")
+	var err error
+	client.Cache, err = LoadResponseCache(path, portal.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientErr := client.ListTenantsForUser()
+	if clientErr != nil {
+		t.Fatal(clientErr)
+	}
+	err = client.Cache.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cache saved with mode %s, expected -rw-------", info.Mode().Perm())
+	}
+
+	client.Cache, err = LoadResponseCache(path, portal.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, clientErr := client.ListTenantsForUser()
+	if clientErr != nil || len(*list) != 1 || *calls != 1 {
+		t.Errorf("got %v after %d calls, expected the saved tenant list", list, *calls)
+	}
+	created := client.Cache.created
+	err = client.Cache.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Cache, err = LoadResponseCache(path, portal.URL, time.Hour)
+	if err != nil || !client.Cache.created.Equal(created) {
+		t.Errorf("saving a loaded cache should keep its creation time %s", created)
+	}
+
+	// A cache saved for another portal is read from the portal again.
+	client.Cache, err = LoadResponseCache(path, "https://other.duplocloud.invalid", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientErr = client.ListTenantsForUser()
+	if clientErr != nil || *calls != 2 {
+		t.Errorf("got %d calls, expected the cache of another portal to be ignored", *calls)
+	}
+
+	// An expired cache is read from the portal again.
+	content, _ := os.ReadFile(path)
+	file := responseCacheFile{}
+	json.Unmarshal(content, &file)
+	file.Created = time.Now().Add(-2 * time.Hour)
+	content, _ = json.Marshal(file)
+	os.WriteFile(path, content, 0600)
+	client.Cache, err = LoadResponseCache(path, portal.URL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientErr = client.ListTenantsForUser()
+	if clientErr != nil || *calls != 3 {
+		t.Errorf("got %d calls, expected the expired cache to be ignored", *calls)
+	}
+
+	cache, err := LoadResponseCache(filepath.Join(t.TempDir(), "missing.json"), portal.URL, time.Hour)
+	if err != nil || cache == nil {
+		t.Errorf("a missing cache file should give an empty cache, got %v", err)
+	}
+}
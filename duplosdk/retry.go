@@ -0,0 +1,125 @@
+package duplosdk
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides how often and how long the client waits before repeating a failed request.
+//
+// Only GET requests are retried, on I/O errors like timeouts, on 429 and on 502, 503 and 504. A
+// 500 is not retried since the portal answers it for APIs missing from older releases.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the wait before the first retry. It doubles for each retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// attempts returns the number of attempts allowed for a request method.
+func (p RetryPolicy) attempts(method string) int {
+	if method != http.MethodGet || p.MaxRetries <= 0 {
+		return 1
+	}
+	return p.MaxRetries + 1
+}
+
+// retryDelay reports whether a failed attempt is retried and how long to wait before. A
+// Retry-After header takes precedence over the backoff but is capped at MaxDelay, so a portal
+// asking for a long wait can not stall the run.
+func (p RetryPolicy) retryDelay(attempt int, res *http.Response, err error) (time.Duration, bool) {
+	if err == nil {
+		switch res.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return 0, false
+		}
+		if delay, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff doubles the delay for each attempt, with a jitter of up to half the delay so that
+// parallel generators do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an http date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// RateLimiter spaces out the requests of a client so that they do not exceed a rate. It is safe
+// for concurrent use. A nil limiter does not limit.
+type RateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time
+}
+
+// NewRateLimiter limits to the given requests per second. Zero or less means no limit.
+func NewRateLimiter(requestsPerSecond float64) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// Wait blocks until the next request is allowed.
+func (l *RateLimiter) Wait() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mutex.Unlock()
+	time.Sleep(slot.Sub(now))
+}
@@ -0,0 +1,150 @@
+package duplosdk
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyPortal answers with the given statuses in turn, and with a tenant list once they are used up.
+func flakyPortal(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	calls := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(calls, 1))
+		if call <= len(statuses) {
+			if statuses[call-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(statuses[call-1])
+			io.WriteString(w, `"try again"`)
+			return
+		}
+		io.WriteString(w, `[{"TenantId":"2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01","AccountName":"dev01"}]`)
+	}))
+	return server, calls
+}
+
+func retryClient(t *testing.T, url string) *Client {
+	t.Helper()
+	client, err := NewClient(url, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Retry = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	return client
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	portal, calls := flakyPortal(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway)
+	defer portal.Close()
+
+	list, err := retryClient(t, portal.URL).ListTenantsForUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*list) != 1 || *calls != 4 {
+		t.Errorf("got %d tenants after %d calls, expected 1 tenant after 4 calls", len(*list), *calls)
+	}
+}
+
+func TestClientReportsAttempts(t *testing.T) {
+	portal, calls := flakyPortal(t, 503, 503, 503, 503, 503)
+	defer portal.Close()
+
+	_, err := retryClient(t, portal.URL).ListTenantsForUser()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if err.Status() != 503 || err.Attempts() != 4 || *calls != 4 {
+		t.Errorf("got status %d after %d attempts and %d calls, expected 503 after 4", err.Status(), err.Attempts(), *calls)
+	}
+}
+
+func TestClientDoesNotRetry(t *testing.T) {
+	for name, status := range map[string]int{"missing api": 500, "not found": 404} {
+		portal, calls := flakyPortal(t, status)
+		_, err := retryClient(t, portal.URL).ListTenantsForUser()
+		portal.Close()
+		if err == nil || err.Attempts() != 1 || *calls != 1 {
+			t.Errorf("%s: expected a single attempt, got %d calls", name, *calls)
+		}
+	}
+
+	// Requests with a body may not be idempotent.
+	portal, calls := flakyPortal(t, 503)
+	defer portal.Close()
+	_, err := retryClient(t, portal.URL).TenantGetApplicationLbSettings("2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01", "arn")
+	if err == nil || *calls != 1 {
+		t.Errorf("post: expected a single attempt, got %d calls", *calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"7":                             7 * time.Second,
+		"Fri, 01 Mar 2024 10:00:30 GMT": 30 * time.Second,
+		"Fri, 01 Mar 2024 09:00:00 GMT": 0,
+	}
+	for value, expected := range cases {
+		delay, ok := parseRetryAfter(value, now)
+		if !ok || delay != expected {
+			t.Errorf("Retry-After %q gave %s, expected %s", value, delay, expected)
+		}
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if _, ok := parseRetryAfter(value, now); ok {
+			t.Errorf("Retry-After %q should be ignored", value)
+		}
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	cases := map[string]time.Duration{
+		"0":    0,
+		"1":    time.Second,
+		"3600": time.Second,
+	}
+	for value, expected := range cases {
+		res := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{value}}}
+		delay, retry := policy.retryDelay(1, res, nil)
+		if !retry || delay != expected {
+			t.Errorf("Retry-After %q waited %s, expected %s", value, delay, expected)
+		}
+	}
+}
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		expected := policy.BaseDelay << (attempt - 1)
+		if expected > policy.MaxDelay {
+			expected = policy.MaxDelay
+		}
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(attempt)
+			if delay < expected/2 || delay > expected {
+				t.Fatalf("attempt %d waited %s, expected between %s and %s", attempt, delay, expected/2, expected)
+			}
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		limiter.Wait()
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("6 requests at 100 per second took %s, expected at least 50ms", elapsed)
+	}
+	if NewRateLimiter(0) != nil {
+		t.Error("a rate of 0 should not limit")
+	}
+}
@@ -8,77 +8,293 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"tenant-terraform-generator/duplosdk"
 	tfgenerator "tenant-terraform-generator/tf-generator"
 	"tenant-terraform-generator/tf-generator/common"
+	"time"
 )
 
 func main() {
+	os.Exit(runCommand(os.Args[1:]))
+}
 
-	// Initialize duplo client and config
+// initialize creates the duplo client and resolves tenant, plan and account details into a config per tenant to export.
+func initialize(config *common.Config) (*duplosdk.Client, []*common.Config, error) {
 	log.Println("[TRACE] <====== Initialize duplo client and config. =====>")
-	validator := common.EnvVarValidator{}
-	config, err := validator.Validate()
+	err := tfgenerator.Registry.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = tfgenerator.Registry.CheckNames(append(append([]string{}, config.EnableGenerators...), config.DisableGenerators...))
 	if err != nil {
-		os.Exit(1)
+		return nil, nil, err
 	}
 	client, err := duplosdk.NewClient(config.DuploHost, config.DuploToken)
 	if err != nil {
 		err = fmt.Errorf("error while creating duplo client %s", err)
 		log.Printf("[TRACE] - %s", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
 
-	sslNoVerify := os.Getenv("ssl_no_verify")
-	if len(sslNoVerify) != 0 {
+	client.HTTPClient.Timeout = time.Duration(config.RequestTimeout) * time.Second
+	client.Retry.MaxRetries = config.MaxRetries
+	client.Limiter = duplosdk.NewRateLimiter(config.RequestsPerSecond)
+	if config.SslNoVerify {
 		client.HTTPClient.Transport = &http.Transport{
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
 	}
+	switch {
+	case len(config.ReplayFixtures) > 0:
+		bundle, err := duplosdk.LoadFixtureBundle(config.ReplayFixtures)
+		if err != nil {
+			log.Printf("[TRACE] - %s", err)
+			return nil, nil, err
+		}
+		client.HTTPClient.Transport = duplosdk.NewFixtureTransport(bundle)
+		log.Printf("[TRACE] Replaying DuploCloud API responses from %s", config.ReplayFixtures)
+	case len(config.RecordFixtures) > 0:
+		client.HTTPClient.Transport = duplosdk.NewFixtureRecorder(client.HTTPClient.Transport)
+		log.Printf("[TRACE] Recording DuploCloud API responses into %s", config.RecordFixtures)
+	}
+	switch {
+	case !config.APICache:
+	case len(config.APICacheFile) > 0 && len(config.ReplayFixtures) == 0:
+		client.Cache, err = duplosdk.LoadResponseCache(config.APICacheFile, client.HostURL, config.APICacheTTL)
+		if err != nil {
+			log.Printf("[TRACE] - %s", err)
+			return nil, nil, err
+		}
+	default:
+		client.Cache = duplosdk.NewResponseCache()
+	}
 	log.Println("[TRACE] <====== Initialized duplo client and config. =====>")
 
-	tenantConfig, err := client.GetTenantByNameForUser(config.TenantName)
+	tenants, err := resolveTenants(config, client)
 	if err != nil {
-		log.Fatalf("error getting tenant from duplo: %s", err)
+		return nil, nil, err
 	}
-	if tenantConfig == nil {
-		log.Fatalf("Tenant not found: Tenant Name - %s ", config.TenantName)
+	defaultInfraConfig, clientErr := client.InfrastructureGetConfig("default")
+	if clientErr != nil || defaultInfraConfig == nil {
+		return nil, nil, fmt.Errorf("error getting default duplo plan region from duplo: %s", clientErr)
 	}
-	config.TenantId = tenantConfig.TenantID
-	config.DuploPlanId = tenantConfig.PlanID
-	accountID, err := client.TenantGetAwsAccountID(config.TenantId)
-	if err != nil {
-		log.Fatalf("error getting aws account id from duplo: %s", err)
+
+	configs := []*common.Config{}
+	for i, tenant := range tenants {
+		tenantConfig := *config
+		tenantConfig.TenantName = tenant.AccountName
+		tenantConfig.TenantId = tenant.TenantID
+		tenantConfig.DuploPlanId = tenant.PlanID
+		// Tenants share a single admin-infra output, so it is generated along with the first tenant only.
+		if i > 0 {
+			tenantConfig.SkipAdminInfra = true
+		} else if !config.SkipAdminInfra && len(tenants) > 1 {
+			for _, other := range tenants[1:] {
+				if other.PlanID != tenant.PlanID {
+					log.Printf("[TRACE] - admin-infra is generated for infrastructure %s only, tenant %s belongs to %s", tenant.PlanID, other.AccountName, other.PlanID)
+				}
+			}
+		}
+		accountID, clientErr := client.TenantGetAwsAccountID(tenantConfig.TenantId)
+		if clientErr != nil {
+			return nil, nil, fmt.Errorf("error getting aws account id from duplo: %s", clientErr)
+		}
+		tenantConfig.AccountID = accountID
+		infraConfig, clientErr := client.InfrastructureGetConfig(tenant.PlanID)
+		if clientErr != nil {
+			return nil, nil, fmt.Errorf("error getting duplo plan region from duplo: %s", clientErr)
+		}
+		tenantConfig.DuploPlanRegion = infraConfig.Region
+		tenantConfig.DuploDefaultPlanRegion = defaultInfraConfig.Region
+
+		log.Printf("[TRACE] Config ==> %+v\n", tenantConfig)
+		configs = append(configs, &tenantConfig)
 	}
-	config.AccountID = accountID
-	infraConfig, err := client.InfrastructureGetConfig(tenantConfig.PlanID)
-	if err != nil {
-		log.Fatalf("error getting duplo plan region from duplo: %s", err)
+	return client, configs, nil
+}
+
+// finishClient saves the response cache and writes the responses recorded by the run, or reports
+// the requests which a replayed run found no response for.
+func finishClient(config *common.Config, client *duplosdk.Client) error {
+	if client.Cache != nil {
+		hits, requests := client.Cache.Stats()
+		log.Printf("[TRACE] DuploCloud API reads: %d sent, %d served from the cache", requests, hits)
+		if len(config.APICacheFile) > 0 && len(config.ReplayFixtures) == 0 {
+			err := client.Cache.Save(config.APICacheFile)
+			if err != nil {
+				return fmt.Errorf("error saving the api cache %s: %s", config.APICacheFile, err)
+			}
+		}
 	}
-	config.DuploPlanRegion = infraConfig.Region
-	defaultInfraConfig, err := client.InfrastructureGetConfig("default")
-	if err != nil || defaultInfraConfig == nil {
-		log.Fatalf("error getting default duplo plan region from duplo: %s", err)
+	switch transport := client.HTTPClient.Transport.(type) {
+	case *duplosdk.FixtureRecorder:
+		err := transport.Write(config.RecordFixtures)
+		if err != nil {
+			return fmt.Errorf("error writing recorded fixtures %s: %s", config.RecordFixtures, err)
+		}
+	case *duplosdk.FixtureTransport:
+		missing := transport.Missing()
+		for _, request := range missing {
+			log.Printf("[TRACE] - %s has no recorded response for %s", config.ReplayFixtures, request)
+		}
+		if len(missing) > 0 {
+			log.Printf("[TRACE] %d request(s) were not recorded in %s, their resources are missing from the generated code.", len(missing), config.ReplayFixtures)
+		}
 	}
-	config.DuploDefaultPlanRegion = defaultInfraConfig.Region
+	return nil
+}
 
-	log.Printf("[TRACE] Config ==> %+v\n", config)
+// resolveTenants finds the tenants to export. These are either the configured tenant names or every tenant of an infrastructure.
+func resolveTenants(config *common.Config, client *duplosdk.Client) ([]duplosdk.DuploTenant, error) {
+	if len(config.InfraName) > 0 {
+		list, clientErr := client.ListTenantsForUserByPlan(config.InfraName)
+		if clientErr != nil {
+			return nil, fmt.Errorf("error getting tenants from duplo: %s", clientErr)
+		}
+		tenants := []duplosdk.DuploTenant{}
+		for _, tenant := range *list {
+			// The generated scripts refuse these names as terraform workspaces.
+			if tenant.AccountName == "default" || tenant.AccountName == "compliance" {
+				log.Printf("[TRACE] Skipping tenant %s of infrastructure %s.", tenant.AccountName, config.InfraName)
+				continue
+			}
+			tenants = append(tenants, tenant)
+		}
+		if len(tenants) == 0 {
+			return nil, fmt.Errorf("No tenants found: Infrastructure Name - %s ", config.InfraName)
+		}
+		sort.Slice(tenants, func(i, j int) bool {
+			return tenants[i].AccountName < tenants[j].AccountName
+		})
+		return tenants, nil
+	}
 
-	tfGeneratorService := tfgenerator.TfGeneratorService{}
+	names := config.TenantNames
+	if len(names) == 0 {
+		names = []string{config.TenantName}
+	}
+	list, clientErr := client.ListTenantsForUser()
+	if clientErr != nil {
+		return nil, fmt.Errorf("error getting tenant from duplo: %s", clientErr)
+	}
+	tenants := []duplosdk.DuploTenant{}
+	missing := []string{}
+	for _, name := range names {
+		found := false
+		for _, tenant := range *list {
+			if tenant.AccountName == name {
+				tenants = append(tenants, tenant)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Tenant not found: Tenant Name - %s ", strings.Join(missing, ", "))
+	}
+	return tenants, nil
+}
 
-	err = tfGeneratorService.PreProcess(config, client)
+// generate runs the full generation pipeline for every tenant. The failures of the run are reported
+// under the customer folder.
+func generate(configs []*common.Config, client *duplosdk.Client) error {
+	tfGeneratorService := tfgenerator.TfGeneratorService{
+		Report: &tfgenerator.ErrorReport{},
+	}
+	err := generateEachTenant(&tfGeneratorService, configs, client)
+	reportDir := filepath.Join(configs[0].TargetDir, configs[0].CustomerName)
+	if reportErr := tfGeneratorService.Report.Write(reportDir); reportErr != nil {
+		log.Printf("[TRACE] - error writing error report: %s", reportErr)
+	}
 	if err != nil {
-		log.Fatalf("error while pre processing: %s", err)
+		return err
+	}
+	if count := tfGeneratorService.Report.Count(); count > 0 {
+		log.Printf("[TRACE] Terraform generation finished with %d error(s). See %s", count, filepath.Join(reportDir, "errors.txt"))
+		if count > configs[0].MaxFailures {
+			return fmt.Errorf("%d error(s) exceed the %d tolerated by max_failures", count, configs[0].MaxFailures)
+		}
 	}
-	err = tfGeneratorService.StartTFGeneration(config, client)
+	return nil
+}
+
+// generateUpdate generates into a staging folder, then merges each generated tenant and admin-infra
+// into the target folder with the generation saved there as base.
+func generateUpdate(configs []*common.Config, client *duplosdk.Client) error {
+	stagingDir, err := os.MkdirTemp("", "tf-generator-update-")
 	if err != nil {
-		log.Fatalf("error while generating terraform: %s", err)
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+	targetDir := configs[0].TargetDir
+	customerName := configs[0].CustomerName
+	for _, config := range configs {
+		config.TargetDir = stagingDir
+	}
+	err = generate(configs, client)
+	for _, config := range configs {
+		config.TargetDir = targetDir
+	}
+	reportErr := os.MkdirAll(filepath.Join(targetDir, customerName), os.ModePerm)
+	for _, report := range []string{"errors.txt", "errors.json"} {
+		if reportErr == nil {
+			reportErr = duplosdk.Copy(filepath.Join(stagingDir, customerName, report), filepath.Join(targetDir, customerName, report))
+		}
+	}
+	if reportErr != nil {
+		log.Printf("[TRACE] - error copying error report: %s", reportErr)
 	}
-	err = tfGeneratorService.PostProcess(config, client)
 	if err != nil {
-		log.Fatalf("error while post processing: %s", err)
+		return err
+	}
+
+	dirs := []string{}
+	for _, config := range configs {
+		dirs = append(dirs, config.TenantName)
+		if !config.SkipAdminInfra {
+			dirs = append(dirs, "admin-infra")
+		}
+	}
+	for _, dir := range dirs {
+		codeDir := filepath.Join(targetDir, customerName, dir)
+		conflicts, err := tfgenerator.MergeGeneration(filepath.Join(stagingDir, customerName, dir), codeDir)
+		if err != nil {
+			return fmt.Errorf("error while updating %s: %s", codeDir, err)
+		}
+		for _, conflict := range conflicts {
+			log.Printf("[TRACE] - %s: %s", dir, conflict.Error())
+		}
+		if len(conflicts) > 0 {
+			fmt.Printf("Updated %s with %d conflict(s). See %s\n", codeDir, len(conflicts), filepath.Join(codeDir, tfgenerator.MERGE_CONFLICTS_FILE))
+		} else {
+			fmt.Printf("Updated %s\n", codeDir)
+		}
+	}
+	return nil
+}
+
+func generateEachTenant(tfGeneratorService *tfgenerator.TfGeneratorService, configs []*common.Config, client *duplosdk.Client) error {
+	for _, config := range configs {
+		err := tfGeneratorService.PreProcess(config, client)
+		if err != nil {
+			return fmt.Errorf("error while pre processing %s: %s", config.TenantName, err)
+		}
+		err = tfGeneratorService.StartTFGeneration(config, client)
+		if err != nil {
+			return fmt.Errorf("error while generating terraform for %s: %s", config.TenantName, err)
+		}
+		err = tfGeneratorService.PostProcess(config, client)
+		if err != nil {
+			return fmt.Errorf("error while post processing %s: %s", config.TenantName, err)
+		}
+		log.Printf("[TRACE] |==========================================================================|")
+		log.Printf("[TRACE] Terraform projects are generated at - %s", filepath.Join(config.TargetDir, config.CustomerName, config.TenantName))
+		log.Printf("[TRACE] |==========================================================================|")
 	}
-	log.Printf("[TRACE] |==========================================================================|")
-	log.Printf("[TRACE] Terraform projects are generated at - %s", filepath.Join("./target", config.CustomerName, config.TenantName))
-	log.Printf("[TRACE] |==========================================================================|")
+	return nil
 }
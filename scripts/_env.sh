@@ -10,7 +10,10 @@ duplo_default_tenant_id=$([ -z "${tenant_id:-}" ] && duplo_api /adminproxy/GetTe
 duplo_host="$duplo_host"
 duplo_token="$duplo_token"
 
-backend="-backend-config=bucket=duplo-tfstate-${AWS_ACCOUNT_ID} -backend-config=dynamodb_table=duplo-tfstate-${AWS_ACCOUNT_ID}-lock"
+# The arguments of "terraform init" and whether the state of each tenant is a terraform workspace.
+# Both are written from the backend of the projects when the scripts are generated.
+backend="<--backend-config-->"
+workspaces="<--workspaces-->"
 
 # Test required environment variables
 for key in duplo_token duplo_host
@@ -18,4 +21,4 @@ do
   eval "[ -n \"\${${key}:-}\" ]" || die "error: $key: environment variable missing or empty"
 done
 
-export duplo_host duplo_token duplo_default_tenant_id backend AWS_ACCOUNT_ID
+export duplo_host duplo_token duplo_default_tenant_id backend workspaces AWS_ACCOUNT_ID
@@ -103,3 +103,10 @@ tf_init() {
   rm -f .terraform/environment .terraform/terraform.tfstate
   tf init "$@"
 }
+
+# Utility function to select the terraform workspace of the tenant, or create it.
+# Backends without workspaces already name the state after the tenant.
+tf_workspace() {
+  [ "$workspaces" = "true" ] || return 0
+  tf workspace select "$1" || tf workspace new "$1"
+}
@@ -28,7 +28,7 @@ source "$(dirname "${BASH_SOURCE[0]}")/_env.sh"
 tf_apply() {
   local project="$1" ; shift
 
-  [ -z "${backend:-}" ] && die "internal error: backend should have been configured by _env.sh"
+  [ -z "${workspaces:-}" ] && die "internal error: the backend should have been configured by _env.sh"
 
   # Skip projects that are not selected.
   if [ -n "$selection" ] && [ "$selection" != "$project" ]; then
@@ -50,7 +50,7 @@ tf_apply() {
   # shellcheck disable=SC2086    # NOTE: we want word splitting
   (cd "terraform/$project" &&
     tf_init $backend &&
-    ( tf workspace select "$ws" || tf workspace new "$ws" ) &&
+    tf_workspace "$ws" &&
     tf apply "${tf_args[@]}" )
 }
 
@@ -63,7 +63,7 @@ tf_output() {
   # shellcheck disable=SC2086    # NOTE: we want word splitting
   (cd "terraform/$project" &&
     tf_init $backend 1>&2 &&
-    ( tf workspace select "$ws" 1>&2 || tf workspace new "$ws" 1>&2 ) &&
+    tf_workspace "$ws" 1>&2 &&
     tf output -json )
 }
 
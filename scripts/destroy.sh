@@ -28,7 +28,7 @@ source "$(dirname "${BASH_SOURCE[0]}")/_env.sh"
 function tf_destroy() {
   local project="$1" ; shift
 
-  [ -z "${backend:-}" ] && die "internal error: backend should have been configured by _env.sh"
+  [ -z "${workspaces:-}" ] && die "internal error: the backend should have been configured by _env.sh"
 
   # Skip projects that are not selected.
   if [ -n "$selection" ] && [ "$selection" != "$project" ]; then
@@ -51,7 +51,9 @@ function tf_destroy() {
   # shellcheck disable=SC2086    # NOTE: we want word splitting
   (cd "terraform/$project" &&
       tf_init $backend &&
-      if tf workspace select "$ws"; then
+      if [ "$workspaces" != "true" ]; then
+        tf destroy "${tf_args[@]}"
+      elif tf workspace select "$ws"; then
         tf destroy "${tf_args[@]}" && tf workspace select default && tf workspace delete "$ws"
       fi)
 }
@@ -65,7 +67,7 @@ tf_output() {
     # shellcheck disable=SC2086    # NOTE: we want word splitting
     (cd "terraform/$project" &&
         tf_init $backend 1>&2 &&
-        ( tf workspace select "$ws" 1>&2 || tf workspace new "$ws" 1>&2 ) &&
+        tf_workspace "$ws" 1>&2 &&
         tf output -json )
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
@@ -26,7 +27,12 @@ func (i InfraSubnet) Generate(config *common.Config, client *duplosdk.Client) (*
 
 	tfContext := common.TFContext{}
 	if i.Subnets != nil {
-		for _, v := range i.Subnets {
+		subnets := append([]duplosdk.DuploInfrastructureVnetSubnet{}, i.Subnets...)
+		sort.SliceStable(subnets, func(i, j int) bool { return subnets[i].Name < subnets[j].Name })
+		for _, v := range subnets {
+			if !config.ResourceAllowed("duplocloud_infrastructure_subnet", v.Name, common.TagsFromKeyValues(v.Tags)) {
+				continue
+			}
 			hclFile := hclwrite.NewEmptyFile()
 			visiblity := "public"
 			if strings.Contains(v.Name, "private") {
@@ -81,14 +87,12 @@ func (i InfraSubnet) Generate(config *common.Config, client *duplosdk.Client) (*
 				fmt.Println(err)
 				return nil, err
 			}
-			if config.GenerateTfState {
-				importConfigs := []common.ImportConfig{}
-				importConfigs = append(importConfigs, common.ImportConfig{
+			if config.ImportsEnabled() {
+				tfContext.ImportConfigs = append(tfContext.ImportConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_infrastructure_subnet." + resourceName,
 					ResourceId:      i.InfraName + "/" + v.Name + "/" + v.AddressPrefix,
 					WorkingDir:      workingDir,
 				})
-				tfContext.ImportConfigs = importConfigs
 			}
 
 		}
@@ -30,6 +30,9 @@ func (i Infra) Generate(config *common.Config, client *duplosdk.Client) (*common
 				log.Printf("Error while fetching infra %s : %s", v.Name, clientErr.Error())
 				continue
 			}
+			if !config.ResourceAllowed("duplocloud_infrastructure", infra.Name, nil) {
+				continue
+			}
 
 			hclFile := hclwrite.NewEmptyFile()
 
@@ -42,8 +45,8 @@ func (i Infra) Generate(config *common.Config, client *duplosdk.Client) (*common
 			}
 			resourceName := common.GetResourceName(infra.Name)
 			varFullPrefix := INFRA_VAR_PREFIX + resourceName + "_"
-			//inputVars := generateInfraVars(infra, varFullPrefix)
-			//tfContext.InputVars = append(tfContext.InputVars, inputVars...)
+			inputVars := generateInfraVars(infra, varFullPrefix)
+			tfContext.InputVars = append(tfContext.InputVars, inputVars...)
 			fmt.Println("Admin generator tfContext.InputVars ", tfContext.InputVars)
 			rootBody := hclFile.Body()
 
@@ -152,14 +155,12 @@ func (i Infra) Generate(config *common.Config, client *duplosdk.Client) (*common
 				return nil, err
 			}
 
-			if config.GenerateTfState {
-				importConfigs := []common.ImportConfig{}
-				importConfigs = append(importConfigs, common.ImportConfig{
+			if config.ImportsEnabled() {
+				tfContext.ImportConfigs = append(tfContext.ImportConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_infrastructure." + resourceName,
 					ResourceId:      "v2/admin/InfrastructureV2/" + infra.Name,
 					WorkingDir:      workingDir,
 				})
-				tfContext.ImportConfigs = importConfigs
 			}
 
 		}
@@ -191,7 +192,7 @@ func generateInfraVars(duplo *duplosdk.DuploInfrastructureConfig, prefix string)
 	}
 	varConfigs["address_prefix"] = var3
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -19,6 +19,9 @@ type PlanConfig struct {
 func (p PlanConfig) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	// create new empty hcl file object
 	workingDir := filepath.Join(config.AdminInfraDir, config.AdminInfra)
+	if !config.ResourceAllowed("duplocloud_plan_configs", p.InfraName, nil) {
+		return &common.TFContext{}, nil
+	}
 	planConfig, clientErr := client.PlanConfigGetList(p.InfraName)
 	if clientErr != nil {
 		return nil, errors.New(clientErr.Error())
@@ -58,7 +61,7 @@ func (p PlanConfig) Generate(config *common.Config, client *duplosdk.Client) (*c
 		fmt.Println(err)
 		return nil, err
 	}
-	if config.GenerateTfState {
+	if config.ImportsEnabled() {
 		importConfigs := []common.ImportConfig{}
 		importConfigs = append(importConfigs, common.ImportConfig{
 			ResourceAddress: "duplocloud_plan_configs." + resourceName,
@@ -37,6 +37,9 @@ func (p PlanImage) Generate(config *common.Config, client *duplosdk.Client) (*co
 	rootBody := hclFile.Body()
 	// initialize the body of the new file object
 	for _, val := range *planImg {
+		if !config.ResourceAllowed("duplocloud_plan_image", val.Name, common.TagsFromKeyValues(val.Tags)) {
+			continue
+		}
 		planBlock := rootBody.AppendNewBlock("resource",
 			[]string{"duplocloud_plan_image", "image-" + val.Name})
 		planBody := planBlock.Body()
@@ -60,7 +63,7 @@ func (p PlanImage) Generate(config *common.Config, client *duplosdk.Client) (*co
 		fmt.Println(err)
 		return nil, err
 	}
-	if config.GenerateTfState {
+	if config.ImportsEnabled() {
 		importConfigs := []common.ImportConfig{}
 		importConfigs = append(importConfigs, common.ImportConfig{
 			ResourceAddress: "duplocloud_plan_certificate." + resourceName,
@@ -19,6 +19,9 @@ type PlanSetting struct {
 func (p PlanSetting) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	// create new empty hcl file object
 	workingDir := filepath.Join(config.AdminInfraDir, config.AdminInfra)
+	if !config.ResourceAllowed("duplocloud_plan_settings", p.InfraName, nil) {
+		return &common.TFContext{}, nil
+	}
 	planSetting, clientErr := client.PlanGetSettings(p.InfraName)
 	if clientErr != nil {
 		return nil, errors.New(clientErr.Error())
@@ -71,7 +74,7 @@ func (p PlanSetting) Generate(config *common.Config, client *duplosdk.Client) (*
 		fmt.Println(err)
 		return nil, err
 	}
-	if config.GenerateTfState {
+	if config.ImportsEnabled() {
 		importConfigs := []common.ImportConfig{}
 		importConfigs = append(importConfigs, common.ImportConfig{
 			ResourceAddress: "duplocloud_plan_certificate." + resourceName,
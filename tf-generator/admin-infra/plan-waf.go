@@ -36,6 +36,9 @@ func (p PlanWaf) Generate(config *common.Config, client *duplosdk.Client) (*comm
 	resourceName := common.GetResourceName(p.InfraName)
 	rootBody := hclFile.Body()
 	for _, waf := range *planWAF {
+		if !config.ResourceAllowed("duplocloud_plan_waf", waf.WebAclName, nil) {
+			continue
+		}
 		// initialize the body of the new file object
 		planBlock := rootBody.AppendNewBlock("resource",
 			[]string{"duplocloud_plan_waf", "plan_waf" + waf.WebAclName})
@@ -51,7 +54,7 @@ func (p PlanWaf) Generate(config *common.Config, client *duplosdk.Client) (*comm
 			fmt.Println(err)
 			return nil, err
 		}
-		if config.GenerateTfState {
+		if config.ImportsEnabled() {
 			importConfigs := []common.ImportConfig{}
 			importConfigs = append(importConfigs, common.ImportConfig{
 				ResourceAddress: "duplocloud_plan_waf." + resourceName,
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -36,26 +37,26 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
 	taskDefn := []string{}
+	// A service or task definition which can not be generated is skipped so that the others keep
+	// their imports.
+	errs := []error{}
 	if list != nil {
 		log.Println("[TRACE] <====== Duplo ECS TF generation started. =====>")
 		for _, ecs := range *list {
+			if !config.ResourceAllowed("duplocloud_ecs_service", ecs.Name, nil) {
+				continue
+			}
 
+			resourceName := common.GetResourceName(ecs.Name)
 			taskDefObj, clientErr := client.EcsTaskDefinitionGet(config.TenantId, ecs.TaskDefinition)
 			if clientErr != nil {
 				fmt.Println(clientErr)
-				return nil, clientErr
+				errs = append(errs, common.NewResourceError("duplocloud_ecs_task_definition."+resourceName, clientErr))
+				continue
 			}
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
 
-			// create new file on system
-			path := filepath.Join(workingDir, "ecs-"+ecs.Name+".tf")
-			tfFile, err := os.Create(path)
-			if err != nil {
-				fmt.Println(err)
-				return nil, err
-			}
-			resourceName := common.GetResourceName(ecs.Name)
 			// initialize the body of the new file object
 			rootBody := hclFile.Body()
 			log.Printf("[TRACE] Generating terraform config for duplo task definition : %s", taskDefObj.Family)
@@ -110,7 +111,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if taskDefObj.Volumes != nil && len(taskDefObj.Volumes) > 0 {
 				volString, err := duplosdk.JSONMarshal(taskDefObj.Volumes)
 				if err != nil {
-					panic(err)
+					errs = append(errs, common.NewResourceError("duplocloud_ecs_task_definition."+resourceName, err))
+					continue
 				}
 				tdBody.SetAttributeTraversal("volumes", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -121,7 +123,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if taskDefObj.ContainerDefinitions != nil && len(taskDefObj.ContainerDefinitions) > 0 {
 				containerString, err := duplosdk.JSONMarshal(taskDefObj.ContainerDefinitions)
 				if err != nil {
-					panic(err)
+					errs = append(errs, common.NewResourceError("duplocloud_ecs_task_definition."+resourceName, err))
+					continue
 				}
 				containerString = strings.Replace(containerString, config.TenantName, "${local.tenant_name}", -1)
 				tdBody.SetAttributeTraversal("container_definitions", hcl.Traversal{
@@ -190,6 +193,7 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				cpConfigBlockBody.SetAttributeValue("capacity_provider",
 					cty.StringVal(capacityProvider.CapacityProvider))
 			}
+			var portErr error
 			for _, serviceConfig := range *ecs.LBConfigurations {
 				lbConfigBlock := ecsBody.AppendNewBlock("load_balancer",
 					nil)
@@ -203,8 +207,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 					cty.BoolVal(serviceConfig.IsInternal))
 				port, err := strconv.Atoi(serviceConfig.Port)
 				if err != nil {
-					fmt.Println(err)
-					return nil, err
+					portErr = err
+					break
 				}
 				lbConfigBlockBody.SetAttributeValue("port",
 					cty.NumberIntVal(int64(port)))
@@ -284,14 +288,25 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				ecsBody.AppendNewline()
 			}
 			//}
+			if portErr != nil {
+				errs = append(errs, common.NewResourceError("duplocloud_ecs_service."+resourceName, portErr))
+				continue
+			}
 
+			// create new file on system
+			path := filepath.Join(workingDir, "ecs-"+ecs.Name+".tf")
+			tfFile, err := os.Create(path)
+			if err != nil {
+				fmt.Println(err)
+				return nil, err
+			}
 			_, err = tfFile.Write(hclFile.Bytes())
 			if err != nil {
 				fmt.Println(err)
 				return nil, err
 			}
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_ecs_task_definition." + resourceName,
 					ResourceId:      "subscriptions/" + config.TenantId + "/EcsTaskDefinition/" + ecs.TaskDefinition,
@@ -312,12 +327,12 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			tdObj, clientErr := client.EcsTaskDefinitionGet(config.TenantId, td)
 			if clientErr != nil {
 				fmt.Println(clientErr)
-				return nil, nil
+				errs = append(errs, common.NewResourceError(td, clientErr))
+				continue
 			}
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
 
-			// create new file on system
 			shortName, err := extractTaskDefnName(client, config.TenantId, tdObj.Family)
 			if err != nil {
 				return nil, err
@@ -325,11 +340,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if common.Contains(taskDefn, shortName) {
 				continue
 			}
-			path := filepath.Join(workingDir, "td-"+shortName+".tf")
-			tfFile, err := os.Create(path)
-			if err != nil {
-				fmt.Println(err)
-				return nil, err
+			if !config.ResourceAllowed("duplocloud_ecs_task_definition", shortName, common.TagsFromKeyValues(tdObj.Tags)) {
+				continue
 			}
 			resourceName := common.GetResourceName(shortName)
 			rootBody := hclFile.Body()
@@ -378,7 +390,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if tdObj.Volumes != nil && len(tdObj.Volumes) > 0 {
 				volString, err := duplosdk.JSONMarshal(tdObj.Volumes)
 				if err != nil {
-					panic(err)
+					errs = append(errs, common.NewResourceError("duplocloud_ecs_task_definition."+resourceName, err))
+					continue
 				}
 				tdBody.SetAttributeTraversal("volumes", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -389,7 +402,8 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if tdObj.ContainerDefinitions != nil && len(tdObj.ContainerDefinitions) > 0 {
 				containerString, err := duplosdk.JSONMarshal(tdObj.ContainerDefinitions)
 				if err != nil {
-					panic(err)
+					errs = append(errs, common.NewResourceError("duplocloud_ecs_task_definition."+resourceName, err))
+					continue
 				}
 				containerString = strings.Replace(containerString, config.TenantName, "${local.tenant_name}", -1)
 				tdBody.SetAttributeTraversal("container_definitions", hcl.Traversal{
@@ -399,12 +413,19 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				})
 			}
 
+			// create new file on system
+			path := filepath.Join(workingDir, "td-"+shortName+".tf")
+			tfFile, err := os.Create(path)
+			if err != nil {
+				fmt.Println(err)
+				return nil, err
+			}
 			_, err = tfFile.Write(hclFile.Bytes())
 			if err != nil {
 				fmt.Println(err)
 				return nil, err
 			}
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_ecs_task_definition." + resourceName,
 					ResourceId:      "subscriptions/" + config.TenantId + "/EcsTaskDefinition/" + td,
@@ -416,7 +437,7 @@ func (ecs *ECS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 		}
 	}
 
-	return &tfContext, nil
+	return &tfContext, errors.Join(errs...)
 }
 
 func extractTaskDefnName(client *duplosdk.Client, tenantID string, family string) (string, error) {
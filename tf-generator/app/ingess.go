@@ -23,7 +23,7 @@ func (k8sIngress *K8sIngress) Generate(config *common.Config, client *duplosdk.C
 	list, clientErr := client.DuploK8sIngressGetList(config.TenantId)
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
@@ -31,6 +31,9 @@ func (k8sIngress *K8sIngress) Generate(config *common.Config, client *duplosdk.C
 		log.Println("[TRACE] <====== Duplo K8S Ingress TF generation started. =====>")
 		for _, k8sIngress := range *list {
 			log.Printf("[TRACE] Generating terraform config for duplo k8s ingress : %s", k8sIngress.Name)
+			if !config.ResourceAllowed("duplocloud_k8_ingress", k8sIngress.Name, k8sIngress.Labels) {
+				continue
+			}
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
 
@@ -144,7 +147,7 @@ func (k8sIngress *K8sIngress) Generate(config *common.Config, client *duplosdk.C
 				return nil, err
 			}
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_k8_ingress." + resourceName,
 					ResourceId:      "v3/subscriptions/" + config.TenantId + "/k8s/ingress/" + k8sIngress.Name,
@@ -26,13 +26,16 @@ func (k8sCronJob *K8sCronJob) Generate(config *common.Config, client *duplosdk.C
 	list, clientErr := client.K8sCronJobGetList(config.TenantId)
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	fmt.Println("List \n\n ", list)
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
 
 	for _, d := range *list {
+		if !config.ResourceAllowed("duplocloud_k8s_cron_job", d.Metadata.Name, d.Metadata.Labels) {
+			continue
+		}
 		hclFile := hclwrite.NewEmptyFile()
 
 		path := filepath.Join(workingDir, "k8s-cron-job-"+d.Metadata.Name+".tf")
@@ -72,7 +75,7 @@ func (k8sCronJob *K8sCronJob) Generate(config *common.Config, client *duplosdk.C
 			return nil, err
 		}
 		// Import all created resources.
-		if config.GenerateTfState {
+		if config.ImportsEnabled() {
 			importConfigs = append(importConfigs, common.ImportConfig{
 				ResourceAddress: "duplocloud_k8_secret." + resourceName,
 				ResourceId:      "v3/subscriptions/" + config.TenantId + "/k8s/cronjob/" + resourceName,
@@ -20,13 +20,16 @@ func (k8sJob *K8sJob) Generate(config *common.Config, client *duplosdk.Client) (
 	list, clientErr := client.K8sJobGetList(config.TenantId)
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	fmt.Println("List \n\n ", list)
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
 
 	for _, d := range *list {
+		if !config.ResourceAllowed("duplocloud_k8s_job", d.Metadata.Name, d.Metadata.Labels) {
+			continue
+		}
 		hclFile := hclwrite.NewEmptyFile()
 
 		path := filepath.Join(workingDir, "k8s-job-"+d.Metadata.Name+".tf")
@@ -67,7 +70,7 @@ func (k8sJob *K8sJob) Generate(config *common.Config, client *duplosdk.Client) (
 			return nil, err
 		}
 		// Import all created resources.
-		if config.GenerateTfState {
+		if config.ImportsEnabled() {
 			importConfigs = append(importConfigs, common.ImportConfig{
 				ResourceAddress: "duplocloud_k8_secret." + resourceName,
 				ResourceId:      "v3/subscriptions/" + config.TenantId + "/k8s/cronjob/" + resourceName,
@@ -14,18 +14,15 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
-const EXCLUDE_K8S_CONFIG_STR = "kube-root-ca.crt"
-
 type K8sConfig struct {
 }
 
 func (k8sConfig *K8sConfig) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	workingDir := filepath.Join(config.TFCodePath, config.AppProject)
 	list, clientErr := client.K8ConfigMapGetList(config.TenantId)
-	exclude_k8s_config_list := strings.Split(EXCLUDE_K8S_CONFIG_STR, ",")
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
@@ -33,15 +30,7 @@ func (k8sConfig *K8sConfig) Generate(config *common.Config, client *duplosdk.Cli
 		log.Println("[TRACE] <====== Duplo K8S Config Map TF generation started. =====>")
 		for _, k8sConfig := range *list {
 			log.Printf("[TRACE] Generating terraform config for duplo k8s config map : %s", k8sConfig.Name)
-			skip := false
-			for _, element := range exclude_k8s_config_list {
-				if strings.Contains(k8sConfig.Name, element) {
-					log.Printf("[TRACE] Generating terraform config for duplo k8s config map : %s skipped.", k8sConfig.Name)
-					skip = true
-					break
-				}
-			}
-			if skip {
+			if !config.ResourceAllowed("duplocloud_k8_config_map", k8sConfig.Name, nil) {
 				continue
 			}
 			// create new empty hcl file object
@@ -76,7 +65,7 @@ func (k8sConfig *K8sConfig) Generate(config *common.Config, client *duplosdk.Cli
 			if len(k8sConfig.Data) > 0 {
 				configDataStr, err := duplosdk.JSONMarshal(EscapeDollarEscapes(k8sConfig.Data))
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_k8_config_map."+resourceName, err)
 				}
 				k8sConfigBody.SetAttributeTraversal("data", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -91,7 +80,7 @@ func (k8sConfig *K8sConfig) Generate(config *common.Config, client *duplosdk.Cli
 				return nil, err
 			}
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_k8_config_map." + resourceName,
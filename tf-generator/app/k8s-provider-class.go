@@ -24,7 +24,7 @@ func (spc *K8sSecretProviderClass) Generate(config *common.Config, client *duplo
 	list, clientErr := client.DuploK8sSecretProviderClassList(config.TenantId)
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
@@ -32,6 +32,9 @@ func (spc *K8sSecretProviderClass) Generate(config *common.Config, client *duplo
 		log.Println("[TRACE] <====== Duplo K8S Secret Provider Class TF generation started. =====>")
 		for _, secretProvClass := range *list {
 			log.Printf("[TRACE] Generating terraform config for duplo secret provider class : %s", secretProvClass.Name)
+			if !config.ResourceAllowed("duplocloud_k8_secret_provider_class", secretProvClass.Name, secretProvClass.Labels) {
+				continue
+			}
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
 
@@ -113,13 +116,12 @@ func (spc *K8sSecretProviderClass) Generate(config *common.Config, client *duplo
 				ymlStr := secretProvClass.Parameters.Objects
 				j2, err := yaml.YAMLToJSON([]byte(ymlStr))
 				if err != nil {
-					fmt.Printf("err: %v\n", err)
-					return nil, nil
+					return nil, common.NewResourceError("duplocloud_k8_secret_provider_class."+resourceName, err)
 				}
 				response := []interface{}{}
 				err = json.Unmarshal(j2, &response)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_k8_secret_provider_class."+resourceName, err)
 				}
 				if len(response) > 0 {
 					for _, r := range response {
@@ -131,7 +133,7 @@ func (spc *K8sSecretProviderClass) Generate(config *common.Config, client *duplo
 					}
 					paramString, err := duplosdk.JSONMarshal(response)
 					if err != nil {
-						panic(err)
+						return nil, common.NewResourceError("duplocloud_k8_secret_provider_class."+resourceName, err)
 					}
 					spcBody.SetAttributeTraversal("parameters", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -148,7 +150,7 @@ func (spc *K8sSecretProviderClass) Generate(config *common.Config, client *duplo
 				return nil, err
 			}
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_k8_secret_provider_class." + resourceName,
 					ResourceId:      "v3/subscriptions/" + config.TenantId + "/k8s/secretproviderclass/" + secretProvClass.Name,
@@ -1,11 +1,12 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
 
@@ -14,18 +15,15 @@ import (
 	"github.com/zclconf/go-cty/cty"
 )
 
-const EXCLUDE_K8S_SECRET_STR = "default-token,duploservices-,filebeat-token-"
-
 type K8sSecret struct {
 }
 
 func (k8sSecret *K8sSecret) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	workingDir := filepath.Join(config.TFCodePath, config.AppProject)
 	list, clientErr := client.K8SecretGetList(config.TenantId)
-	exclude_k8s_secret_list := strings.Split(EXCLUDE_K8S_SECRET_STR, ",")
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
@@ -33,15 +31,7 @@ func (k8sSecret *K8sSecret) Generate(config *common.Config, client *duplosdk.Cli
 		log.Println("[TRACE] <====== Duplo K8S Secret TF generation started. =====>")
 		for _, k8sSecret := range *list {
 			log.Printf("[TRACE] Generating terraform config for duplo k8s secret : %s", k8sSecret.SecretName)
-			skip := false
-			for _, element := range exclude_k8s_secret_list {
-				if strings.Contains(k8sSecret.SecretName, element) {
-					log.Printf("[TRACE] Generating terraform config for duplo k8s secret : %s skipped.", k8sSecret.SecretName)
-					skip = true
-					break
-				}
-			}
-			if skip {
+			if !config.ResourceAllowed("duplocloud_k8_secret", k8sSecret.SecretName, nil) {
 				continue
 			}
 			// create new empty hcl file object
@@ -84,25 +74,34 @@ func (k8sSecret *K8sSecret) Generate(config *common.Config, client *duplosdk.Cli
 			}
 
 			if len(k8sSecret.SecretData) > 0 {
-				var secretDataStr string
-				secretData := map[string]interface{}{}
-				if config.EnableSecretPlaceholder {
-					for key := range k8sSecret.SecretData {
-						secretData[key] = config.K8sSecretPlaceholder
-					}
-					secretDataStr, err = duplosdk.JSONMarshal(secretData)
-				} else {
-					secretDataStr, err = duplosdk.JSONMarshal(k8sSecret.SecretData)
+				// Each key is a secret of its own so that keys can have different policies.
+				keys := []string{}
+				for key := range k8sSecret.SecretData {
+					keys = append(keys, key)
 				}
-
-				if err != nil {
-					panic(err)
+				sort.Strings(keys)
+				secretData := []hclwrite.ObjectAttrTokens{}
+				for _, key := range keys {
+					value, ok := k8sSecret.SecretData[key].(string)
+					if !ok {
+						encoded, err := json.Marshal(k8sSecret.SecretData[key])
+						if err != nil {
+							return nil, common.NewResourceError("duplocloud_k8_secret."+resourceName, err)
+						}
+						value = string(encoded)
+					}
+					secretData = append(secretData, hclwrite.ObjectAttrTokens{
+						Name: hclwrite.TokensForValue(cty.StringVal(key)),
+						Value: config.SecretTokens(rootBody, &tfContext, common.Secret{
+							Project:      config.AppProject,
+							ResourceType: "duplocloud_k8_secret",
+							ResourceName: resourceName,
+							Attribute:    "secret_data." + key,
+							Value:        value,
+						}),
+					})
 				}
-				k8sSecretBody.SetAttributeTraversal("secret_data", hcl.Traversal{
-					hcl.TraverseRoot{
-						Name: "jsonencode(" + secretDataStr + ")",
-					},
-				})
+				k8sSecretBody.SetAttributeRaw("secret_data", hclwrite.TokensForFunctionCall("jsonencode", hclwrite.TokensForObject(secretData)))
 			}
 
 			_, err = tfFile.Write(hclFile.Bytes())
@@ -111,7 +110,7 @@ func (k8sSecret *K8sSecret) Generate(config *common.Config, client *duplosdk.Cli
 				return nil, err
 			}
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_k8_secret." + resourceName,
 					ResourceId:      "v2/subscriptions/" + config.TenantId + "/K8SecretApiV2/" + k8sSecret.SecretName,
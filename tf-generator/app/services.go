@@ -2,6 +2,7 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -18,7 +19,6 @@ import (
 )
 
 const SVC_VAR_PREFIX = "svc_"
-const EXCLUDE_SVC_STR = "duploinfrasvc,dockerservices-shell,system-svc-"
 
 type Services struct {
 }
@@ -26,51 +26,39 @@ type Services struct {
 func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	workingDir := filepath.Join(config.TFCodePath, config.AppProject)
 	list, clientErr := client.ReplicationControllerList(config.TenantId)
-	exclude_svc_list := strings.Split(EXCLUDE_SVC_STR, ",")
 	if clientErr != nil {
 		fmt.Println(clientErr)
 		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
+	// A service which can not be generated is skipped so that the others keep their vars and imports.
+	errs := []error{}
 	if list != nil {
 		log.Println("[TRACE] <====== Duplo Services TF generation started. =====>")
+		// The services reference the secrets and config maps they read. Without the lists these
+		// references would be left out, so a failed read fails the generator.
 		k8sSecretList, clientErr := client.K8SecretGetList(config.TenantId)
 		if clientErr != nil {
-			k8sSecretList = nil
+			fmt.Println(clientErr)
+			return nil, clientErr
 		}
 		configMapList, clientErr := client.K8ConfigMapGetList(config.TenantId)
 		if clientErr != nil {
-			configMapList = nil
+			fmt.Println(clientErr)
+			return nil, clientErr
 		}
 		for _, service := range *list {
 			log.Printf("[TRACE] Generating terraform config for duplo service : %s", service.Name)
-			skip := false
-			for _, element := range exclude_svc_list {
-				if strings.Contains(service.Name, element) {
-					log.Printf("[TRACE] Generating terraform config for duplo service : %s skipped.", service.Name)
-					skip = true
-					break
-				}
-			}
-			if skip {
+			if !config.ResourceAllowed("duplocloud_duplo_service", service.Name, common.TagsFromKeyValues(service.Tags)) {
 				continue
 			}
 			resourceName := common.GetResourceName(service.Name)
 			varFullPrefix := SVC_VAR_PREFIX + resourceName + "_"
-			inputVars := generateSvcVars(service, varFullPrefix)
-			tfContext.InputVars = append(tfContext.InputVars, inputVars...)
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
 
-			// create new file on system
-			path := filepath.Join(workingDir, "svc-"+service.Name+".tf")
-			tfFile, err := os.Create(path)
-			if err != nil {
-				fmt.Println(err)
-				return nil, err
-			}
 			// initialize the body of the new file object
 			rootBody := hclFile.Body()
 			// Add duplocloud_aws_host resource
@@ -118,7 +106,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					otherDockerConfigMap := make(map[string]interface{})
 					err := json.Unmarshal([]byte(service.Template.OtherDockerConfig), &otherDockerConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					if service.Template.AgentPlatform == 7 && k8sSecretList != nil {
 						for _, k8sSecret := range *k8sSecretList {
@@ -229,7 +218,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 
 					otherDockerConfigStr, err := duplosdk.JSONMarshal(otherDockerConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					svcBody.SetAttributeTraversal("other_docker_config", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -242,11 +232,13 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					log.Printf("[TRACE] ExtraConfig *** : %s", service.Template.ExtraConfig)
 					err := json.Unmarshal([]byte(service.Template.ExtraConfig), &extraConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					extraConfigStr, err := duplosdk.JSONMarshal(extraConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					svcBody.SetAttributeTraversal("extra_config", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -258,12 +250,14 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					OtherDockerHostConfigMap := make(map[string]interface{})
 					err := json.Unmarshal([]byte(service.Template.OtherDockerHostConfig), &OtherDockerHostConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					log.Printf("[TRACE] OtherDockerHostConfig *** : %s", service.Template.OtherDockerHostConfig)
 					OtherDockerHostConfigStr, err := duplosdk.JSONMarshal(OtherDockerHostConfigMap)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					svcBody.SetAttributeTraversal("other_docker_host_config", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -298,7 +292,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					log.Printf("[TRACE] HPASpecs *** : %s", service.HPASpecs)
 					hpaSpecsStr, err := duplosdk.JSONMarshal(service.HPASpecs)
 					if err != nil {
-						panic(err)
+						errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+						continue
 					}
 					svcBody.SetAttributeTraversal("hpa_specs", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -317,7 +312,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 						var volConfigMapList []interface{}
 						err := json.Unmarshal([]byte(service.Template.Volumes), &volConfigMapList)
 						if err != nil {
-							panic(err)
+							errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+							continue
 						}
 						if service.Template.AgentPlatform == 7 && k8sSecretList != nil {
 							for _, k8sSecret := range *k8sSecretList {
@@ -356,7 +352,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 						log.Printf("[TRACE] VolConfigMapList *** : %s", volConfigMapList)
 						volConfigMapStr, err := duplosdk.JSONMarshal(volConfigMapList)
 						if err != nil {
-							panic(err)
+							errs = append(errs, common.NewResourceError("duplocloud_duplo_service."+resourceName, err))
+							continue
 						}
 
 						svcBody.SetAttributeTraversal("volumes", hcl.Traversal{
@@ -379,9 +376,11 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 			configList, clientErr := client.ReplicationControllerLbConfigurationList(config.TenantId, service.Name)
 			if clientErr != nil {
 				fmt.Println(clientErr)
-				return nil, clientErr
+				errs = append(errs, common.NewResourceError("duplocloud_duplo_service_lbconfigs."+resourceName+"_config", clientErr))
+				continue
 			}
 			configPresent := false
+			var portErr error
 			if configList != nil && len(*configList) > 0 {
 				configPresent = true
 				svcConfigBlock := rootBody.AppendNewBlock("resource",
@@ -416,8 +415,8 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 						cty.BoolVal(serviceConfig.IsInternal))
 					port, err := strconv.Atoi(serviceConfig.Port)
 					if err != nil {
-						fmt.Println(err)
-						return nil, err
+						portErr = err
+						break
 					}
 					lbConfigBlockBody.SetAttributeValue("port",
 						cty.NumberIntVal(int64(port)))
@@ -446,6 +445,10 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					}
 					//svcConfigBody.AppendNewline()
 				}
+				if portErr != nil {
+					errs = append(errs, common.NewResourceError("duplocloud_duplo_service_lbconfigs."+resourceName+"_config", portErr))
+					continue
+				}
 				if doesReplicationControllerHaveAlbOrNlb(&service) {
 					svcParamBlock := rootBody.AppendNewBlock("resource",
 						[]string{"duplocloud_duplo_service_params",
@@ -482,9 +485,11 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 					if doesReplicationControllerHaveAlb(&service) {
 						webAclId, clientError := client.ReplicationControllerLbWafGet(config.TenantId, service.Name)
 						if clientError != nil {
-							if clientError.Status() == 500 && service.Template.Cloud != 0 {
-								log.Printf("[TRACE] Ignoring error %s for non AWS cloud.", clientError)
+							if clientError.Status() != 500 || service.Template.Cloud == 0 {
+								errs = append(errs, common.NewResourceError("duplocloud_duplo_service_params."+resourceName+"_params", clientError))
+								continue
 							}
+							log.Printf("[TRACE] Ignoring error %s for non AWS cloud.", clientError)
 							webAclId = ""
 						}
 						if len(webAclId) > 0 {
@@ -529,13 +534,21 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 				}
 			}
 
+			// create new file on system
+			path := filepath.Join(workingDir, "svc-"+service.Name+".tf")
+			tfFile, err := os.Create(path)
+			if err != nil {
+				fmt.Println(err)
+				return nil, err
+			}
 			_, err = tfFile.Write(hclFile.Bytes())
 			if err != nil {
 				fmt.Println(err)
 				return nil, err
 			}
+			tfContext.InputVars = append(tfContext.InputVars, generateSvcVars(service, varFullPrefix)...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_duplo_service." + resourceName,
@@ -563,7 +576,7 @@ func (s *Services) Generate(config *common.Config, client *duplosdk.Client) (*co
 		log.Println("[TRACE] <====== Duplo Services TF generation done. =====>")
 	}
 
-	return &tfContext, nil
+	return &tfContext, errors.Join(errs...)
 }
 
 func generateSvcVars(duplo duplosdk.DuploReplicationController, prefix string) []common.VarConfig {
@@ -576,7 +589,7 @@ func generateSvcVars(duplo duplosdk.DuploReplicationController, prefix string) [
 	}
 	varConfigs["docker_image"] = imageIdVar
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -33,6 +33,9 @@ func (agi *ApiGatewayIntegration) Generate(config *common.Config, client *duplos
 			shortName, _ := extractAGIName(client, config.TenantId, agi.Name)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo Api Gateway Integration : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_api_gateway_integration", agi.Name, nil) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -76,7 +79,7 @@ func (agi *ApiGatewayIntegration) Generate(config *common.Config, client *duplos
 			log.Printf("[TRACE] Terraform config is generated for duplo Api Gateway Integration : %s", shortName)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_api_gateway_integration." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
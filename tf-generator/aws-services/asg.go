@@ -36,6 +36,9 @@ func (asg *ASG) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName := asgProfile.FriendlyName[len("duploservices-"+config.TenantName+"-"):len(asgProfile.FriendlyName)]
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo ASG : %s", asgProfile.FriendlyName)
+			if !config.ResourceAllowed("duplocloud_asg_profile", asgProfile.FriendlyName, common.TagsFromKeyValues(asgProfile.Tags)) {
+				continue
+			}
 			varFullPrefix := ASG_VAR_PREFIX + resourceName + "_"
 
 			hclFile := hclwrite.NewEmptyFile()
@@ -203,7 +206,7 @@ func (asg *ASG) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			outVars := generateAsgOutputVars(asgProfile, varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_asg_profile." + resourceName,
 					ResourceId:      config.TenantId + "/" + asgProfile.FriendlyName,
@@ -255,7 +258,7 @@ func generateAsgVars(duplo duplosdk.DuploAsgProfile, prefix string) []common.Var
 	}
 	varConfigs["max_instance_count"] = maxCountVar
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -273,7 +276,7 @@ func generateAsgOutputVars(duplo duplosdk.DuploAsgProfile, prefix, resourceName
 	}
 	outVarConfigs["fullname"] = fullNameVar
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -25,7 +25,7 @@ func (bce *BatchCE) Generate(config *common.Config, client *duplosdk.Client) (*c
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -39,6 +39,9 @@ func (bce *BatchCE) Generate(config *common.Config, client *duplosdk.Client) (*c
 			shortName, _ := duplosdk.UnprefixName(prefix, ce.ComputeEnvironmentName)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Batch Compute Environment : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_batch_compute_environment", ce.ComputeEnvironmentName, ce.Tags) {
+				continue
+			}
 
 			varFullPrefix := BCE_VAR_PREFIX + resourceName + "_"
 
@@ -188,7 +191,7 @@ func (bce *BatchCE) Generate(config *common.Config, client *duplosdk.Client) (*c
 			outVars := generateBatchCEOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_batch_compute_environment." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -214,7 +217,7 @@ func generateBatchCEOutputVars(prefix, resourceName string) []common.OutputVarCo
 	}
 	outVarConfigs["arn"] = var1
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
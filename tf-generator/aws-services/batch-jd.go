@@ -26,7 +26,7 @@ func (bjd *BatchJD) Generate(config *common.Config, client *duplosdk.Client) (*c
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -48,6 +48,9 @@ func (bjd *BatchJD) Generate(config *common.Config, client *duplosdk.Client) (*c
 			shortName, _ := duplosdk.UnprefixName(prefix, jd.JobDefinitionName)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Batch Job Definition: %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_batch_job_definition", jd.JobDefinitionName, jd.Tags) {
+				continue
+			}
 
 			varFullPrefix := BJD_VAR_PREFIX + resourceName + "_"
 
@@ -159,7 +162,7 @@ func (bjd *BatchJD) Generate(config *common.Config, client *duplosdk.Client) (*c
 				}
 				containerPropsStr, err := duplosdk.JSONMarshal(jd.ContainerProperties)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_aws_batch_job_definition."+resourceName, err)
 				}
 				containerPropsTokens = append(containerPropsTokens, &hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(containerPropsStr)})
 				containerPropsTokens = append(containerPropsTokens, &hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte("\n")})
@@ -191,7 +194,7 @@ func (bjd *BatchJD) Generate(config *common.Config, client *duplosdk.Client) (*c
 			outVars := generateBatchJDOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_batch_job_definition." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -217,7 +220,7 @@ func generateBatchJDOutputVars(prefix, resourceName string) []common.OutputVarCo
 	}
 	outVarConfigs["arn"] = var1
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -26,7 +26,7 @@ func (bjq *BatchQ) Generate(config *common.Config, client *duplosdk.Client) (*co
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -42,6 +42,9 @@ func (bjq *BatchQ) Generate(config *common.Config, client *duplosdk.Client) (*co
 			shortName, _ := duplosdk.UnprefixName(prefix, q.JobQueueName)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Batch Job Queue: %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_batch_job_queue", q.JobQueueName, q.Tags) {
+				continue
+			}
 
 			varFullPrefix := BJQ_VAR_PREFIX + resourceName + "_"
 
@@ -167,7 +170,7 @@ func (bjq *BatchQ) Generate(config *common.Config, client *duplosdk.Client) (*co
 			outVars := generateBatchJQOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_batch_job_queue." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -193,7 +196,7 @@ func generateBatchJQOutputVars(prefix, resourceName string) []common.OutputVarCo
 	}
 	outVarConfigs["arn"] = var1
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
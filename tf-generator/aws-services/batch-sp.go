@@ -25,7 +25,7 @@ func (bsp *BatchSP) Generate(config *common.Config, client *duplosdk.Client) (*c
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -39,6 +39,9 @@ func (bsp *BatchSP) Generate(config *common.Config, client *duplosdk.Client) (*c
 			shortName, _ := duplosdk.UnprefixName(prefix, sp.Name)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Batch Scheduling policy : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_batch_scheduling_policy", sp.Name, sp.Tags) {
+				continue
+			}
 
 			varFullPrefix := BCE_VAR_PREFIX + resourceName + "_"
 
@@ -125,7 +128,7 @@ func (bsp *BatchSP) Generate(config *common.Config, client *duplosdk.Client) (*c
 			outVars := generateBatchSPOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_batch_scheduling_policy." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -151,7 +154,7 @@ func generateBatchSPOutputVars(prefix, resourceName string) []common.OutputVarCo
 	}
 	outVarConfigs["arn"] = var1
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -35,6 +35,9 @@ func (byoh *BYOH) Generate(config *common.Config, client *duplosdk.Client) (*com
 			shortName := byoh.Name
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo byoh Instance : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_byoh", byoh.Name, common.TagsFromKeyValues(byoh.Tags)) {
+				continue
+			}
 
 			varFullPrefix := BYOH_VAR_PREFIX + resourceName + "_"
 			// create new empty hcl file object
@@ -93,12 +96,22 @@ func (byoh *BYOH) Generate(config *common.Config, client *duplosdk.Client) (*com
 						cty.StringVal(cred.Username))
 				}
 				if len(cred.Password) > 0 {
-					byohBody.SetAttributeValue("password",
-						cty.StringVal(cred.Password))
+					config.SetSecretAttribute(byohBody, rootBody, &tfContext, common.Secret{
+						Project:      config.AwsServicesProject,
+						ResourceType: "duplocloud_byoh",
+						ResourceName: resourceName,
+						Attribute:    "password",
+						Value:        cred.Password,
+					})
 				}
 				if len(cred.Privatekey) > 0 {
-					byohBody.SetAttributeValue("private_key",
-						cty.StringVal(cred.Privatekey))
+					config.SetSecretAttribute(byohBody, rootBody, &tfContext, common.Secret{
+						Project:      config.AwsServicesProject,
+						ResourceType: "duplocloud_byoh",
+						ResourceName: resourceName,
+						Attribute:    "private_key",
+						Value:        cred.Privatekey,
+					})
 				}
 			}
 			//fmt.Printf("%s", hclFile.Bytes())
@@ -114,7 +127,7 @@ func (byoh *BYOH) Generate(config *common.Config, client *duplosdk.Client) (*com
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_byoh." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -148,7 +161,7 @@ func generateBYOHOutputVars(prefix, resourceName string) []common.OutputVarConfi
 	}
 	outVarConfigs["network_agent_url"] = var2
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -29,7 +29,7 @@ func (cfd *CFD) Generate(config *common.Config, client *duplosdk.Client) (*commo
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -44,6 +44,9 @@ func (cfd *CFD) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName, _ := duplosdk.UnprefixName(prefix, cfd.Comment)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Cloudfront Distribution : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_cloudfront_distribution", shortName, nil) {
+				continue
+			}
 
 			varFullPrefix := CFD_VAR_PREFIX + resourceName + "_"
 
@@ -489,7 +492,7 @@ func (cfd *CFD) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			outVars := generateCFDOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_cloudfront_distribution." + resourceName,
 					ResourceId:      config.TenantId + "/" + cfd.Id,
@@ -531,7 +534,7 @@ func generateCFDOutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["domain_name"] = var3
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
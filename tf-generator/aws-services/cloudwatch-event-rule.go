@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
 
@@ -24,14 +25,19 @@ func (cwer *CloudwatchEventRule) Generate(config *common.Config, client *duplosd
 		fmt.Println(clientErr)
 		return nil, clientErr
 	}
-	tfContext := common.TFContext{}
+	tfContext := common.TFContext{
+		ArnRefs: map[string]string{},
+	}
 	importConfigs := []common.ImportConfig{}
 	if list != nil {
 		log.Println("[TRACE] <====== Cloudwatch event rules TF generation started. =====>")
 		for _, cwer := range *list {
-			shortName := cwer.Name[len("duploservices-"+config.TenantName+"-"):len(cwer.Name)]
+			shortName := strings.TrimPrefix(cwer.Name, "duploservices-"+config.TenantName+"-")
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo Cloudwatch event rules : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_cloudwatch_event_rule", cwer.Name, nil) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -83,11 +89,15 @@ func (cwer *CloudwatchEventRule) Generate(config *common.Config, client *duplosd
 				cwerBody.SetAttributeValue("state",
 					cty.StringVal(cwer.State.Value))
 			}
-			targetList, _ := client.DuploCloudWatchEventTargetsList(config.TenantId, cwer.Name)
+			targetList, clientErr := client.DuploCloudWatchEventTargetsList(config.TenantId, cwer.Name)
+			if clientErr != nil {
+				fmt.Println(clientErr)
+				return nil, clientErr
+			}
 			if targetList != nil && len(*targetList) > 0 {
 				rootBody.AppendNewline()
 				for _, target := range *targetList {
-					targetResourceName := resourceName + "-target"
+					targetResourceName := resourceName + "_" + common.GetResourceName(target.Id)
 					cwetBlock := rootBody.AppendNewBlock("resource",
 						[]string{"duplocloud_aws_cloudwatch_event_target",
 							targetResourceName})
@@ -108,8 +118,13 @@ func (cwer *CloudwatchEventRule) Generate(config *common.Config, client *duplosd
 							Name: "fullname",
 						},
 					})
+					// The functions, queues, topics and ECS cluster of the tenant are referenced once the
+					// project is generated. See ReferenceLiterals.
 					cwetBody.SetAttributeValue("target_arn",
 						cty.StringVal(target.Arn))
+					if isTenantEcsCluster(target.Arn, config) {
+						tfContext.ArnRefs[target.Arn] = `"arn:aws:ecs:${local.region}:${data.aws_caller_identity.current.account_id}:cluster/duploservices-${local.tenant_name}"`
+					}
 					cwetBody.SetAttributeValue("target_id",
 						cty.StringVal(target.Id))
 					if len(target.RoleArn) > 0 {
@@ -120,7 +135,7 @@ func (cwer *CloudwatchEventRule) Generate(config *common.Config, client *duplosd
 						cwetBody.SetAttributeValue("event_bus_name",
 							cty.StringVal(cwer.EventBusName))
 					}
-					if config.GenerateTfState {
+					if config.ImportsEnabled() {
 						importConfigs = append(importConfigs, common.ImportConfig{
 							ResourceAddress: "duplocloud_aws_cloudwatch_event_target." + targetResourceName,
 							ResourceId:      config.TenantId + "/" + cwer.Name + "/" + target.Id,
@@ -136,20 +151,26 @@ func (cwer *CloudwatchEventRule) Generate(config *common.Config, client *duplosd
 				fmt.Println(err)
 				return nil, err
 			}
-			log.Printf("[TRACE] Terraform config is generated for duplo Cloudwatch metrics : %s", shortName)
+			log.Printf("[TRACE] Terraform config is generated for duplo Cloudwatch event rule : %s", shortName)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_cloudwatch_event_rule." + resourceName,
 					ResourceId:      config.TenantId + "/" + cwer.Name,
 					WorkingDir:      workingDir,
 				})
-				tfContext.ImportConfigs = importConfigs
 			}
 		}
+		tfContext.ImportConfigs = importConfigs
 		log.Println("[TRACE] <====== Cloudwatch event rule TF generation done. =====>")
 	}
 
 	return &tfContext, nil
 }
+
+// isTenantEcsCluster reports whether an ARN is the ECS cluster of the tenant, arn:aws:ecs:<region>:<account>:cluster/duploservices-<tenant>.
+func isTenantEcsCluster(arn string, config *common.Config) bool {
+	parts := strings.SplitN(arn, ":", 6)
+	return len(parts) == 6 && parts[2] == "ecs" && parts[5] == "cluster/duploservices-"+config.TenantName
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"tenant-terraform-generator/duplosdk"
@@ -35,7 +36,15 @@ func (cwm *CloudwatchMetrics) Generate(config *common.Config, client *duplosdk.C
 		rdsList, _ := client.RdsInstanceList(config.TenantId)
 		dynamoDBList, _ := client.TenantDynamoDBList(config.TenantId)
 		log.Println("[TRACE] <====== Cloudwatch metrics TF generation started. =====>")
-		for i, cwm := range *list {
+		// The alarms are numbered, so they are sorted to keep their names when the API order changes.
+		alarms := append([]duplosdk.DuploCloudWatchMetricAlarm{}, *list...)
+		sort.SliceStable(alarms, func(i, j int) bool {
+			if alarms[i].Name != alarms[j].Name {
+				return alarms[i].Name < alarms[j].Name
+			}
+			return alarms[i].MetricName < alarms[j].MetricName
+		})
+		for i, cwm := range alarms {
 			friendlyNames := []string{}
 			namespace := strings.Split(cwm.Namespace, "/")[0]
 			if len(strings.Split(cwm.Namespace, "/")) > 1 {
@@ -44,6 +53,9 @@ func (cwm *CloudwatchMetrics) Generate(config *common.Config, client *duplosdk.C
 			shortName := cwm.MetricName + "-" + namespace + "-" + strconv.Itoa(i+1)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo Cloudwatch metrics : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_cloudwatch_metric_alarm", shortName, nil) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -173,7 +185,7 @@ func (cwm *CloudwatchMetrics) Generate(config *common.Config, client *duplosdk.C
 			log.Printf("[TRACE] Terraform config is generated for duplo Cloudwatch metrics : %s", shortName)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_cloudwatch_metric_alarm." + resourceName,
 					ResourceId:      config.TenantId + "/" + strings.Join(friendlyNames, "-"),
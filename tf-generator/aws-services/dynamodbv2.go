@@ -34,6 +34,9 @@ func (dynamodb *DynamoDB) Generate(config *common.Config, client *duplosdk.Clien
 			shortName, _ := extractDynamoDBName(client, config.TenantId, dynamodb.Name)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for DynamoDB : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_dynamodb_table_v2", dynamodb.Name, nil) {
+				continue
+			}
 
 			dynamodbInfo, clientErr := client.DynamoDBTableGetV2(config.TenantId, dynamodb.Name)
 			if clientErr != nil {
@@ -203,7 +206,7 @@ func (dynamodb *DynamoDB) Generate(config *common.Config, client *duplosdk.Clien
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_dynamodb_table_v2." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -236,7 +239,7 @@ func generateDynamoDBOutputVars(prefix, resourceName string) []common.OutputVarC
 	}
 	outVarConfigs["arn"] = var2
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
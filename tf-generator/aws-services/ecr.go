@@ -36,6 +36,9 @@ func (ecr *ECR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName := ecr.Name
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS ECR : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_ecr_repository", ecr.Name, nil) {
+				continue
+			}
 
 			varFullPrefix := ECR_VAR_PREFIX + resourceName + "_"
 
@@ -95,7 +98,7 @@ func (ecr *ECR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_ecr_repository." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -136,7 +139,7 @@ func generateECROutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["repository_url"] = var3
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -34,6 +34,9 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName, _ := extractEMRShortName(client, config.TenantId, emr.Name)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo EMR Instance : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_emr_cluster", emr.Name, nil) {
+				continue
+			}
 
 			emrInfo, clientErr := client.DuploEmrClusterGet(config.TenantId, emr.JobFlowId)
 			if clientErr != nil {
@@ -102,7 +105,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.Applications != nil {
 				appsStr, err := duplosdk.JSONMarshal(emrInfo.Applications)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("applications", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -113,7 +116,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.BootstrapActions != nil {
 				bootstrapActionsMapStr, err := duplosdk.JSONMarshal(emrInfo.BootstrapActions)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("bootstrap_actions", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -124,7 +127,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.Configurations != nil {
 				configurationsMapStr, err := duplosdk.JSONMarshal(emrInfo.Configurations)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("configurations", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -135,7 +138,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.Steps != nil {
 				stepsMapStr, err := duplosdk.JSONMarshal(emrInfo.Steps)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("steps", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -147,11 +150,11 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				var additionalInfoMap interface{}
 				err := json.Unmarshal([]byte(emrInfo.AdditionalInfo), &additionalInfoMap)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				additionalInfoMapStr, err := duplosdk.JSONMarshal(additionalInfoMap)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("additional_info", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -162,7 +165,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.ManagedScalingPolicy != nil {
 				managedScalingPolicyMapStr, err := duplosdk.JSONMarshal(emrInfo.ManagedScalingPolicy)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("managed_scaling_policy", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -173,7 +176,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			if emrInfo.InstanceFleets != nil {
 				instanceFleetsMapStr, err := duplosdk.JSONMarshal(emrInfo.InstanceFleets)
 				if err != nil {
-					panic(err)
+					return nil, common.NewResourceError("duplocloud_emr_cluster."+resourceName, err)
 				}
 				emrBody.SetAttributeTraversal("instance_fleets", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -223,7 +226,7 @@ func (emr *EMR) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_emr_cluster." + resourceName,
 					ResourceId:      config.TenantId + "/" + emr.JobFlowId,
@@ -265,7 +268,7 @@ func generateEMROutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["job_flow_id"] = var3
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -44,6 +44,9 @@ func (es *ES) Generate(config *common.Config, client *duplosdk.Client) (*common.
 			shortName := es.Name
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo Elastic Search : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_elasticsearch", es.Name, nil) {
+				continue
+			}
 
 			varFullPrefix := ES_VAR_PREFIX + resourceName + "_"
 			inputVars := generateESVars(es, varFullPrefix)
@@ -166,7 +169,7 @@ func (es *ES) Generate(config *common.Config, client *duplosdk.Client) (*common.
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_elasticsearch." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -204,7 +207,7 @@ func generateESVars(duplo duplosdk.DuploElasticSearchDomain, prefix string) []co
 	}
 	varConfigs["selected_zone"] = var3
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -254,7 +257,7 @@ func generateESOutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["endpoints"] = var5
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -26,7 +26,7 @@ func (h *Hosts) Generate(config *common.Config, client *duplosdk.Client) (*commo
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	tfContext := common.TFContext{}
 	importConfigs := []common.ImportConfig{}
@@ -42,6 +42,9 @@ func (h *Hosts) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			}
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo host : %s", host.FriendlyName)
+			if !config.ResourceAllowed("duplocloud_aws_host", host.FriendlyName, common.TagsFromKeyValues(host.Tags)) {
+				continue
+			}
 			if isPartOfAsg(host) {
 				continue
 			}
@@ -195,7 +198,7 @@ func (h *Hosts) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			outVars := generateHostOutputVars(host, varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_host." + resourceName,
 					ResourceId:      "v2/subscriptions/" + config.TenantId + "/NativeHostV2/" + host.InstanceID,
@@ -237,7 +240,7 @@ func generateHostVars(duplo duplosdk.DuploNativeHost, prefix string) []common.Va
 	}
 	varConfigs["capacity"] = capacityVar
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -262,7 +265,7 @@ func generateHostOutputVars(duplo duplosdk.DuploNativeHost, prefix, resourceName
 	}
 	outVarConfigs["private_ip_address"] = var2
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
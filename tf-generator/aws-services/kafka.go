@@ -36,12 +36,14 @@ func (k *Kafka) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName := kafka.Name[len("duploservices-"+config.TenantName+"-"):len(kafka.Name)]
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo kafka Instance : %s", shortName)
-
 			clusterInfo, clientErr := client.TenantGetKafkaClusterInfo(config.TenantId, kafka.Arn)
 			if clientErr != nil {
 				fmt.Println(clientErr)
 				return nil, clientErr
 			}
+			if !config.ResourceAllowed("duplocloud_aws_kafka_cluster", kafka.Name, common.TagsFromMap(clusterInfo.Tags)) {
+				continue
+			}
 			varFullPrefix := KAFKA_VAR_PREFIX + resourceName + "_"
 			inputVars := generateKafkaVars(clusterInfo, varFullPrefix)
 			tfContext.InputVars = append(tfContext.InputVars, inputVars...)
@@ -134,7 +136,7 @@ func (k *Kafka) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_kafka_cluster." + resourceName,
 					ResourceId:      "v2/subscriptions/" + config.TenantId + "/ECacheDBInstance/" + shortName,
@@ -173,7 +175,7 @@ func generateKafkaVars(duplo *duplosdk.DuploKafkaClusterInfo, prefix string) []c
 	}
 	varConfigs["storage_size"] = var3
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -239,7 +241,7 @@ func generateKafkaOutputVars(prefix, resourceName string) []common.OutputVarConf
 	}
 	outVarConfigs["tls_zookeeper_connect_string"] = var7
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
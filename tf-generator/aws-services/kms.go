@@ -0,0 +1,95 @@
+package awsservices
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"tenant-terraform-generator/duplosdk"
+	"tenant-terraform-generator/tf-generator/common"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type KMS struct {
+}
+
+// Generate writes the KMS keys of the plan usable by the tenant as data sources. The key of the
+// tenant is read by aws-services-main. Both are returned so that the project references them.
+func (k *KMS) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
+	log.Println("[TRACE] <====== KMS keys TF generation started. =====>")
+	workingDir := filepath.Join(config.TFCodePath, config.AwsServicesProject)
+	keys, clientErr := client.TenantGetAllKmsKeys(config.TenantId)
+	if clientErr != nil {
+		fmt.Println(clientErr)
+		return nil, clientErr
+	}
+	tfContext := common.TFContext{}
+	hclFile := hclwrite.NewEmptyFile()
+	rootBody := hclFile.Body()
+	names := map[string]bool{}
+	for i, key := range keys {
+		if i == 0 {
+			// The key of the tenant.
+			tfContext.KmsKeys = append(tfContext.KmsKeys, common.KmsKeyConfig{
+				KeyArn: key.KeyArn,
+				KeyId:  key.KeyID,
+				ArnRef: "data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn",
+				IdRef:  "data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id",
+			})
+			continue
+		}
+		if len(key.KeyName) == 0 || !config.ResourceAllowed("duplocloud_plan_kms", key.KeyName, nil) {
+			continue
+		}
+		resourceName := common.GetResourceName(key.KeyName)
+		if names[resourceName] {
+			log.Printf("[TRACE] KMS key %s has the name of another key, it is skipped.", key.KeyName)
+			continue
+		}
+		names[resourceName] = true
+		log.Printf("[TRACE] Generating terraform config for duplo KMS key : %s", key.KeyName)
+
+		kmsBody := rootBody.AppendNewBlock("data",
+			[]string{"duplocloud_plan_kms",
+				resourceName}).Body()
+		kmsBody.SetAttributeTraversal("plan_id", hcl.Traversal{
+			hcl.TraverseRoot{
+				Name: "var",
+			},
+			hcl.TraverseAttr{
+				Name: "plan_id",
+			},
+		})
+		kmsBody.SetAttributeValue("name",
+			cty.StringVal(key.KeyName))
+		rootBody.AppendNewline()
+
+		// Resources take the ARN of a plan key wherever they take its ID.
+		ref := "data.duplocloud_plan_kms." + resourceName + ".arn"
+		tfContext.KmsKeys = append(tfContext.KmsKeys, common.KmsKeyConfig{
+			KeyArn: key.KeyArn,
+			KeyId:  key.KeyID,
+			ArnRef: ref,
+			IdRef:  ref,
+		})
+	}
+	if len(names) > 0 {
+		path := filepath.Join(workingDir, "kms.tf")
+		err := os.WriteFile(path, hclwrite.Format(hclFile.Bytes()), 0644)
+		if err != nil {
+			fmt.Println(err)
+			return nil, err
+		}
+		tfContext.InputVars = append(tfContext.InputVars, common.VarConfig{
+			Name:       "plan_id",
+			DefaultVal: config.DuploPlanId,
+			TypeVal:    "string",
+			DescVal:    "The plan whose KMS keys are used by the tenant.",
+		})
+	}
+	log.Println("[TRACE] <====== KMS keys TF generation done. =====>")
+	return &tfContext, nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"tenant-terraform-generator/duplosdk"
@@ -31,7 +32,9 @@ func (lf *LambdaFunction) Generate(config *common.Config, client *duplosdk.Clien
 	}
 	s3s, _ := client.TenantListS3Buckets(config.TenantId)
 
-	tfContext := common.TFContext{}
+	tfContext := common.TFContext{
+		ArnRefs: map[string]string{},
+	}
 	importConfigs := []common.ImportConfig{}
 	if list != nil {
 		log.Println("[TRACE] <====== Lambda Function TF generation started. =====>")
@@ -39,12 +42,14 @@ func (lf *LambdaFunction) Generate(config *common.Config, client *duplosdk.Clien
 			shortName := lf.Name
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for lammbda funtion : %s", shortName)
-
 			lfDetails, clientErr := client.LambdaFunctionGet(config.TenantId, lf.FunctionName)
 			if clientErr != nil {
 				fmt.Println(clientErr)
 				continue
 			}
+			if !config.ResourceAllowed("duplocloud_aws_lambda_function", lf.FunctionName, lfDetails.Tags) {
+				continue
+			}
 			varFullPrefix := LF_VAR_PREFIX + resourceName + "_"
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -187,7 +192,9 @@ func (lf *LambdaFunction) Generate(config *common.Config, client *duplosdk.Clien
 			// Lambda Permission Resource
 			lfPermission, _ := client.LambdaPermissionGet(config.TenantId, lf.FunctionName)
 			if lfPermission != nil && len(*lfPermission) > 0 {
-				for i, lfPerm := range *lfPermission {
+				permissions := append([]duplosdk.DuploLambdaPermissionStatement{}, *lfPermission...)
+				sort.SliceStable(permissions, func(i, j int) bool { return permissions[i].Sid < permissions[j].Sid })
+				for i, lfPerm := range permissions {
 					index := strconv.Itoa(i)
 					lfPermBlock := rootBody.AppendNewBlock("resource",
 						[]string{"duplocloud_aws_lambda_permission",
@@ -227,9 +234,12 @@ func (lf *LambdaFunction) Generate(config *common.Config, client *duplosdk.Clien
 
 			outVars := generateLFOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
+			if len(lfDetails.Configuration.FunctionArn) > 0 {
+				tfContext.ArnRefs[lfDetails.Configuration.FunctionArn] = "duplocloud_aws_lambda_function." + resourceName + ".arn"
+			}
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_lambda_function." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -270,7 +280,7 @@ func generateLFOutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["version"] = var3
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -294,7 +304,7 @@ func generatelambdaVars(lf duplosdk.DuploLambdaConfiguration, prefix string) []c
 		}
 		varConfigs["s3_key"] = var2
 	}
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
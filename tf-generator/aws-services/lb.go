@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"tenant-terraform-generator/duplosdk"
@@ -40,6 +41,9 @@ func (lb *LoadBalancer) Generate(config *common.Config, client *duplosdk.Client)
 				fmt.Println(err)
 				return nil, err
 			}
+			if !config.ResourceAllowed("duplocloud_aws_load_balancer", lb.Name, common.TagsFromKeyValues(lb.Tags)) {
+				continue
+			}
 			settings, err := client.TenantGetApplicationLbSettings(config.TenantId, lb.Arn)
 			if err != nil {
 				fmt.Println(err)
@@ -210,7 +214,7 @@ func (lb *LoadBalancer) Generate(config *common.Config, client *duplosdk.Client)
 					}
 
 					// Add all listener Rules
-					appendListenerRuleResources(listener.ListenerArn, listenerResourceName, rootBody, config, client, importConfigs, workingDir)
+					appendListenerRuleResources(listener.ListenerArn, listenerResourceName, rootBody, &tfContext, config, client, importConfigs, workingDir)
 				}
 			}
 
@@ -227,7 +231,7 @@ func (lb *LoadBalancer) Generate(config *common.Config, client *duplosdk.Client)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_load_balancer." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -278,21 +282,32 @@ func generateLBOutputVars(prefix, resourceName string) []common.OutputVarConfig
 	}
 	outVarConfigs["dns_name"] = var3
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
 	return outVars
 }
 
-func appendListenerRuleResources(listenerArn string, listenerResourceName string, body *hclwrite.Body, config *common.Config, client *duplosdk.Client, importConfigs []common.ImportConfig, workingDir string) {
+func appendListenerRuleResources(listenerArn string, listenerResourceName string, body *hclwrite.Body, tfContext *common.TFContext, config *common.Config, client *duplosdk.Client, importConfigs []common.ImportConfig, workingDir string) {
 	listenerRules, clientErr := client.DuploAwsLbListenerRuleList(config.TenantId, listenerArn)
 	if clientErr != nil {
 		fmt.Println(clientErr)
 		listenerRules = nil
 	}
 	if listenerRules != nil {
-		for i, listenerRule := range *listenerRules {
+		// The rules are numbered in priority order, not in the order of the API. The default rule
+		// comes first as it does in the API, so the numbers of the rules do not change.
+		rules := append([]duplosdk.DuploAwsLbListenerRule{}, *listenerRules...)
+		sort.SliceStable(rules, func(i, j int) bool {
+			a, errA := strconv.Atoi(rules[i].Priority)
+			b, errB := strconv.Atoi(rules[j].Priority)
+			if errA != nil || errB != nil {
+				return errA != nil && errB == nil
+			}
+			return a < b
+		})
+		for i, listenerRule := range rules {
 			if listenerRule.IsDefault {
 				continue
 			}
@@ -424,7 +439,13 @@ func appendListenerRuleResources(listenerArn string, listenerResourceName string
 							oidcBody := oidcBlock.Body()
 							oidcBody.SetAttributeValue("authorization_endpoint", cty.StringVal(action.AuthenticateOidcConfig.AuthorizationEndpoint))
 							oidcBody.SetAttributeValue("client_id", cty.StringVal(action.AuthenticateOidcConfig.ClientId))
-							oidcBody.SetAttributeValue("client_secret", cty.StringVal(action.AuthenticateOidcConfig.ClientSecret))
+							config.SetSecretAttribute(oidcBody, body, tfContext, common.Secret{
+								Project:      config.AwsServicesProject,
+								ResourceType: "duplocloud_aws_lb_listener_rule",
+								ResourceName: listenerRuleResourceName,
+								Attribute:    "action.authenticate_oidc.client_secret",
+								Value:        action.AuthenticateOidcConfig.ClientSecret,
+							})
 							oidcBody.SetAttributeValue("issuer", cty.StringVal(action.AuthenticateOidcConfig.Issuer))
 							oidcBody.SetAttributeValue("token_endpoint", cty.StringVal(action.AuthenticateOidcConfig.TokenEndpoint))
 							oidcBody.SetAttributeValue("user_info_endpoint", cty.StringVal(action.AuthenticateOidcConfig.UserInfoEndpoint))
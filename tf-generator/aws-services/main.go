@@ -8,11 +8,8 @@ import (
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
 
-	"github.com/hashicorp/hcl/v2/hclsyntax"
-
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
-	"github.com/zclconf/go-cty/cty"
 )
 
 type AwsServicesMain struct {
@@ -20,11 +17,6 @@ type AwsServicesMain struct {
 
 func (asm *AwsServicesMain) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
 	workingDir := filepath.Join(config.TFCodePath, config.AwsServicesProject)
-	defaultInfraConfig, clientErr := client.InfrastructureGetConfig("default")
-	if clientErr != nil {
-		fmt.Println(clientErr)
-		return nil, clientErr
-	}
 	log.Println("[TRACE] <====== AWS services main TF generation started. =====>")
 
 	//1. ==========================================================================================
@@ -59,12 +51,7 @@ func (asm *AwsServicesMain) Generate(config *common.Config, client *duplosdk.Cli
 	localsBlock := rootBody.AppendNewBlock("locals",
 		nil)
 	localsBlockBody := localsBlock.Body()
-	tfstateBucketTokens := hclwrite.Tokens{
-		{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
-		{Type: hclsyntax.TokenIdent, Bytes: []byte(`duplo-tfstate-${data.aws_caller_identity.current.account_id}`)},
-		{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
-	}
-	localsBlockBody.SetAttributeRaw("tfstate_bucket", tfstateBucketTokens)
+	config.StateBackend().WriteLocals(localsBlockBody)
 
 	localsBlockBody.SetAttributeTraversal("region", hcl.Traversal{
 		hcl.TraverseRoot{
@@ -118,86 +105,8 @@ func (asm *AwsServicesMain) Generate(config *common.Config, client *duplosdk.Cli
 		[]string{"terraform_remote_state",
 			"tenant"})
 	remoteStateBody := remoteStateBlock.Body()
-	remoteStateBody.SetAttributeValue("backend",
-		cty.StringVal("s3"))
-	remoteStateBody.SetAttributeTraversal("workspace", hcl.Traversal{
-		hcl.TraverseRoot{
-			Name: "terraform",
-		},
-		hcl.TraverseAttr{
-			Name: "workspace",
-		},
-	})
-
-	//configMap := map[string]cty.Value{}
-	// tokens := hclwrite.Tokens{
-	// 	//{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
-	// 	//{Type: hclsyntax.TokenTemplateInterp, Bytes: []byte(`${`)},
-	// 	{Type: hclsyntax.TokenIdent, Bytes: []byte(`local.tfstate_bucket`)},
-	// 	//{Type: hclsyntax.TokenTemplateSeqEnd, Bytes: []byte(`}`)},
-	// 	//{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
-	// }
-	//remoteStateBody.SetAttributeRaw("bucket", tokens)
-
-	// configTokens := map[string]hclwrite.Tokens{
-	// 	"bucket": hclwrite.TokensForTraversal(hcl.Traversal{
-	// 		hcl.TraverseRoot{Name: "local"},
-	// 		hcl.TraverseAttr{Name: "tfstate_bucket"},
-	// 	}),
-	// 	"workspace_key_prefix": hclwrite.TokensForValue(cty.StringVal("admin:")),
-	// 	"key":                  hclwrite.TokensForValue(cty.StringVal("tenant")),
-	// 	"region": hclwrite.TokensForTraversal(hcl.Traversal{
-	// 		hcl.TraverseRoot{Name: "local"},
-	// 		hcl.TraverseAttr{Name: "region"},
-	// 	}),
-	// }
-	configTokens := []common.ObjectAttrTokens{
-		{
-			Name: hclwrite.TokensForTraversal(hcl.Traversal{
-				hcl.TraverseRoot{Name: "bucket"},
-			}),
-			Value: hclwrite.TokensForTraversal(hcl.Traversal{
-				hcl.TraverseRoot{Name: "local"},
-				hcl.TraverseAttr{Name: "tfstate_bucket"},
-			}),
-		},
-		{
-			Name: hclwrite.TokensForTraversal(hcl.Traversal{
-				hcl.TraverseRoot{Name: "workspace_key_prefix"},
-			}),
-			Value: hclwrite.TokensForValue(cty.StringVal("admin:")),
-		},
-		{
-			Name: hclwrite.TokensForTraversal(hcl.Traversal{
-				hcl.TraverseRoot{Name: "key"},
-			}),
-			Value: hclwrite.TokensForValue(cty.StringVal("tenant")),
-		},
-		{
-			Name: hclwrite.TokensForTraversal(hcl.Traversal{
-				hcl.TraverseRoot{Name: "region"},
-			}),
-			// Value: hclwrite.TokensForTraversal(hcl.Traversal{
-			// 	hcl.TraverseRoot{Name: "local"},
-			// 	hcl.TraverseAttr{Name: "region"},
-			// }),
-			Value: hclwrite.TokensForValue(cty.StringVal(defaultInfraConfig.Region)),
-		},
-	}
-	tokens := common.TokensForObject(configTokens)
-	remoteStateBody.SetAttributeRaw("config", tokens)
-	// 	cty.ObjectVal(configMap))
-
-	// configMap["bucket"] = cty.StringVal("${local.tfstate_bucket}")
-	// configMap["workspace_key_prefix"] = cty.StringVal("admin:")
-	// configMap["key"] = cty.StringVal("tenant")
-	// configMap["region"] = cty.StringVal("${local.region}")
-
-	// //configMap["region"] = cty.CapsuleVal("${local.region}")
-	// remoteStateBody.SetAttributeValue("config",
-	// 	cty.ObjectVal(configMap))
+	config.StateBackend().WriteRemoteState(remoteStateBody, common.TenantProjectState(config), config)
 
-	fmt.Printf("%s", hclFile.Bytes())
 	_, err = tfFile.Write(hclFile.Bytes())
 	if err != nil {
 		fmt.Println(err)
@@ -219,7 +128,7 @@ func generateVars(config *common.Config) []common.VarConfig {
 	}
 	varConfigs["region"] = regionVar
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
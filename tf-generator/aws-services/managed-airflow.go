@@ -25,7 +25,7 @@ func (mwaa *MWAA) Generate(config *common.Config, client *duplosdk.Client) (*com
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -40,13 +40,15 @@ func (mwaa *MWAA) Generate(config *common.Config, client *duplosdk.Client) (*com
 			shortName, _ := duplosdk.UnprefixName(prefix, mwaa.Name)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Apache Airflow : %s", mwaa.Name)
-
 			varFullPrefix := MWAA_VAR_PREFIX + resourceName + "_"
 			mwaaDetails, clientErr := client.MwaaAirflowDetailsGet(config.TenantId, mwaa.Name)
 			if clientErr != nil {
 				fmt.Println(clientErr)
 				return nil, clientErr
 			}
+			if !config.ResourceAllowed("duplocloud_aws_mwaa_environment", mwaa.Name, common.TagsFromMap(mwaaDetails.Tags)) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -244,7 +246,7 @@ func (mwaa *MWAA) Generate(config *common.Config, client *duplosdk.Client) (*com
 			outVars := generateMWAAOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_mwaa_environment." + resourceName,
 					ResourceId:      config.TenantId + "/" + mwaa.Name,
@@ -278,7 +280,7 @@ func generateMWAAOutputVars(prefix, resourceName string) []common.OutputVarConfi
 	}
 	outVarConfigs["arn"] = var2
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
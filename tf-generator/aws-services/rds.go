@@ -38,6 +38,9 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 			shortName := rds.Identifier[len("duplo"):len(rds.Identifier)]
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo RDS Instance : %s", rds.Identifier)
+			if !config.ResourceAllowed("duplocloud_rds_instance", rds.Identifier, nil) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -162,7 +165,7 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 				outVars := generateRdsOutputVars(varFullPrefix, resourceName, "duplocloud_rds_read_replica")
 				tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 				// Import all created resources.
-				if config.GenerateTfState {
+				if config.ImportsEnabled() {
 					importConfigs = append(importConfigs, common.ImportConfig{
 						ResourceAddress: "duplocloud_rds_read_replica." + resourceName,
 						ResourceId:      "v2/subscriptions/" + config.TenantId + "/RDSDBInstance/" + shortName,
@@ -177,15 +180,17 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 				inputVars := generateRdsVars(rds, varFullPrefix)
 				tfContext.InputVars = append(tfContext.InputVars, inputVars...)
 
-				if len(rds.MasterPassword) > 0 {
-					randomBlock := rootBody.AppendNewBlock("resource",
-						[]string{"random_password",
-							resourceName + "_password"})
-					randomBody := randomBlock.Body()
-					randomBody.SetAttributeValue("length",
-						cty.NumberIntVal(int64(16)))
-					randomBody.SetAttributeValue("special",
-						cty.BoolVal(false))
+				// The blocks a password reads are written before the instance.
+				var masterPassword hclwrite.Tokens
+				if len(rds.SnapshotID) == 0 {
+					masterPassword = config.SecretTokens(rootBody, &tfContext, common.Secret{
+						Project:      config.AwsServicesProject,
+						ResourceType: "duplocloud_rds_instance",
+						ResourceName: resourceName,
+						Attribute:    "master_password",
+						Value:        rds.MasterPassword,
+						Name:         resourceName + "_password",
+					})
 				}
 				// Add duplocloud_rds_instance resource
 				rdsBlock := rootBody.AppendNewBlock("resource",
@@ -243,14 +248,7 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 							Name: varFullPrefix + "master_username",
 						},
 					})
-					rdsBody.SetAttributeTraversal("master_password", hcl.Traversal{
-						hcl.TraverseRoot{
-							Name: " random_password." + resourceName + "_password",
-						},
-						hcl.TraverseAttr{
-							Name: "result",
-						},
-					})
+					rdsBody.SetAttributeRaw("master_password", masterPassword)
 				}
 
 				if len(rds.DBParameterGroupName) > 0 {
@@ -267,6 +265,10 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 						Name: varFullPrefix + "encrypt_storage",
 					},
 				})
+				if rds.EncryptStorage && len(rds.EncryptionKmsKeyId) > 0 {
+					rdsBody.SetAttributeValue("kms_key_id",
+						cty.StringVal(rds.EncryptionKmsKeyId))
+				}
 				rdsBody.SetAttributeValue("enable_logging",
 					cty.BoolVal(rds.EnableLogging))
 				rdsBody.SetAttributeValue("multi_az",
@@ -314,14 +316,16 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 							Name: varFullPrefix + "performance_insights_retention_period",
 						},
 					})
-					inightBody.SetAttributeTraversal("kms_key_id", hcl.Traversal{
-						hcl.TraverseRoot{
-							Name: "var",
-						},
-						hcl.TraverseAttr{
-							Name: varFullPrefix + "performance_insights_kms_key_id",
-						},
-					})
+					if rds.PerformanceInsightsKMSKeyId != "" {
+						inightBody.SetAttributeTraversal("kms_key_id", hcl.Traversal{
+							hcl.TraverseRoot{
+								Name: "var",
+							},
+							hcl.TraverseAttr{
+								Name: varFullPrefix + "performance_insights_kms_key_id",
+							},
+						})
+					}
 				}
 				rdsBody.SetAttributeTraversal("enhanced_monitoring", hcl.Traversal{
 					hcl.TraverseRoot{
@@ -339,7 +343,7 @@ func (r *Rds) Generate(config *common.Config, client *duplosdk.Client) (*common.
 				outVars := generateRdsOutputVars(varFullPrefix, resourceName, "duplocloud_rds_instance")
 				tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 				// Import all created resources.
-				if config.GenerateTfState {
+				if config.ImportsEnabled() {
 					importConfigs = append(importConfigs, common.ImportConfig{
 						ResourceAddress: "duplocloud_rds_instance." + resourceName,
 						ResourceId:      "v2/subscriptions/" + config.TenantId + "/RDSDBInstance/" + shortName,
@@ -423,7 +427,7 @@ func generateRdsRRVars(duplo duplosdk.DuploRdsInstance, prefix string) []common.
 		varConfigs["min_capacity"] = var7
 	}
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -509,7 +513,7 @@ func generateRdsVars(duplo duplosdk.DuploRdsInstance, prefix string) []common.Va
 		TypeVal:    "number",
 	}
 	varConfigs["enhanced_monitoring"] = var10
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -559,7 +563,7 @@ func generateRdsOutputVars(prefix, resourceName string, resourceType string) []c
 	}
 	outVarConfigs["port"] = var5
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
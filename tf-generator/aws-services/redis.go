@@ -39,6 +39,9 @@ func (r *Redis) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			shortName := redis.Identifier[len("duplo-"):len(redis.Identifier)]
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo Redis Instance : %s", redis.Identifier)
+			if !config.ResourceAllowed("duplocloud_ecache_instance", redis.Identifier, nil) {
+				continue
+			}
 
 			varFullPrefix := REDIS_VAR_PREFIX + resourceName + "_"
 			inputVars := generateRedisVars(redis, varFullPrefix)
@@ -159,7 +162,7 @@ func (r *Redis) Generate(config *common.Config, client *duplosdk.Client) (*commo
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_ecache_instance." + resourceName,
 					ResourceId:      "v2/subscriptions/" + config.TenantId + "/ECacheDBInstance/" + shortName,
@@ -205,7 +208,7 @@ func generateRedisVars(duplo duplosdk.DuploEcacheInstance, prefix string) []comm
 	}
 	varConfigs["engine_version"] = var3
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -255,7 +258,7 @@ func generateRedisOutputVars(prefix, resourceName string) []common.OutputVarConf
 	}
 	outVarConfigs["port"] = var5
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -44,6 +44,9 @@ func (s3 *S3Bucket) Generate(config *common.Config, client *duplosdk.Client) (*c
 			}
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo s3 bucket : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_s3_bucket", s3.Name, common.TagsFromKeyValues(s3.Tags)) {
+				continue
+			}
 			varFullPrefix := S3_VAR_PREFIX + resourceName + "_"
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -138,7 +141,7 @@ func (s3 *S3Bucket) Generate(config *common.Config, client *duplosdk.Client) (*c
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_s3_bucket." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -176,7 +179,7 @@ func generateS3Vars(duplo *duplosdk.DuploS3Bucket, prefix string) []common.VarCo
 	}
 	varConfigs["enable_versioning"] = var3
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -202,7 +205,7 @@ func generateS3OutputVars(duplo duplosdk.DuploS3Bucket, prefix, resourceName str
 	}
 	outVarConfigs["arn"] = arnVar
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
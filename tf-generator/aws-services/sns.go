@@ -32,7 +32,9 @@ func (sns *SNS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 		fmt.Println(clientErr)
 		return nil, clientErr
 	}
-	tfContext := common.TFContext{}
+	tfContext := common.TFContext{
+		ArnRefs: map[string]string{},
+	}
 	importConfigs := []common.ImportConfig{}
 	if list != nil {
 		for _, sns := range *list {
@@ -43,6 +45,9 @@ func (sns *SNS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				return nil, err
 			}
 			log.Printf("[TRACE] Generating terraform config for duplo SNS Topic : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_sns_topic", sns.Name, nil) {
+				continue
+			}
 			varFullPrefix := SNS_VAR_PREFIX + resourceName + "_"
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -84,9 +89,11 @@ func (sns *SNS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 
 			outVars := generateSnsOutputVars(sns, varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
+			// The name of a topic is its ARN.
+			tfContext.ArnRefs[sns.Name] = "duplocloud_aws_sns_topic." + resourceName + ".arn"
 
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_sns_topic." + resourceName,
 					ResourceId:      config.TenantId + "/" + sns.Name,
@@ -111,7 +118,7 @@ func generateSnsOutputVars(duplo duplosdk.DuploAwsResource, prefix, resourceName
 	}
 	outVarConfigs["arn"] = arnVar
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
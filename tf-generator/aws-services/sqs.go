@@ -3,6 +3,7 @@ package awsservices
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,7 +29,9 @@ func (sqs *SQS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 		fmt.Println(clientErr)
 		return nil, clientErr
 	}
-	tfContext := common.TFContext{}
+	tfContext := common.TFContext{
+		ArnRefs: map[string]string{},
+	}
 	if list != nil {
 		for _, sqs := range *list {
 			shortName, err := extractSqsName(client, config.TenantId, sqs.Name)
@@ -37,6 +40,9 @@ func (sqs *SQS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 				return nil, err
 			}
 			log.Printf("[TRACE] Generating terraform config for duplo SQS : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_sqs_queue", sqs.Name, nil) {
+				continue
+			}
 			varFullPrefix := SQS_VAR_PREFIX + resourceName + "_"
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -111,16 +117,17 @@ func (sqs *SQS) Generate(config *common.Config, client *duplosdk.Client) (*commo
 
 			outVars := generateSQSOutputVars(sqs, varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
+			if arn := sqsQueueArn(sqs.Url); len(arn) > 0 {
+				tfContext.ArnRefs[arn] = "duplocloud_aws_sqs_queue." + resourceName + ".arn"
+			}
 
 			// Import all created resources.
-			if config.GenerateTfState {
-				importConfigs := []common.ImportConfig{}
-				importConfigs = append(importConfigs, common.ImportConfig{
+			if config.ImportsEnabled() {
+				tfContext.ImportConfigs = append(tfContext.ImportConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_sqs_queue." + resourceName,
 					ResourceId:      config.TenantId + "/" + sqs.Name,
 					WorkingDir:      workingDir,
 				})
-				tfContext.ImportConfigs = importConfigs
 			}
 		}
 	}
@@ -139,7 +146,7 @@ func generateSQSOutputVars(duplo duplosdk.DuploSQSQueue, prefix, resourceName st
 	}
 	outVarConfigs["url"] = urlVar
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -162,3 +169,17 @@ func extractSqsName(client *duplosdk.Client, tenantID string, sqsUrl string) (st
 	name, _ := duplosdk.UnwrapName(prefix, accountID, fullname, true)
 	return name, nil
 }
+
+// sqsQueueArn returns the ARN of the queue at https://sqs.<region>.amazonaws.com/<account>/<name>.
+func sqsQueueArn(queueUrl string) string {
+	u, err := url.Parse(queueUrl)
+	if err != nil {
+		return ""
+	}
+	host := strings.Split(u.Host, ".")
+	path := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(host) < 2 || host[0] != "sqs" || len(path) != 2 {
+		return ""
+	}
+	return "arn:aws:sqs:" + host[1] + ":" + path[0] + ":" + path[1]
+}
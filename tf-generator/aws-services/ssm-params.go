@@ -37,6 +37,9 @@ func (ssmParams *SsmParams) Generate(config *common.Config, client *duplosdk.Cli
 			shortName := ssmParam.Name
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo SSM Parameter : %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_ssm_parameter", ssmParam.Name, nil) {
+				continue
+			}
 
 			// create new empty hcl file object
 			hclFile := hclwrite.NewEmptyFile()
@@ -81,13 +84,23 @@ func (ssmParams *SsmParams) Generate(config *common.Config, client *duplosdk.Cli
 			ssmParamBody.SetAttributeValue("type",
 				cty.StringVal(ssmParam.Type))
 
-			if len(ssmDetails.Value) > 0 {
+			if ssmParam.Type == "SecureString" {
+				// The decrypted value is written according to the secret policies.
+				config.SetSecretAttribute(ssmParamBody, rootBody, &tfContext, common.Secret{
+					Project:      config.AwsServicesProject,
+					ResourceType: "duplocloud_aws_ssm_parameter",
+					ResourceName: resourceName,
+					Attribute:    "value",
+					Value:        ssmDetails.Value,
+					Name:         SSM_VAR_PREFIX + strings.Trim(resourceName, "_"),
+				})
+			} else if len(ssmDetails.Value) > 0 {
 				valueMap := make(map[string]interface{})
 				err := json.Unmarshal([]byte(ssmDetails.Value), &valueMap)
 				if err == nil {
 					valueMapStr, err := duplosdk.JSONMarshal(valueMap)
 					if err != nil {
-						panic(err)
+						return nil, common.NewResourceError("duplocloud_aws_ssm_parameter."+resourceName, err)
 					}
 					ssmParamBody.SetAttributeTraversal("value", hcl.Traversal{
 						hcl.TraverseRoot{
@@ -117,10 +130,6 @@ func (ssmParams *SsmParams) Generate(config *common.Config, client *duplosdk.Cli
 				}
 			}
 
-			if ssmParam.Type == "SecureString" {
-				ssmParamBody.SetAttributeValue("value", cty.StringVal(ssmDetails.Value))
-			}
-
 			if len(ssmDetails.Description) > 0 {
 				ssmParamBody.SetAttributeValue("description",
 					cty.StringVal(ssmDetails.Description))
@@ -135,6 +144,12 @@ func (ssmParams *SsmParams) Generate(config *common.Config, client *duplosdk.Cli
 				ssmParamBody.SetAttributeValue("allowed_pattern",
 					cty.StringVal(ssmDetails.AllowedPattern))
 			}
+			if ssmParam.Type == "SecureString" && config.Secrets.Policy("duplocloud_aws_ssm_parameter", "value") == common.SECRET_POLICY_PLACEHOLDER {
+				// The placeholder must not replace the value set in AWS.
+				lifecycleBody := ssmParamBody.AppendNewBlock("lifecycle", nil).Body()
+				lifecycle := common.StringSliceToListVal([]string{"value"})
+				lifecycleBody.SetAttributeValue("ignore_changes", cty.ListVal(lifecycle))
+			}
 			//fmt.Printf("%s", hclFile.Bytes())
 			_, err = tfFile.Write(hclFile.Bytes())
 			if err != nil {
@@ -144,14 +159,12 @@ func (ssmParams *SsmParams) Generate(config *common.Config, client *duplosdk.Cli
 			log.Printf("[TRACE] Terraform config is generated for duplo SSM parameter : %s", shortName)
 
 			// Import all created resources.
-			if config.GenerateTfState {
-				importConfigs := []common.ImportConfig{}
-				importConfigs = append(importConfigs, common.ImportConfig{
+			if config.ImportsEnabled() {
+				tfContext.ImportConfigs = append(tfContext.ImportConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_ssm_parameter." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
 					WorkingDir:      workingDir,
 				})
-				tfContext.ImportConfigs = importConfigs
 			}
 		}
 	}
@@ -25,7 +25,7 @@ func (tdb *TimestreamDB) Generate(config *common.Config, client *duplosdk.Client
 
 	if clientErr != nil {
 		fmt.Println(clientErr)
-		return nil, nil
+		return nil, clientErr
 	}
 	prefix, clientErr := client.GetDuploServicesPrefix(config.TenantId)
 	if clientErr != nil {
@@ -39,6 +39,9 @@ func (tdb *TimestreamDB) Generate(config *common.Config, client *duplosdk.Client
 			shortName, _ := duplosdk.UnprefixName(prefix, tsdb.DatabaseName)
 			resourceName := common.GetResourceName(shortName)
 			log.Printf("[TRACE] Generating terraform config for duplo AWS Timestream DB: %s", shortName)
+			if !config.ResourceAllowed("duplocloud_aws_timestreamwrite_database", tsdb.DatabaseName, common.TagsFromKeyValues(tsdb.Tags)) {
+				continue
+			}
 
 			varFullPrefix := TTDB_VAR_PREFIX + resourceName + "_"
 
@@ -99,7 +102,7 @@ func (tdb *TimestreamDB) Generate(config *common.Config, client *duplosdk.Client
 			tblList, clientErr := client.DuploTimestreamDBTableGetList(config.TenantId, tsdb.DatabaseName)
 			if clientErr != nil {
 				fmt.Println(clientErr)
-				return nil, nil
+				return nil, clientErr
 			}
 			if tblList != nil && len(*tblList) > 0 {
 				for _, tstbl := range *tblList {
@@ -193,7 +196,7 @@ func (tdb *TimestreamDB) Generate(config *common.Config, client *duplosdk.Client
 						}
 					}
 
-					if config.GenerateTfState {
+					if config.ImportsEnabled() {
 						importConfigs = append(importConfigs, common.ImportConfig{
 							ResourceAddress: "duplocloud_aws_timestreamwrite_table." + resourceName + "_" + tblResourceName,
 							ResourceId:      config.TenantId + "/" + tstbl.TableName,
@@ -217,7 +220,7 @@ func (tdb *TimestreamDB) Generate(config *common.Config, client *duplosdk.Client
 			outVars := generateTimestreamDBOutputVars(varFullPrefix, resourceName)
 			tfContext.OutputVars = append(tfContext.OutputVars, outVars...)
 			// Import all created resources.
-			if config.GenerateTfState {
+			if config.ImportsEnabled() {
 				importConfigs = append(importConfigs, common.ImportConfig{
 					ResourceAddress: "duplocloud_aws_timestreamwrite_database." + resourceName,
 					ResourceId:      config.TenantId + "/" + shortName,
@@ -243,7 +246,7 @@ func generateTimestreamDBOutputVars(prefix, resourceName string) []common.Output
 	}
 	outVarConfigs["arn"] = var1
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -0,0 +1,180 @@
+package tfgenerator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+	"testing"
+)
+
+// TestGenerateBackends checks that the backend of each project and the remote state reading the
+// tenant project are written from the same backend setting.
+func TestGenerateBackends(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      map[string]string
+		backend     string
+		remoteState string
+		// tenantName is the tenant name of the tenant project, which is the workspace unless the state is named after the tenant.
+		tenantName string
+	}{
+		{
+			name:        "gcs",
+			values:      map[string]string{"backend": "gcs", "backend_bucket": "acme-tfstate", "backend_key_prefix": "duplo/"},
+			backend:     "backend \"gcs\" {\n    bucket = \"acme-tfstate\"\n    prefix = \"duplo/tenant/app\"\n  }",
+			remoteState: "backend   = \"gcs\"\n  workspace = terraform.workspace\n  config = {\n    bucket = \"acme-tfstate\"\n    prefix = \"duplo/admin/tenant\"\n  }",
+		},
+		{
+			name:        "azurerm",
+			values:      map[string]string{"backend": "azurerm", "backend_resource_group": "tfstate", "backend_storage_account": "acmetfstate", "backend_container": "duplo"},
+			backend:     "key                  = \"tenant/app.tfstate\"",
+			remoteState: "key                  = \"admin/tenant.tfstate\"",
+		},
+		{
+			name:        "http",
+			values:      map[string]string{"backend": "http", "backend_address": "https://state.acme.invalid/"},
+			backend:     "backend \"http\" {\n    address = \"https://state.acme.invalid/" + testTenantName + "-tenant-app\"\n  }",
+			remoteState: "backend = \"http\"\n  config = {\n    address = \"https://state.acme.invalid/" + testTenantName + "-admin-tenant\"\n  }",
+			tenantName:  "\"" + testTenantName + "\"",
+		},
+		{
+			name:        "cloud",
+			values:      map[string]string{"backend": "cloud", "backend_organization": "acme"},
+			backend:     "cloud {\n    organization = \"acme\"\n    workspaces {\n      name = \"" + testTenantName + "-tenant-app\"\n    }\n  }",
+			remoteState: "backend = \"remote\"\n  config = {\n    organization = \"acme\"\n    workspaces = {\n      name = \"" + testTenantName + "-admin-tenant\"\n    }\n  }",
+			tenantName:  "\"" + testTenantName + "\"",
+		},
+		{
+			name:        "local",
+			values:      map[string]string{"backend": "local", "backend_path": "../../state"},
+			backend:     "backend \"local\" {\n    path          = \"../../state/tenant-app.tfstate\"\n    workspace_dir = \"../../state/tenant-app.tfstate.d\"\n  }",
+			remoteState: "path          = \"../../state/admin-tenant.tfstate\"",
+		},
+		{
+			name:        "no backend",
+			values:      map[string]string{"s3_backend": "false", "backend": "gcs", "backend_bucket": "acme-tfstate"},
+			remoteState: "backend   = \"local\"\n  workspace = terraform.workspace\n  config = {\n    path          = \"../admin-tenant/terraform.tfstate\"\n    workspace_dir = \"../admin-tenant/terraform.tfstate.d\"\n  }",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			config := testConfig(t, targetDir, test.values)
+			client, _ := testClient(t)
+			generateFixtureTenant(t, config, client)
+			files, err := readFiles(filepath.Join(targetDir, "terraform", config.AppProject))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(test.backend) == 0 {
+				if _, ok := files["backend.tf"]; ok {
+					t.Errorf("the backend should not be generated:\n%s", files["backend.tf"])
+				}
+			} else if !strings.Contains(files["backend.tf"], test.backend) {
+				t.Errorf("got backend.tf\n%s\nexpected\n%s", files["backend.tf"], test.backend)
+			}
+			if !strings.Contains(files["main.tf"], test.remoteState) {
+				t.Errorf("got main.tf\n%s\nexpected\n%s", files["main.tf"], test.remoteState)
+			}
+			tenantMain, err := os.ReadFile(filepath.Join(targetDir, "terraform", config.TenantProject, "main.tf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tenantName := test.tenantName
+			if len(tenantName) == 0 {
+				tenantName = "terraform.workspace"
+			}
+			if !strings.Contains(string(tenantMain), "tenant_name = "+tenantName) {
+				t.Errorf("the tenant name should be %s:\n%s", tenantName, tenantMain)
+			}
+			if strings.Contains(files["main.tf"], "tfstate_bucket") {
+				t.Errorf("only the s3 backend reads the bucket of the account:\n%s", files["main.tf"])
+			}
+		})
+	}
+}
+
+func TestBackendSettings(t *testing.T) {
+	for _, values := range []map[string]string{
+		{"backend": "gcs"},
+		{"backend": "azurerm", "backend_resource_group": "tfstate", "backend_storage_account": "acmetfstate"},
+		{"backend": "consul"},
+	} {
+		env := map[string]string{
+			"duplo_host":    "https://fixtures.duplocloud.invalid",
+			"duplo_token":   "fixture-token",
+			"tenant_name":   testTenantName,
+			"customer_name": "duplo-fixtures",
+		}
+		for key, value := range values {
+			env[key] = value
+		}
+		validator := common.EnvVarValidator{
+			Lookup: func(key string) (string, bool) {
+				value, ok := env[key]
+				return value, ok
+			},
+		}
+		_, err := validator.Validate()
+		if err == nil || !strings.Contains(err.Error(), "backend") {
+			t.Errorf("backend settings %v should be rejected, got %v", values, err)
+		}
+	}
+}
+
+// TestScriptReplacements checks that the scripts pass the settings of the backend to terraform
+// init and only select workspaces when the backend has them.
+func TestScriptReplacements(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        map[string]string
+		backendConfig string
+		workspaces    string
+	}{
+		{
+			name:          "s3 bucket of the account",
+			values:        map[string]string{},
+			backendConfig: "-backend-config=bucket=duplo-tfstate-${AWS_ACCOUNT_ID} -backend-config=dynamodb_table=duplo-tfstate-${AWS_ACCOUNT_ID}-lock",
+			workspaces:    "true",
+		},
+		{
+			name:          "s3 lock table",
+			values:        map[string]string{"backend_dynamodb_table": "acme-lock"},
+			backendConfig: "-backend-config=bucket=duplo-tfstate-${AWS_ACCOUNT_ID}",
+			workspaces:    "true",
+		},
+		{
+			name:       "s3 bucket",
+			values:     map[string]string{"backend_bucket": "acme-tfstate"},
+			workspaces: "true",
+		},
+		{
+			name:       "gcs",
+			values:     map[string]string{"backend": "gcs", "backend_bucket": "acme-tfstate"},
+			workspaces: "true",
+		},
+		{
+			name:       "http",
+			values:     map[string]string{"backend": "http", "backend_address": "https://state.acme.invalid"},
+			workspaces: "false",
+		},
+		{
+			name:       "cloud",
+			values:     map[string]string{"backend": "cloud", "backend_organization": "acme"},
+			workspaces: "false",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := testConfig(t, t.TempDir(), test.values)
+			replacements := scriptReplacements(config)
+			if replacements["<--backend-config-->"] != test.backendConfig {
+				t.Errorf("got backend config %q, expected %q", replacements["<--backend-config-->"], test.backendConfig)
+			}
+			if replacements["<--workspaces-->"] != test.workspaces {
+				t.Errorf("got workspaces %q, expected %q", replacements["<--workspaces-->"], test.workspaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// COLLECTION_RESOURCE_NAME is the name of the resource holding every item of a collection.
+const COLLECTION_RESOURCE_NAME = "this"
+
+// collectionType is a resource type written as a single resource with for_each in collection mode.
+// Its items are the values of Var in the config of the project.
+type collectionType struct {
+	ResourceType string
+	Var          string
+	File         string
+	Description  string
+}
+
+var collectionTypes = []collectionType{
+	{ResourceType: "duplocloud_aws_sqs_queue", Var: "sqs_queues", File: "sqs.tf", Description: "SQS queues of the tenant keyed by resource name."},
+	{ResourceType: "duplocloud_aws_sns_topic", Var: "sns_topics", File: "sns.tf", Description: "SNS topics of the tenant keyed by resource name."},
+	{ResourceType: "duplocloud_s3_bucket", Var: "s3_buckets", File: "s3.tf", Description: "S3 buckets of the tenant keyed by resource name."},
+	{ResourceType: "duplocloud_k8_config_map", Var: "k8s_config_maps", File: "k8s-cm.tf", Description: "Kubernetes config maps of the tenant keyed by resource name."},
+}
+
+// collectionMetaArguments can not differ between the items of a collection.
+var collectionMetaArguments = map[string]bool{"count": true, "for_each": true, "provider": true, "depends_on": true, "lifecycle": true, "provisioner": true, "connection": true}
+
+// collectionEvalContext evaluates the literal values of the generated code, jsonencode is used for
+// documents like the data of config maps.
+var collectionEvalContext = &hcl.EvalContext{Functions: map[string]function.Function{"jsonencode": stdlib.JSONEncodeFunc}}
+
+type collectionItem struct {
+	label string
+	file  string
+	block *hclsyntax.Block
+	src   []byte
+}
+
+// collection is the resource written for the items of a collection type.
+type collection struct {
+	collectionType
+	items []*collectionItem
+	// code is the resource block with for_each.
+	code string
+	// values are the attributes of each item, keyed by its resource name.
+	values  map[string]map[string]interface{}
+	typeVal string
+	// consumedVars are the variables whose value moved into the items.
+	consumedVars map[string]bool
+}
+
+// CollectProject writes the resources of each collection type of a generated project as a single
+// resource with for_each over a map variable whose values are written to the config of the project.
+// References to the items, outputs and import configs use their key like
+// duplocloud_aws_sqs_queue.this["orders_events"] and moved.tf moves them from their old address. A
+// type whose items can not share a resource, like items with different meta arguments, is left
+// as it is.
+func CollectProject(projectDir string, tfContext *common.TFContext) error {
+	paths, err := filepath.Glob(filepath.Join(projectDir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	sources := map[string][]byte{}
+	bodies := map[string]*hclsyntax.Body{}
+	itemsByType := map[string][]*collectionItem{}
+	for _, path := range paths {
+		file := filepath.Base(path)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return diags
+		}
+		body := hclFile.Body.(*hclsyntax.Body)
+		sources[file], bodies[file] = src, body
+		for _, block := range body.Blocks {
+			if block.Type == "resource" && len(block.Labels) == 2 && block.Labels[1] != COLLECTION_RESOURCE_NAME {
+				itemsByType[block.Labels[0]] = append(itemsByType[block.Labels[0]], &collectionItem{label: block.Labels[1], file: file, block: block, src: src})
+			}
+		}
+	}
+
+	inputVars := map[string]common.VarConfig{}
+	for _, inputVar := range tfContext.InputVars {
+		if !inputVar.Sensitive {
+			inputVars[inputVar.Name] = inputVar
+		}
+	}
+	collections := []*collection{}
+	// items maps the address of every item onto its address in the collection.
+	items := map[string]string{}
+	for _, ct := range collectionTypes {
+		if len(itemsByType[ct.ResourceType]) == 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(projectDir, ct.File)); err == nil {
+			log.Printf("[TRACE] %s is already written, %s are not collected.", ct.File, ct.ResourceType)
+			continue
+		}
+		c := &collection{collectionType: ct, items: itemsByType[ct.ResourceType]}
+		sort.Slice(c.items, func(i, j int) bool { return c.items[i].label < c.items[j].label })
+		err = c.build(inputVars)
+		if err != nil {
+			log.Printf("[TRACE] %s are not collected, %s", ct.ResourceType, err)
+			continue
+		}
+		collections = append(collections, c)
+		for _, item := range c.items {
+			items[ct.ResourceType+"."+item.label] = collectionAddress(ct.ResourceType, item.label)
+		}
+	}
+	if len(collections) == 0 {
+		return nil
+	}
+
+	// Items are removed from their files and references to them point at their key.
+	usedVars := map[string]bool{}
+	for _, path := range paths {
+		file := filepath.Base(path)
+		src := sources[file]
+		edits := []moduleEdit{}
+		for _, block := range bodies[file].Blocks {
+			if block.Type == "resource" && len(block.Labels) == 2 {
+				if _, ok := items[block.Labels[0]+"."+block.Labels[1]]; ok {
+					end := block.Range().End.Byte
+					if end < len(src) && src[end] == '\n' {
+						end++
+					}
+					edits = append(edits, moduleEdit{start: block.Range().Start.Byte, end: end})
+					continue
+				}
+			}
+			if block.Type == "import" || block.Type == "moved" {
+				continue
+			}
+			walkAttributes(block.Body, func(name string, attribute *hclsyntax.Attribute, nested bool) {
+				for _, traversal := range attribute.Expr.Variables() {
+					if traversal.RootName() == "var" && len(traversal) > 1 {
+						if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+							usedVars[attr.Name] = true
+						}
+					}
+					if len(traversal) < 2 {
+						continue
+					}
+					attr, ok := traversal[1].(hcl.TraverseAttr)
+					if !ok {
+						continue
+					}
+					address, ok := items[traversal.RootName()+"."+attr.Name]
+					if !ok {
+						continue
+					}
+					if name == "depends_on" && !nested {
+						// depends_on refers to the whole resource.
+						address = traversal.RootName() + "." + COLLECTION_RESOURCE_NAME
+					}
+					edits = append(edits, traversalEdit(traversal, 2, address))
+				}
+			})
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		content := applyEdits(src, edits)
+		if len(strings.TrimSpace(string(content))) == 0 {
+			err = os.Remove(path)
+		} else {
+			err = os.WriteFile(path, hclwrite.Format(content), 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, c := range collections {
+		err = os.WriteFile(filepath.Join(projectDir, c.File), hclwrite.Format([]byte(c.code)), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	inputVarList := []common.VarConfig{}
+	for _, inputVar := range tfContext.InputVars {
+		consumed := false
+		for _, c := range collections {
+			consumed = consumed || c.consumedVars[inputVar.Name]
+		}
+		for _, outputVar := range tfContext.OutputVars {
+			usedVars[inputVar.Name] = usedVars[inputVar.Name] || containsAddress(outputVar.ActualVal, "var."+inputVar.Name)
+		}
+		if !consumed || usedVars[inputVar.Name] {
+			inputVarList = append(inputVarList, inputVar)
+		}
+	}
+	for _, c := range collections {
+		inputVarList = append(inputVarList, common.VarConfig{
+			Name:      c.Var,
+			TypeVal:   c.typeVal,
+			DescVal:   c.Description,
+			ConfigVal: c.values,
+		})
+	}
+	tfContext.InputVars = inputVarList
+	for address, newAddress := range items {
+		for i := range tfContext.OutputVars {
+			tfContext.OutputVars[i].ActualVal = replaceAddress(tfContext.OutputVars[i].ActualVal, address, newAddress)
+		}
+		for i := range tfContext.ImportConfigs {
+			if tfContext.ImportConfigs[i].ResourceAddress == address {
+				tfContext.ImportConfigs[i].ResourceAddress = newAddress
+			}
+		}
+	}
+	return writeMovedBlocks(projectDir, items)
+}
+
+// collectionAddress is the address of an item in its collection.
+func collectionAddress(resourceType, key string) string {
+	return resourceType + "." + COLLECTION_RESOURCE_NAME + "[" + strconv.Quote(key) + "]"
+}
+
+// replaceAddress replaces an address in a text. Longer addresses starting with it are kept.
+func replaceAddress(text, address, replacement string) string {
+	var out strings.Builder
+	for {
+		i := strings.Index(text, address)
+		if i < 0 {
+			out.WriteString(text)
+			return out.String()
+		}
+		end := i + len(address)
+		whole := (i == 0 || !isAddressChar(text[i-1])) && (end == len(text) || !isAddressChar(text[end]))
+		out.WriteString(text[:i])
+		if whole {
+			out.WriteString(replacement)
+		} else {
+			out.WriteString(address)
+		}
+		text = text[end:]
+	}
+}
+
+func containsAddress(text, address string) bool {
+	return replaceAddress(text, address, "") != text
+}
+
+func isAddressChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// collectionAttribute is an attribute of the items of a collection, either the same expression for
+// every item or a value of each item.
+type collectionAttribute struct {
+	name   string
+	static string
+	types  map[string]bool
+	count  int
+}
+
+// build writes the resource of a collection and the values of its items.
+func (c *collection) build(inputVars map[string]common.VarConfig) error {
+	c.values = map[string]map[string]interface{}{}
+	c.consumedVars = map[string]bool{}
+	attributes := map[string]*collectionAttribute{}
+	order := []string{}
+	nestedBlocks := map[string]*collectionAttribute{}
+	nestedOrder := []string{}
+	nestedFields := map[string]map[string]*collectionAttribute{}
+	for _, item := range c.items {
+		values := map[string]interface{}{}
+		for _, attribute := range sortedAttributes(item.block.Body) {
+			ca, ok := attributes[attribute.Name]
+			if !ok {
+				ca = &collectionAttribute{name: attribute.Name, types: map[string]bool{}}
+				attributes[attribute.Name] = ca
+				order = append(order, attribute.Name)
+			}
+			ca.count++
+			value, varName, ok := literalValue(attribute.Expr, inputVars)
+			if ok && !collectionMetaArguments[attribute.Name] {
+				if len(ca.static) > 0 {
+					return fmt.Errorf("%s of %s is not a value", attribute.Name, item.label)
+				}
+				raw, err := ctyjson.Marshal(value, value.Type())
+				if err != nil {
+					return err
+				}
+				values[attribute.Name] = json.RawMessage(raw)
+				ca.types[typeConstraint(value)] = true
+				if len(varName) > 0 {
+					c.consumedVars[varName] = true
+				}
+				continue
+			}
+			source := exprSource(attribute.Expr, item.src)
+			if len(ca.types) > 0 || (len(ca.static) > 0 && ca.static != source) {
+				return fmt.Errorf("%s of %s differs between the resources", attribute.Name, item.label)
+			}
+			ca.static = source
+		}
+		blocksByType := map[string][]*hclsyntax.Block{}
+		for _, block := range item.block.Body.Blocks {
+			if _, ok := blocksByType[block.Type]; !ok {
+				nestedOrder = appendMissing(nestedOrder, block.Type)
+			}
+			blocksByType[block.Type] = append(blocksByType[block.Type], block)
+		}
+		for _, blockType := range nestedOrder {
+			blocks, ok := blocksByType[blockType]
+			if !ok {
+				continue
+			}
+			cb, ok := nestedBlocks[blockType]
+			if !ok {
+				cb = &collectionAttribute{name: blockType, types: map[string]bool{}}
+				nestedBlocks[blockType] = cb
+				nestedFields[blockType] = map[string]*collectionAttribute{}
+			}
+			cb.count++
+			source := []string{}
+			for _, block := range blocks {
+				source = append(source, string(block.Range().SliceBytes(item.src)))
+			}
+			cb.types[strings.Join(source, "\n")] = true
+			if collectionMetaArguments[blockType] && len(cb.types) > 1 {
+				return fmt.Errorf("%s of %s differs between the resources", blockType, item.label)
+			}
+			blockValues := []map[string]json.RawMessage{}
+			for _, block := range blocks {
+				blockValue := map[string]json.RawMessage{}
+				if len(block.Body.Blocks) > 0 || len(block.Labels) > 0 {
+					blockValue = nil
+				}
+				for name, attribute := range block.Body.Attributes {
+					value, _, ok := literalValue(attribute.Expr, nil)
+					if !ok {
+						blockValue = nil
+						break
+					}
+					raw, err := ctyjson.Marshal(value, value.Type())
+					if err != nil {
+						return err
+					}
+					blockValue[name] = json.RawMessage(raw)
+					field, ok := nestedFields[blockType][name]
+					if !ok {
+						field = &collectionAttribute{name: name, types: map[string]bool{}}
+						nestedFields[blockType][name] = field
+					}
+					field.count++
+					field.types[typeConstraint(value)] = true
+				}
+				if blockValue == nil {
+					blockValues = nil
+					break
+				}
+				blockValues = append(blockValues, blockValue)
+			}
+			if blockValues == nil {
+				values[blockType] = nil
+			} else {
+				values[blockType] = blockValues
+			}
+		}
+		c.values[item.label] = values
+	}
+
+	var code strings.Builder
+	types := []string{}
+	fmt.Fprintf(&code, "resource %q %q {\n  for_each = var.%s\n", c.ResourceType, COLLECTION_RESOURCE_NAME, c.Var)
+	for _, name := range order {
+		ca := attributes[name]
+		if len(ca.static) > 0 {
+			if ca.count != len(c.items) {
+				return fmt.Errorf("%s is only set on some of the resources", name)
+			}
+			fmt.Fprintf(&code, "  %s = %s\n", name, ca.static)
+			continue
+		}
+		fmt.Fprintf(&code, "  %s = each.value.%s\n", name, name)
+		types = append(types, fmt.Sprintf("%s = %s", name, optionalType(mergedType(ca.types), ca.count < len(c.items), "")))
+	}
+	for _, blockType := range nestedOrder {
+		cb := nestedBlocks[blockType]
+		if len(cb.types) == 1 && cb.count == len(c.items) {
+			// The same for every item, so it is written once.
+			for source := range cb.types {
+				fmt.Fprintf(&code, "  %s\n", source)
+			}
+			for label := range c.values {
+				delete(c.values[label], blockType)
+			}
+			continue
+		}
+		if collectionMetaArguments[blockType] {
+			return fmt.Errorf("%s differs between the resources", blockType)
+		}
+		fields := []string{}
+		contents := []string{}
+		for _, name := range sortedKeys(nestedFields[blockType]) {
+			field := nestedFields[blockType][name]
+			fields = append(fields, fmt.Sprintf("%s = %s", name, optionalType(mergedType(field.types), field.count < cb.count, "")))
+			contents = append(contents, fmt.Sprintf("      %s = %s.value.%s\n", name, blockType, name))
+		}
+		for label, values := range c.values {
+			if value, ok := values[blockType]; ok && value == nil {
+				return fmt.Errorf("%s of %s is not a value", blockType, label)
+			}
+		}
+		fmt.Fprintf(&code, "  dynamic %q {\n    for_each = each.value.%s\n    content {\n%s    }\n  }\n", blockType, blockType, strings.Join(contents, ""))
+		types = append(types, fmt.Sprintf("%s = %s", blockType, optionalType("list(object({ "+strings.Join(fields, ", ")+" }))", cb.count < len(c.items), "[]")))
+	}
+	code.WriteString("}\n")
+	c.code = code.String()
+	c.typeVal = "map(object({\n" + strings.Join(types, "\n") + "\n}))"
+	return nil
+}
+
+// literalValue returns the value of an expression which does not depend on anything, or of a
+// variable of the project whose value moves into the items. It returns the name of that variable.
+func literalValue(expr hclsyntax.Expression, inputVars map[string]common.VarConfig) (cty.Value, string, bool) {
+	variables := expr.Variables()
+	if len(variables) == 0 {
+		value, diags := expr.Value(collectionEvalContext)
+		if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+			return cty.NilVal, "", false
+		}
+		return value, "", true
+	}
+	traversalExpr, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(traversalExpr.Traversal) != 2 || traversalExpr.Traversal.RootName() != "var" {
+		return cty.NilVal, "", false
+	}
+	name := traversalExpr.Traversal[1].(hcl.TraverseAttr).Name
+	inputVar, ok := inputVars[name]
+	if !ok {
+		return cty.NilVal, "", false
+	}
+	switch inputVar.TypeVal {
+	case "string":
+		return cty.StringVal(inputVar.DefaultVal), name, true
+	case "bool":
+		value, err := strconv.ParseBool(inputVar.DefaultVal)
+		if err != nil {
+			return cty.NilVal, "", false
+		}
+		return cty.BoolVal(value), name, true
+	case "number":
+		value, err := cty.ParseNumberVal(inputVar.DefaultVal)
+		if err != nil {
+			return cty.NilVal, "", false
+		}
+		return value, name, true
+	}
+	return cty.NilVal, "", false
+}
+
+// typeConstraint is the type of a variable holding a value. Collections mixing types are any.
+func typeConstraint(value cty.Value) string {
+	switch {
+	case value.Type() == cty.String:
+		return "string"
+	case value.Type() == cty.Number:
+		return "number"
+	case value.Type() == cty.Bool:
+		return "bool"
+	case value.Type().IsTupleType() || value.Type().IsListType() || value.Type().IsSetType():
+		return "list(" + elementType(value) + ")"
+	case value.Type().IsObjectType() || value.Type().IsMapType():
+		return "map(" + elementType(value) + ")"
+	}
+	return "any"
+}
+
+func elementType(value cty.Value) string {
+	types := map[string]bool{}
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		types[typeConstraint(element)] = true
+	}
+	if len(types) == 0 {
+		return "any"
+	}
+	return mergedType(types)
+}
+
+func mergedType(types map[string]bool) string {
+	if len(types) != 1 {
+		return "any"
+	}
+	for typeName := range types {
+		return typeName
+	}
+	return "any"
+}
+
+func optionalType(typeName string, optional bool, defaultVal string) string {
+	if !optional {
+		return typeName
+	}
+	if len(defaultVal) > 0 {
+		return "optional(" + typeName + ", " + defaultVal + ")"
+	}
+	return "optional(" + typeName + ")"
+}
+
+// sortedAttributes returns the attributes of a body in the order they are written.
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attributes := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attribute := range body.Attributes {
+		attributes = append(attributes, attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].SrcRange.Start.Byte < attributes[j].SrcRange.Start.Byte })
+	return attributes
+}
+
+func appendMissing(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,106 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+	"testing"
+)
+
+// TestGenerateCollections checks that collection mode writes the queues of the fixtures as a single
+// resource whose items are in the config, and that imports, outputs and references use their keys.
+func TestGenerateCollections(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{
+		"collection_mode":        "true",
+		"generate_import_blocks": "true",
+	})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+	files, err := readFiles(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsServices := filepath.Join("terraform", "aws-services")
+
+	expected := `resource "duplocloud_aws_sqs_queue" "this" {
+  for_each                    = var.sqs_queues
+  tenant_id                   = local.tenant_id
+  name                        = each.value.name
+  message_retention_seconds   = each.value.message_retention_seconds
+  visibility_timeout_seconds  = each.value.visibility_timeout_seconds
+  delay_seconds               = each.value.delay_seconds
+  fifo_queue                  = each.value.fifo_queue
+  content_based_deduplication = each.value.content_based_deduplication
+  deduplication_scope         = each.value.deduplication_scope
+  fifo_throughput_limit       = each.value.fifo_throughput_limit
+}
+`
+	if files[filepath.Join(awsServices, "sqs.tf")] != expected {
+		t.Errorf("got sqs.tf\n%s\nexpected\n%s", files[filepath.Join(awsServices, "sqs.tf")], expected)
+	}
+	for path := range files {
+		base := filepath.Base(path)
+		if strings.HasPrefix(base, "sqs-") || strings.HasPrefix(base, "s3-") || strings.HasPrefix(base, "k8s-cm-") {
+			t.Errorf("%s should be collected", path)
+		}
+	}
+
+	vars := files[filepath.Join(awsServices, "vars.tf")]
+	if strings.Contains(vars, "s3_assets_enable_versioning") {
+		t.Errorf("the variables of the bucket should move into its item:\n%s", vars)
+	}
+	if !strings.Contains(vars, "fifo_queue                  = optional(bool)") {
+		t.Errorf("attributes of some items should be optional:\n%s", vars)
+	}
+	var tfvars struct {
+		SqsQueues map[string]map[string]interface{} `json:"sqs_queues"`
+		S3Buckets map[string]map[string]interface{} `json:"s3_buckets"`
+	}
+	err = json.Unmarshal([]byte(files[filepath.Join("config", testTenantName, "aws-services.tfvars.json")]), &tfvars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tfvars.SqsQueues["payments"]["fifo_queue"] != true || tfvars.SqsQueues["orders_events"]["name"] != "orders-events" {
+		t.Errorf("got sqs_queues %v", tfvars.SqsQueues)
+	}
+	if tfvars.S3Buckets["assets"]["enable_versioning"] != true {
+		t.Errorf("got s3_buckets %v", tfvars.S3Buckets)
+	}
+
+	for path, reference := range map[string]string{
+		filepath.Join(awsServices, "imports.tf"):               `to = duplocloud_aws_sqs_queue.this["orders_events"]`,
+		filepath.Join(awsServices, "outputs.tf"):               `duplocloud_s3_bucket.this["assets"].fullname`,
+		filepath.Join(awsServices, MOVED_FILE):                 "from = duplocloud_s3_bucket.assets\n  to   = duplocloud_s3_bucket.this[\"assets\"]",
+		filepath.Join("terraform", "app", "svc-orders-api.tf"): `${duplocloud_k8_config_map.this["orders_config"].name}`,
+	} {
+		if !strings.Contains(files[path], reference) {
+			t.Errorf("%s should have %s:\n%s", path, reference, files[path])
+		}
+	}
+}
+
+func TestCollectProjectKeepsDifferentResources(t *testing.T) {
+	dir := t.TempDir()
+	tfFiles := map[string]string{
+		"sqs-a.tf": "resource \"duplocloud_aws_sqs_queue\" \"a\" {\n  tenant_id = local.tenant_id\n  name      = \"a\"\n}\n",
+		"sqs-b.tf": "resource \"duplocloud_aws_sqs_queue\" \"b\" {\n  tenant_id = local.other_tenant_id\n  name      = \"b\"\n}\n",
+	}
+	writeMergeTree(t, dir, tfFiles)
+	tfContext := &common.TFContext{ImportConfigs: []common.ImportConfig{{ResourceAddress: "duplocloud_aws_sqs_queue.a"}}}
+	err := CollectProject(dir, tfContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := readFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files["sqs-a.tf"] != tfFiles["sqs-a.tf"] || files["sqs-b.tf"] != tfFiles["sqs-b.tf"] {
+		t.Errorf("queues of different tenants can not share a resource, got %v", files)
+	}
+	if tfContext.ImportConfigs[0].ResourceAddress != "duplocloud_aws_sqs_queue.a" || len(tfContext.InputVars) != 0 {
+		t.Errorf("got %+v", tfContext)
+	}
+}
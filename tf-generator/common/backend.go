@@ -0,0 +1,396 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Backends where the projects keep their state.
+const (
+	BACKEND_S3      = "s3"
+	BACKEND_LOCAL   = "local"
+	BACKEND_GCS     = "gcs"
+	BACKEND_AZURERM = "azurerm"
+	BACKEND_HTTP    = "http"
+	BACKEND_CLOUD   = "cloud"
+	DEFAULT_BACKEND = BACKEND_S3
+)
+
+var BackendTypes = []string{BACKEND_S3, BACKEND_LOCAL, BACKEND_GCS, BACKEND_AZURERM, BACKEND_HTTP, BACKEND_CLOUD}
+
+// BackendSettings are the settings of the backends, read from the env vars with the backend_ prefix.
+var BackendSettings = []string{"bucket", "region", "dynamodb_table", "key_prefix", "path", "resource_group", "storage_account", "container", "address", "organization", "hostname"}
+
+// backendRequiredSettings must be set for a backend.
+var backendRequiredSettings = map[string][]string{
+	BACKEND_GCS:     {"bucket"},
+	BACKEND_AZURERM: {"resource_group", "storage_account", "container"},
+	BACKEND_HTTP:    {"address"},
+	BACKEND_CLOUD:   {"organization"},
+}
+
+// StateLocation names the state of a project. Scope tells the admin projects from the tenant ones.
+type StateLocation struct {
+	// Project is the folder of the project.
+	Project string
+	Key     string
+	Scope   string
+}
+
+// TenantProjectState is the state of the tenant project, read by the other projects.
+func TenantProjectState(config *Config) StateLocation {
+	return StateLocation{Project: config.TenantProject, Key: "tenant", Scope: "admin"}
+}
+
+// ServicesProjectState is the state of the aws-services or app project.
+func ServicesProjectState(config *Config, project string) StateLocation {
+	return StateLocation{Project: project, Key: project, Scope: "tenant"}
+}
+
+// Backend writes where the projects keep their state, and the terraform_remote_state data sources
+// reading it so that both always match.
+type Backend interface {
+	// WriteBackend adds the backend of a project to its terraform block.
+	WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config)
+	// WriteLocals adds the locals used by the remote state data sources.
+	WriteLocals(localsBody *hclwrite.Body)
+	// WriteRemoteState sets the backend and config of a terraform_remote_state data source.
+	WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config)
+	// BackendConfig are the key=value settings passed to terraform init with -backend-config.
+	// Both the generator and the generated scripts pass them.
+	BackendConfig(config *Config) []string
+	// Workspaces reports whether the state of each tenant is a workspace of the project. Backends
+	// without workspaces keep it under a name with the tenant.
+	Workspaces() bool
+}
+
+// NewBackend returns the backend of a type with its settings, keyed like BackendSettings.
+func NewBackend(backendType string, settings map[string]string) (Backend, error) {
+	for _, setting := range backendRequiredSettings[backendType] {
+		if len(settings[setting]) == 0 {
+			return nil, fmt.Errorf("the %s backend requires its %s setting", backendType, setting)
+		}
+	}
+	switch backendType {
+	case BACKEND_S3:
+		return &S3Backend{Bucket: settings["bucket"], Region: settings["region"], DynamoDBTable: settings["dynamodb_table"], KeyPrefix: settings["key_prefix"]}, nil
+	case BACKEND_LOCAL:
+		return &LocalBackend{Path: settings["path"]}, nil
+	case BACKEND_GCS:
+		return &GCSBackend{Bucket: settings["bucket"], KeyPrefix: settings["key_prefix"]}, nil
+	case BACKEND_AZURERM:
+		return &AzureRMBackend{ResourceGroup: settings["resource_group"], StorageAccount: settings["storage_account"], Container: settings["container"], KeyPrefix: settings["key_prefix"]}, nil
+	case BACKEND_HTTP:
+		return &HTTPBackend{Address: strings.TrimSuffix(settings["address"], "/")}, nil
+	case BACKEND_CLOUD:
+		return &CloudBackend{Organization: settings["organization"], Hostname: settings["hostname"], WorkspacePrefix: settings["key_prefix"]}, nil
+	}
+	return nil, fmt.Errorf("backend must be one of %s: %q", strings.Join(BackendTypes, ", "), backendType)
+}
+
+// StateBackend is the backend of the projects. Projects without a generated backend keep their
+// state in their folder.
+func (c *Config) StateBackend() Backend {
+	if c.S3Backend && c.Backend != nil {
+		return c.Backend
+	}
+	return &LocalBackend{}
+}
+
+// InitOptions are the options of terraform init for the backend of the projects.
+func (c *Config) InitOptions() []tfexec.InitOption {
+	options := []tfexec.InitOption{tfexec.Upgrade(true)}
+	if c.S3Backend {
+		for _, setting := range c.StateBackend().BackendConfig(c) {
+			options = append(options, tfexec.BackendConfig(setting))
+		}
+	}
+	return options
+}
+
+// S3Backend keeps the state in an S3 bucket, the duplo-tfstate-<account id> bucket by default.
+type S3Backend struct {
+	Bucket        string
+	Region        string
+	DynamoDBTable string
+	KeyPrefix     string
+}
+
+func (b *S3Backend) region(config *Config) string {
+	if len(b.Region) > 0 {
+		return b.Region
+	}
+	return config.DuploDefaultPlanRegion
+}
+
+func (b *S3Backend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	body := tfBody.AppendNewBlock("backend", []string{BACKEND_S3}).Body()
+	if len(b.Bucket) > 0 {
+		body.SetAttributeValue("bucket", cty.StringVal(b.Bucket))
+	}
+	body.SetAttributeValue("region", cty.StringVal(b.region(config)))
+	body.SetAttributeValue("key", cty.StringVal(b.KeyPrefix+state.Key))
+	body.SetAttributeValue("workspace_key_prefix", cty.StringVal(state.Scope+":"))
+	body.SetAttributeValue("encrypt", cty.True)
+	if len(b.DynamoDBTable) > 0 {
+		body.SetAttributeValue("dynamodb_table", cty.StringVal(b.DynamoDBTable))
+	}
+}
+
+func (b *S3Backend) WriteLocals(localsBody *hclwrite.Body) {
+	if len(b.Bucket) > 0 {
+		return
+	}
+	localsBody.SetAttributeRaw("tfstate_bucket", hclwrite.Tokens{
+		{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(`duplo-tfstate-${data.aws_caller_identity.current.account_id}`)},
+		{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
+	})
+}
+
+func (b *S3Backend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	bucket := hclwrite.TokensForValue(cty.StringVal(b.Bucket))
+	if len(b.Bucket) == 0 {
+		bucket = hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: "local"}, hcl.TraverseAttr{Name: "tfstate_bucket"}})
+	}
+	writeRemoteState(remoteStateBody, BACKEND_S3, true, []ObjectAttrTokens{
+		objectAttr("bucket", bucket),
+		objectAttr("workspace_key_prefix", hclwrite.TokensForValue(cty.StringVal(state.Scope+":"))),
+		objectAttr("key", hclwrite.TokensForValue(cty.StringVal(b.KeyPrefix+state.Key))),
+		objectAttr("region", hclwrite.TokensForValue(cty.StringVal(b.region(config)))),
+	})
+}
+
+// BackendConfig names the bucket of the account when no bucket is set. Its lock table is used
+// unless another one is set.
+func (b *S3Backend) BackendConfig(config *Config) []string {
+	if len(b.Bucket) > 0 {
+		return nil
+	}
+	settings := []string{"bucket=duplo-tfstate-" + config.AccountID}
+	if len(b.DynamoDBTable) == 0 {
+		settings = append(settings, "dynamodb_table=duplo-tfstate-"+config.AccountID+"-lock")
+	}
+	return settings
+}
+
+func (b *S3Backend) Workspaces() bool {
+	return true
+}
+
+// LocalBackend keeps the state in files, under Path relative to the project folder or in the
+// project folder itself.
+type LocalBackend struct {
+	Path string
+}
+
+// paths are the state file and the workspaces folder of a project, relative to a project folder.
+func (b *LocalBackend) paths(state StateLocation) (string, string) {
+	if len(b.Path) == 0 {
+		return path.Join("..", state.Project, "terraform.tfstate"), path.Join("..", state.Project, "terraform.tfstate.d")
+	}
+	base := path.Join(b.Path, state.Scope+"-"+state.Key)
+	return base + ".tfstate", base + ".tfstate.d"
+}
+
+func (b *LocalBackend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	body := tfBody.AppendNewBlock("backend", []string{BACKEND_LOCAL}).Body()
+	if len(b.Path) > 0 {
+		statePath, workspaceDir := b.paths(state)
+		body.SetAttributeValue("path", cty.StringVal(statePath))
+		body.SetAttributeValue("workspace_dir", cty.StringVal(workspaceDir))
+	}
+}
+
+func (b *LocalBackend) WriteLocals(localsBody *hclwrite.Body) {}
+
+func (b *LocalBackend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	statePath, workspaceDir := b.paths(state)
+	writeRemoteState(remoteStateBody, BACKEND_LOCAL, true, []ObjectAttrTokens{
+		objectAttr("path", hclwrite.TokensForValue(cty.StringVal(statePath))),
+		objectAttr("workspace_dir", hclwrite.TokensForValue(cty.StringVal(workspaceDir))),
+	})
+}
+
+func (b *LocalBackend) BackendConfig(config *Config) []string {
+	return nil
+}
+
+func (b *LocalBackend) Workspaces() bool {
+	return true
+}
+
+// GCSBackend keeps the state in a Google Cloud Storage bucket.
+type GCSBackend struct {
+	Bucket    string
+	KeyPrefix string
+}
+
+func (b *GCSBackend) prefix(state StateLocation) string {
+	return b.KeyPrefix + state.Scope + "/" + state.Key
+}
+
+func (b *GCSBackend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	body := tfBody.AppendNewBlock("backend", []string{BACKEND_GCS}).Body()
+	body.SetAttributeValue("bucket", cty.StringVal(b.Bucket))
+	body.SetAttributeValue("prefix", cty.StringVal(b.prefix(state)))
+}
+
+func (b *GCSBackend) WriteLocals(localsBody *hclwrite.Body) {}
+
+func (b *GCSBackend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	writeRemoteState(remoteStateBody, BACKEND_GCS, true, []ObjectAttrTokens{
+		objectAttr("bucket", hclwrite.TokensForValue(cty.StringVal(b.Bucket))),
+		objectAttr("prefix", hclwrite.TokensForValue(cty.StringVal(b.prefix(state)))),
+	})
+}
+
+func (b *GCSBackend) BackendConfig(config *Config) []string {
+	return nil
+}
+
+func (b *GCSBackend) Workspaces() bool {
+	return true
+}
+
+// AzureRMBackend keeps the state in a container of an Azure storage account.
+type AzureRMBackend struct {
+	ResourceGroup  string
+	StorageAccount string
+	Container      string
+	KeyPrefix      string
+}
+
+func (b *AzureRMBackend) attributes(state StateLocation) []ObjectAttrTokens {
+	return []ObjectAttrTokens{
+		objectAttr("resource_group_name", hclwrite.TokensForValue(cty.StringVal(b.ResourceGroup))),
+		objectAttr("storage_account_name", hclwrite.TokensForValue(cty.StringVal(b.StorageAccount))),
+		objectAttr("container_name", hclwrite.TokensForValue(cty.StringVal(b.Container))),
+		objectAttr("key", hclwrite.TokensForValue(cty.StringVal(b.KeyPrefix+state.Scope+"/"+state.Key+".tfstate"))),
+	}
+}
+
+func (b *AzureRMBackend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	writeBackendAttributes(tfBody.AppendNewBlock("backend", []string{BACKEND_AZURERM}).Body(), b.attributes(state))
+}
+
+func (b *AzureRMBackend) WriteLocals(localsBody *hclwrite.Body) {}
+
+func (b *AzureRMBackend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	writeRemoteState(remoteStateBody, BACKEND_AZURERM, true, b.attributes(state))
+}
+
+func (b *AzureRMBackend) BackendConfig(config *Config) []string {
+	return nil
+}
+
+func (b *AzureRMBackend) Workspaces() bool {
+	return true
+}
+
+// HTTPBackend keeps the state behind a REST endpoint, like the terraform state of a GitLab project.
+// The state of each project of a tenant is under Address/<tenant>-<scope>-<key>.
+type HTTPBackend struct {
+	Address string
+}
+
+func (b *HTTPBackend) address(state StateLocation, config *Config) string {
+	return b.Address + "/" + config.TenantName + "-" + state.Scope + "-" + state.Key
+}
+
+func (b *HTTPBackend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	body := tfBody.AppendNewBlock("backend", []string{BACKEND_HTTP}).Body()
+	body.SetAttributeValue("address", cty.StringVal(b.address(state, config)))
+}
+
+func (b *HTTPBackend) WriteLocals(localsBody *hclwrite.Body) {}
+
+func (b *HTTPBackend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	writeRemoteState(remoteStateBody, BACKEND_HTTP, false, []ObjectAttrTokens{
+		objectAttr("address", hclwrite.TokensForValue(cty.StringVal(b.address(state, config)))),
+	})
+}
+
+func (b *HTTPBackend) BackendConfig(config *Config) []string {
+	return nil
+}
+
+func (b *HTTPBackend) Workspaces() bool {
+	return false
+}
+
+// CloudBackend keeps the state in HCP Terraform or Terraform Enterprise. Each project of a tenant
+// is the <prefix><tenant>-<scope>-<key> workspace of the organization.
+type CloudBackend struct {
+	Organization    string
+	Hostname        string
+	WorkspacePrefix string
+}
+
+func (b *CloudBackend) workspace(state StateLocation, config *Config) string {
+	return b.WorkspacePrefix + config.TenantName + "-" + state.Scope + "-" + state.Key
+}
+
+func (b *CloudBackend) WriteBackend(tfBody *hclwrite.Body, state StateLocation, config *Config) {
+	body := tfBody.AppendNewBlock("cloud", nil).Body()
+	body.SetAttributeValue("organization", cty.StringVal(b.Organization))
+	if len(b.Hostname) > 0 {
+		body.SetAttributeValue("hostname", cty.StringVal(b.Hostname))
+	}
+	body.AppendNewBlock("workspaces", nil).Body().SetAttributeValue("name", cty.StringVal(b.workspace(state, config)))
+}
+
+func (b *CloudBackend) WriteLocals(localsBody *hclwrite.Body) {}
+
+func (b *CloudBackend) WriteRemoteState(remoteStateBody *hclwrite.Body, state StateLocation, config *Config) {
+	attributes := []ObjectAttrTokens{objectAttr("organization", hclwrite.TokensForValue(cty.StringVal(b.Organization)))}
+	if len(b.Hostname) > 0 {
+		attributes = append(attributes, objectAttr("hostname", hclwrite.TokensForValue(cty.StringVal(b.Hostname))))
+	}
+	attributes = append(attributes, objectAttr("workspaces", TokensForObject([]ObjectAttrTokens{
+		objectAttr("name", hclwrite.TokensForValue(cty.StringVal(b.workspace(state, config)))),
+	})))
+	// Remote state reads the outputs of a cloud workspace through the remote backend.
+	writeRemoteState(remoteStateBody, "remote", false, attributes)
+}
+
+func (b *CloudBackend) BackendConfig(config *Config) []string {
+	return nil
+}
+
+func (b *CloudBackend) Workspaces() bool {
+	return false
+}
+
+func objectAttr(name string, value hclwrite.Tokens) ObjectAttrTokens {
+	return ObjectAttrTokens{
+		Name:  hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: name}}),
+		Value: value,
+	}
+}
+
+func writeBackendAttributes(body *hclwrite.Body, attributes []ObjectAttrTokens) {
+	for _, attribute := range attributes {
+		body.SetAttributeRaw(string(attribute.Name.Bytes()), attribute.Value)
+	}
+}
+
+// writeRemoteState writes a terraform_remote_state data source. Backends with workspaces read the
+// workspace of the tenant.
+func writeRemoteState(remoteStateBody *hclwrite.Body, backend string, workspaces bool, config []ObjectAttrTokens) {
+	remoteStateBody.SetAttributeValue("backend", cty.StringVal(backend))
+	if workspaces {
+		remoteStateBody.SetAttributeTraversal("workspace", hcl.Traversal{
+			hcl.TraverseRoot{Name: "terraform"},
+			hcl.TraverseAttr{Name: "workspace"},
+		})
+	}
+	remoteStateBody.SetAttributeRaw("config", TokensForObject(config))
+}
@@ -1,10 +1,17 @@
 package common
 
+import (
+	"log"
+	"time"
+)
+
 type Config struct {
 	DuploHost               string
 	DuploToken              string
 	TenantId                string
 	TenantName              string
+	TenantNames             []string
+	InfraName               string
 	CertArn                 string
 	CustomerName            string
 	AdminTenantDir          string
@@ -33,6 +40,79 @@ type Config struct {
 	AdminInfraPath          string
 	AdminInfraDir           string
 	SkipAdminInfra          bool
+	GenerateImportBlocks    bool
+	SslNoVerify             bool
+	TargetDir               string
+	ConfigFile              string
+	Parallelism             int
+	ContinueOnError         bool
+	MaxFailures             int
+	EnableGenerators        []string
+	DisableGenerators       []string
+	IncludeResources        []string
+	ExcludeResources        []string
+	ResourceFilter          *ResourceFilter
+	MaxRetries              int
+	RequestTimeout          int
+	RequestsPerSecond       float64
+	// SecretPolicy is the default policy of secret values, SecretPolicyRules the policies of
+	// resource types and attributes. Secrets compiles them along with the external store settings.
+	SecretPolicy      string
+	SecretPolicyRules []string
+	SecretStore       string
+	SecretStorePrefix string
+	Secrets           *SecretPolicies
+	// APICache memoizes the DuploCloud API reads of the run, APICacheFile keeps them across runs
+	// for APICacheTTL.
+	APICache     bool
+	APICacheFile string
+	APICacheTTL  time.Duration
+	// UpdateMode merges the generation into the existing code instead of replacing it.
+	UpdateMode bool
+	// OutputFormat is the format of the drift report, text or json.
+	OutputFormat string
+	// RecordFixtures is the fixture bundle where the Duplo API responses of the run are recorded.
+	RecordFixtures string
+	// ReplayFixtures is the fixture bundle the run is served from instead of the Duplo API.
+	ReplayFixtures string
+	// SecretScan is the mode of the scan of the generated files, off, report, redact or fail.
+	// SecretScanAllow are regular expressions of the values it ignores.
+	SecretScan      string
+	SecretScanAllow []string
+	// ModuleMode moves each file of resources of a project into a local module.
+	ModuleMode bool
+	// CollectionMode writes the SQS queues, SNS topics, S3 buckets and config maps of a project as
+	// one resource with for_each per type.
+	CollectionMode bool
+	// Backend is where the projects keep their state when S3Backend generates their backend. See
+	// StateBackend.
+	Backend Backend
+	// ProjectContract is how the other projects read the values of the tenant project, remote_state,
+	// data_source, tfvars or ssm.
+	ProjectContract string
+	// TenantSettings are the keys of the tenant metadata written as tenant config settings. See
+	// DEFAULT_TENANT_SETTINGS.
+	TenantSettings []string
+}
+
+// MultiTenant reports whether the run exports a list of tenants or every tenant of an infrastructure.
+func (c *Config) MultiTenant() bool {
+	return len(c.TenantNames) > 1 || len(c.InfraName) > 0
+}
+
+// ResourceAllowed reports whether the resource filters let the resource be generated.
+func (c *Config) ResourceAllowed(resourceType, name string, tags map[string]string) bool {
+	if c.ResourceFilter.Allows(resourceType, name, tags) {
+		return true
+	}
+	log.Printf("[TRACE] %s %s is skipped by the resource filters.", resourceType, name)
+	return false
+}
+
+// ImportsEnabled reports whether generators should collect import configs,
+// either to run terraform import or to write import blocks.
+func (c *Config) ImportsEnabled() bool {
+	return c.GenerateTfState || c.GenerateImportBlocks
 }
 
 type TFContext struct {
@@ -41,4 +121,18 @@ type TFContext struct {
 	OutputVars     []OutputVarConfig
 	ImportConfigs  []ImportConfig
 	ConfgiVars     ConfigVars
+	// KmsKeys are the KMS keys usable by the tenant. Literals of their ARN or ID in the project are
+	// replaced by references to them.
+	KmsKeys []KmsKeyConfig
+	// ArnRefs map the ARNs of the resources written by a generator onto their references, which
+	// replace the literals of these ARNs in the project.
+	ArnRefs map[string]string
+}
+
+// KmsKeyConfig is a KMS key with the expressions reading its ARN and ID.
+type KmsKeyConfig struct {
+	KeyArn string
+	KeyId  string
+	ArnRef string
+	IdRef  string
 }
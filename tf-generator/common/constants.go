@@ -2,4 +2,24 @@ package common
 
 const (
 	TF_DEFAULT_VERSION = "v1.4.7"
+	// Import blocks are only understood by terraform 1.5 and later.
+	TF_IMPORT_BLOCKS_MIN_VERSION     = "v1.5.0"
+	TF_IMPORT_BLOCKS_DEFAULT_VERSION = "v1.5.7"
+	// Number of generators run concurrently for a project.
+	DEFAULT_PARALLELISM = 4
+	// Retries of a failed DuploCloud API read and the timeout of a single request in seconds.
+	DEFAULT_MAX_RETRIES     = 3
+	DEFAULT_REQUEST_TIMEOUT = 20
+	// Age after which a saved DuploCloud API response cache is read from the portal again.
+	DEFAULT_API_CACHE_TTL = "1h"
+	// Formats of the reports printed by commands.
+	OUTPUT_FORMAT_TEXT = "text"
+	OUTPUT_FORMAT_JSON = "json"
+	// Host and token of a run replayed from a fixture bundle. The portal is never called.
+	REPLAY_DUPLO_HOST  = "https://replay.duplocloud.invalid"
+	REPLAY_DUPLO_TOKEN = "replay"
 )
+
+// DEFAULT_TENANT_SETTINGS are the tenant config settings written to the tenant project. Other keys
+// of the tenant metadata are managed by DuploCloud. tenant_settings adds keys to them.
+var DEFAULT_TENANT_SETTINGS = []string{"block_master_vpn", "delete_protection", "enable_alerting", "enable_host_other_tenants"}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Contracts through which the aws-services and app projects read the values of the tenant project.
+const (
+	CONTRACT_REMOTE_STATE = "remote_state"
+	CONTRACT_DATA_SOURCE  = "data_source"
+	CONTRACT_TFVARS       = "tfvars"
+	CONTRACT_SSM          = "ssm"
+	DEFAULT_CONTRACT      = CONTRACT_REMOTE_STATE
+)
+
+var ContractTypes = []string{CONTRACT_REMOTE_STATE, CONTRACT_DATA_SOURCE, CONTRACT_TFVARS, CONTRACT_SSM}
+
+// CONTRACT_SSM_PREFIX starts the SSM parameters of the ssm contract, which are named
+// <prefix>/<tenant>/contract/<project>.
+const CONTRACT_SSM_PREFIX = "/duplocloud"
+
+// ContractValues are the outputs of the tenant project the other projects can read, with their
+// value for the tenant being generated.
+func ContractValues(config *Config) map[string]string {
+	return map[string]string{
+		"tenant_id":   config.TenantId,
+		"tenant_name": config.TenantName,
+		// The certificate of the tenant project is a variable left empty. See generateTenantVars.
+		"cert_arn": "",
+	}
+}
+
+// SetTenantName sets an attribute to the name of the tenant, which is the workspace of the project unless
+// the backend names the state after the tenant.
+func SetTenantName(body *hclwrite.Body, name string, config *Config) {
+	if config.StateBackend().Workspaces() {
+		body.SetAttributeTraversal(name, hcl.Traversal{
+			hcl.TraverseRoot{
+				Name: "terraform",
+			},
+			hcl.TraverseAttr{
+				Name: "workspace",
+			},
+		})
+		return
+	}
+	body.SetAttributeValue(name, cty.StringVal(config.TenantName))
+}
@@ -0,0 +1,19 @@
+package common
+
+// ResourceError ties an error to the terraform resource being generated when it happened.
+type ResourceError struct {
+	Resource string
+	Err      error
+}
+
+func (e *ResourceError) Error() string {
+	return e.Resource + ": " + e.Err.Error()
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+func NewResourceError(resource string, err error) error {
+	return &ResourceError{Resource: resource, Err: err}
+}
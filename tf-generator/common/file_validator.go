@@ -0,0 +1,404 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+const CONFIG_FILE_VERSION = 1
+
+// FileConfig is the schema of the generator config file. It can be written as yaml or json.
+type FileConfig struct {
+	Version         int                  `json:"version"`
+	DuploHost       string               `json:"duplo_host,omitempty"`
+	DuploToken      string               `json:"duplo_token,omitempty"`
+	SslNoVerify     *bool                `json:"ssl_no_verify,omitempty"`
+	Customer        string               `json:"customer"`
+	Tenants         []string             `json:"tenants,omitempty"`
+	Infrastructure  string               `json:"infrastructure,omitempty"`
+	TargetDir       string               `json:"target_dir,omitempty"`
+	ProviderVersion string               `json:"provider_version,omitempty"`
+	Parallelism     int                  `json:"parallelism,omitempty"`
+	ContinueOnError *bool                `json:"continue_on_error,omitempty"`
+	MaxFailures     *int                 `json:"max_failures,omitempty"`
+	Terraform       FileTerraformConfig  `json:"terraform,omitempty"`
+	Projects        FileProjectsConfig   `json:"projects,omitempty"`
+	Skip            []string             `json:"skip,omitempty"`
+	Generators      FileGeneratorsConfig `json:"generators,omitempty"`
+	Filters         FileFiltersConfig    `json:"filters,omitempty"`
+	Secrets         FileSecretsConfig    `json:"secrets,omitempty"`
+	Fixtures        FileFixturesConfig   `json:"fixtures,omitempty"`
+	API             FileAPIConfig        `json:"api,omitempty"`
+	OutputFormat    string               `json:"output_format,omitempty"`
+	// TenantSettings are tenant config settings written besides DEFAULT_TENANT_SETTINGS.
+	TenantSettings []string `json:"tenant_settings,omitempty"`
+}
+
+type FileTerraformConfig struct {
+	Version       string `json:"version,omitempty"`
+	Validate      *bool  `json:"validate,omitempty"`
+	S3Backend     *bool  `json:"s3_backend,omitempty"`
+	GenerateState *bool  `json:"generate_state,omitempty"`
+	ImportBlocks  *bool  `json:"import_blocks,omitempty"`
+	Modules       *bool  `json:"modules,omitempty"`
+	Collections   *bool  `json:"collections,omitempty"`
+	// Backend settings are the backend_ env vars without their prefix.
+	Backend map[string]string `json:"backend,omitempty"`
+	// Contract is how the other projects read the values of the tenant project.
+	Contract string `json:"contract,omitempty"`
+}
+
+type FileProjectsConfig struct {
+	Tenant      string `json:"tenant,omitempty"`
+	AwsServices string `json:"aws_services,omitempty"`
+	App         string `json:"app,omitempty"`
+}
+
+// FileGeneratorsConfig turns generators on or off by the names shown by list-generators.
+type FileGeneratorsConfig struct {
+	Enable  []string `json:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty"`
+}
+
+// FileFiltersConfig holds resource filters. See ResourceFilter for the format of a rule.
+type FileFiltersConfig struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// FileSecretsConfig decides how secret values are written. See SecretPolicies.
+type FileSecretsConfig struct {
+	// Policy is the default policy and Policies the ones of resource types or attributes.
+	Policy      string            `json:"policy,omitempty"`
+	Policies    map[string]string `json:"policies,omitempty"`
+	Placeholder string            `json:"placeholder,omitempty"`
+	Store       string            `json:"store,omitempty"`
+	StorePrefix string            `json:"store_prefix,omitempty"`
+	// Scan is the mode of the secret scan of the generated files, ScanAllow the values it ignores.
+	Scan      string   `json:"scan,omitempty"`
+	ScanAllow []string `json:"scan_allow,omitempty"`
+}
+
+// FileAPIConfig tunes the requests sent to the DuploCloud portal.
+type FileAPIConfig struct {
+	MaxRetries        *int     `json:"max_retries,omitempty"`
+	RequestTimeout    int      `json:"request_timeout,omitempty"`
+	RequestsPerSecond *float64 `json:"requests_per_second,omitempty"`
+	Cache             *bool    `json:"cache,omitempty"`
+	CacheFile         string   `json:"cache_file,omitempty"`
+	CacheTTL          string   `json:"cache_ttl,omitempty"`
+}
+
+// FileFixturesConfig records the Duplo API responses of a run or replays a run from them.
+type FileFixturesConfig struct {
+	Record string `json:"record,omitempty"`
+	Replay string `json:"replay,omitempty"`
+}
+
+// skipKeys maps the project names accepted in the skip list onto their config keys.
+var skipKeys = map[string]string{
+	"admin-tenant": "skip_admin_tenant",
+	"aws-services": "skip_aws_services",
+	"app":          "skip_app",
+	"admin-infra":  "skip_admin_infra",
+}
+
+// LoadFileConfig reads and validates a generator config file.
+func LoadFileConfig(path string) (*FileConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %s", path, err)
+	}
+	jsonContent, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %s", path, err)
+	}
+	fileConfig := &FileConfig{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(fileConfig)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid config file %s: %s must be of type %s, got %s", path, typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return nil, fmt.Errorf("invalid config file %s: %s", path, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	if problems := fileConfig.validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config file %s:\n  - %s", path, strings.Join(problems, "\n  - "))
+	}
+	return fileConfig, nil
+}
+
+func (fc *FileConfig) validate() []string {
+	problems := []string{}
+	if fc.Version != CONFIG_FILE_VERSION {
+		problems = append(problems, fmt.Sprintf("version must be %d, got %d", CONFIG_FILE_VERSION, fc.Version))
+	}
+	if len(fc.Tenants) > 0 && len(fc.Infrastructure) > 0 {
+		problems = append(problems, "tenants and infrastructure can not be used together")
+	}
+	for i, tenant := range fc.Tenants {
+		if len(strings.TrimSpace(tenant)) == 0 {
+			problems = append(problems, fmt.Sprintf("tenants[%d] must not be empty", i))
+		}
+	}
+	for _, skip := range fc.Skip {
+		if _, ok := skipKeys[skip]; !ok {
+			problems = append(problems, fmt.Sprintf("skip contains unknown project %q, expected one of admin-tenant, aws-services, app, admin-infra", skip))
+		}
+	}
+	for i, filter := range fc.Filters.Include {
+		if err := ValidateFilterRule(filter); err != nil {
+			problems = append(problems, fmt.Sprintf("filters.include[%d] %s", i, err))
+		}
+	}
+	for i, filter := range fc.Filters.Exclude {
+		if err := ValidateFilterRule(filter); err != nil {
+			problems = append(problems, fmt.Sprintf("filters.exclude[%d] %s", i, err))
+		}
+	}
+	if len(fc.Fixtures.Record) > 0 && len(fc.Fixtures.Replay) > 0 {
+		problems = append(problems, "fixtures.record and fixtures.replay can not be used together")
+	}
+	if fc.API.MaxRetries != nil && *fc.API.MaxRetries < 0 {
+		problems = append(problems, fmt.Sprintf("api.max_retries must be zero or positive, got %d", *fc.API.MaxRetries))
+	}
+	if fc.API.RequestTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("api.request_timeout must be positive, got %d", fc.API.RequestTimeout))
+	}
+	if fc.API.RequestsPerSecond != nil && *fc.API.RequestsPerSecond < 0 {
+		problems = append(problems, fmt.Sprintf("api.requests_per_second must be zero or positive, got %g", *fc.API.RequestsPerSecond))
+	}
+	if len(fc.API.CacheTTL) > 0 {
+		if ttl, err := time.ParseDuration(fc.API.CacheTTL); err != nil || ttl < 0 {
+			problems = append(problems, fmt.Sprintf("api.cache_ttl must be a duration like 30m or 12h, got %q", fc.API.CacheTTL))
+		}
+	}
+	if len(fc.API.CacheFile) > 0 && len(fc.Fixtures.Record) > 0 {
+		problems = append(problems, "api.cache_file and fixtures.record can not be used together")
+	}
+	if len(fc.Terraform.Backend) > 0 {
+		backendKeys := []string{}
+		for key := range fc.Terraform.Backend {
+			backendKeys = append(backendKeys, key)
+		}
+		sort.Strings(backendKeys)
+		backendSettings := map[string]string{}
+		for _, key := range backendKeys {
+			if key != "type" && !Contains(BackendSettings, key) {
+				problems = append(problems, fmt.Sprintf("terraform.backend contains unknown setting %q, expected type or one of %s", key, strings.Join(BackendSettings, ", ")))
+			}
+			backendSettings[key] = fc.Terraform.Backend[key]
+		}
+		backendType := fc.Terraform.Backend["type"]
+		if len(backendType) == 0 {
+			backendType = DEFAULT_BACKEND
+		}
+		if _, err := NewBackend(backendType, backendSettings); err != nil {
+			problems = append(problems, fmt.Sprintf("terraform.backend %s", err))
+		}
+	}
+	switch fc.OutputFormat {
+	case "", OUTPUT_FORMAT_TEXT, OUTPUT_FORMAT_JSON:
+	default:
+		problems = append(problems, fmt.Sprintf("output_format must be one of text, json, got %q", fc.OutputFormat))
+	}
+	if len(fc.Secrets.Policy) > 0 && !Contains(SecretPolicyNames, fc.Secrets.Policy) {
+		problems = append(problems, fmt.Sprintf("secrets.policy must be one of %s, got %q", strings.Join(SecretPolicyNames, ", "), fc.Secrets.Policy))
+	}
+	policyKeys := []string{}
+	for key := range fc.Secrets.Policies {
+		policyKeys = append(policyKeys, key)
+	}
+	sort.Strings(policyKeys)
+	for _, key := range policyKeys {
+		if err := ValidateSecretPolicyRule(key + "=" + fc.Secrets.Policies[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("secrets.policies %s", err))
+		}
+	}
+	switch fc.Secrets.Store {
+	case "", SECRET_STORE_SECRETS_MANAGER, SECRET_STORE_SSM:
+	default:
+		problems = append(problems, fmt.Sprintf("secrets.store must be one of secretsmanager, ssm, got %q", fc.Secrets.Store))
+	}
+	if len(fc.Terraform.Contract) > 0 && !Contains(ContractTypes, fc.Terraform.Contract) {
+		problems = append(problems, fmt.Sprintf("terraform.contract must be one of %s, got %q", strings.Join(ContractTypes, ", "), fc.Terraform.Contract))
+	}
+	if len(fc.Secrets.Scan) > 0 && !Contains(SecretScanModes, fc.Secrets.Scan) {
+		problems = append(problems, fmt.Sprintf("secrets.scan must be one of %s, got %q", strings.Join(SecretScanModes, ", "), fc.Secrets.Scan))
+	}
+	for i, pattern := range fc.Secrets.ScanAllow {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("secrets.scan_allow[%d] is not a regular expression: %s", i, err))
+		} else if strings.Contains(pattern, ",") {
+			problems = append(problems, fmt.Sprintf("secrets.scan_allow[%d] can not have a comma", i))
+		}
+	}
+	return problems
+}
+
+// values flattens the file config onto the keys read by EnvVarValidator.
+func (fc *FileConfig) values() map[string]string {
+	values := map[string]string{}
+	set := func(key, value string) {
+		if len(value) > 0 {
+			values[key] = value
+		}
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			values[key] = strconv.FormatBool(*value)
+		}
+	}
+	set("duplo_host", fc.DuploHost)
+	set("duplo_token", fc.DuploToken)
+	setBool("ssl_no_verify", fc.SslNoVerify)
+	set("customer_name", fc.Customer)
+	set("tenant_names", strings.Join(fc.Tenants, ","))
+	set("infra_name", fc.Infrastructure)
+	set("target_dir", fc.TargetDir)
+	set("duplo_provider_version", fc.ProviderVersion)
+	if fc.Parallelism != 0 {
+		values["parallelism"] = strconv.Itoa(fc.Parallelism)
+	}
+	setBool("continue_on_error", fc.ContinueOnError)
+	if fc.MaxFailures != nil {
+		values["max_failures"] = strconv.Itoa(*fc.MaxFailures)
+	}
+	set("tf_version", fc.Terraform.Version)
+	setBool("validate_tf", fc.Terraform.Validate)
+	setBool("s3_backend", fc.Terraform.S3Backend)
+	setBool("generate_tf_state", fc.Terraform.GenerateState)
+	setBool("generate_import_blocks", fc.Terraform.ImportBlocks)
+	setBool("module_mode", fc.Terraform.Modules)
+	setBool("collection_mode", fc.Terraform.Collections)
+	for key, value := range fc.Terraform.Backend {
+		if key == "type" {
+			set("backend", value)
+		} else {
+			set("backend_"+key, value)
+		}
+	}
+	set("project_contract", fc.Terraform.Contract)
+	set("tenant_settings", strings.Join(fc.TenantSettings, ","))
+	set("tenant_project", fc.Projects.Tenant)
+	set("aws_services_project", fc.Projects.AwsServices)
+	set("app_project", fc.Projects.App)
+	for _, skip := range fc.Skip {
+		values[skipKeys[skip]] = "true"
+	}
+	set("enable_generators", strings.Join(fc.Generators.Enable, ","))
+	set("disable_generators", strings.Join(fc.Generators.Disable, ","))
+	set("secret_policy", fc.Secrets.Policy)
+	secretPolicies := []string{}
+	for key, policy := range fc.Secrets.Policies {
+		secretPolicies = append(secretPolicies, key+"="+policy)
+	}
+	sort.Strings(secretPolicies)
+	set("secret_policies", strings.Join(secretPolicies, ","))
+	set("secret_store", fc.Secrets.Store)
+	set("secret_store_prefix", fc.Secrets.StorePrefix)
+	set("k8s_secret_placeholder", fc.Secrets.Placeholder)
+	set("secret_scan", fc.Secrets.Scan)
+	set("secret_scan_allow", strings.Join(fc.Secrets.ScanAllow, ","))
+	if fc.API.MaxRetries != nil {
+		values["max_retries"] = strconv.Itoa(*fc.API.MaxRetries)
+	}
+	if fc.API.RequestTimeout != 0 {
+		values["request_timeout"] = strconv.Itoa(fc.API.RequestTimeout)
+	}
+	if fc.API.RequestsPerSecond != nil {
+		values["requests_per_second"] = strconv.FormatFloat(*fc.API.RequestsPerSecond, 'f', -1, 64)
+	}
+	setBool("api_cache", fc.API.Cache)
+	set("api_cache_file", fc.API.CacheFile)
+	set("api_cache_ttl", fc.API.CacheTTL)
+	set("output_format", fc.OutputFormat)
+	set("record_fixtures", fc.Fixtures.Record)
+	set("replay_fixtures", fc.Fixtures.Replay)
+	return values
+}
+
+// Sanitized returns a copy of the file config without credentials, so that it can be
+// committed next to the generated code.
+func (fc *FileConfig) Sanitized() *FileConfig {
+	sanitized := *fc
+	sanitized.DuploToken = ""
+	return &sanitized
+}
+
+// FileValidator reads the config from a yaml or json config file. Env variables are used
+// for every key which is not set in the file.
+type FileValidator struct {
+	Path string
+	// Override resolves keys which take precedence over the file, like command line flags.
+	Override func(key string) (string, bool)
+}
+
+func (fileValidator *FileValidator) Validate() (*Config, error) {
+	fileConfig, err := LoadFileConfig(fileValidator.Path)
+	if err != nil {
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+	values := fileConfig.values()
+	if fileValidator.Override != nil {
+		// Tenants passed as flags replace the tenants of the file.
+		for _, key := range []string{"tenant_name", "tenant_names", "infra_name"} {
+			if _, ok := fileValidator.Override(key); ok {
+				delete(values, "tenant_names")
+				delete(values, "infra_name")
+			}
+		}
+	}
+	envVarValidator := EnvVarValidator{
+		Lookup: func(key string) (string, bool) {
+			if fileValidator.Override != nil {
+				if value, ok := fileValidator.Override(key); ok {
+					return value, true
+				}
+			}
+			if value, ok := values[key]; ok {
+				return value, true
+			}
+			return os.LookupEnv(key)
+		},
+	}
+	config, err := envVarValidator.Validate()
+	if err != nil {
+		return nil, err
+	}
+	config.ConfigFile = fileValidator.Path
+	// Filters of the file are lists, so they are applied here unless passed as flags.
+	if !fileValidator.overridden("include_resources") && len(fileConfig.Filters.Include) > 0 {
+		config.IncludeResources = fileConfig.Filters.Include
+	}
+	if !fileValidator.overridden("exclude_resources") && len(fileConfig.Filters.Exclude) > 0 {
+		config.ExcludeResources = fileConfig.Filters.Exclude
+	}
+	config.ResourceFilter, err = NewResourceFilter(config.IncludeResources, config.ExcludeResources)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (fileValidator *FileValidator) overridden(key string) bool {
+	if fileValidator.Override == nil {
+		return false
+	}
+	_, ok := fileValidator.Override(key)
+	return ok
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tf-generator.yaml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		problem string
+	}{
+		{name: "valid", content: "version: 1\ncustomer: acme\ntenants: [dev01]\n"},
+		{name: "json", content: `{"version": 1, "customer": "acme", "terraform": {"modules": true}}`},
+		{name: "unknown field", content: "version: 1\ncustomer: acme\ntenant: dev01\n", problem: `unknown field "tenant"`},
+		{name: "unknown nested field", content: "version: 1\ncustomer: acme\nterraform:\n  module_mode: true\n", problem: `unknown field "module_mode"`},
+		{name: "missing version", content: "customer: acme\n", problem: "version must be 1, got 0"},
+		{name: "version mismatch", content: "version: 2\ncustomer: acme\n", problem: "version must be 1, got 2"},
+		{name: "wrong type", content: "version: 1\ncustomer: acme\nparallelism: many\n", problem: "parallelism must be of type int, got string"},
+		{name: "several problems", content: "version: 1\ncustomer: acme\ntenants: [dev01]\ninfrastructure: nonprod\nskip: [tenant]\n", problem: "tenants and infrastructure can not be used together\n  - skip contains unknown project \"tenant\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileConfig, err := LoadFileConfig(writeTestConfigFile(t, test.content))
+			if len(test.problem) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fileConfig.Customer != "acme" {
+					t.Errorf("got customer %q, expected acme", fileConfig.Customer)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.problem) {
+				t.Errorf("got %v, expected an error with %q", err, test.problem)
+			}
+		})
+	}
+}
+
+func TestFileValidatorOverride(t *testing.T) {
+	path := writeTestConfigFile(t, `version: 1
+duplo_host: https://file.duplocloud.net
+duplo_token: file-token
+customer: acme
+tenants: [dev01, qa01]
+target_dir: file-target
+parallelism: 2
+filters:
+  include: [duplocloud_s3_bucket]
+`)
+	t.Setenv("duplo_host", "https://env.duplocloud.net")
+	t.Setenv("parallelism", "8")
+	t.Setenv("tenant_name", "env01")
+	t.Setenv("duplo_provider_version", "0.10.40")
+
+	tests := []struct {
+		name     string
+		override map[string]string
+		check    func(config *Config) string
+	}{
+		{
+			name: "file over env",
+			check: func(config *Config) string {
+				if config.DuploHost != "https://file.duplocloud.net" || config.Parallelism != 2 || config.TargetDir != "file-target" {
+					return "the file should win over the env"
+				}
+				if !reflect.DeepEqual(config.TenantNames, []string{"dev01", "qa01"}) {
+					return "the tenants of the file should be used"
+				}
+				if config.DuploProviderVersion != "0.10.40" {
+					return "the env should be used for keys missing from the file"
+				}
+				return ""
+			},
+		},
+		{
+			name:     "override over file",
+			override: map[string]string{"duplo_host": "https://flag.duplocloud.net", "parallelism": "4"},
+			check: func(config *Config) string {
+				if config.DuploHost != "https://flag.duplocloud.net" || config.Parallelism != 4 || config.TargetDir != "file-target" {
+					return "the override should win over the file"
+				}
+				return ""
+			},
+		},
+		{
+			name:     "tenant override",
+			override: map[string]string{"tenant_name": "flag01"},
+			check: func(config *Config) string {
+				if config.TenantName != "flag01" || len(config.TenantNames) > 0 {
+					return "a tenant passed as flag should replace the tenants of the file"
+				}
+				return ""
+			},
+		},
+		{
+			name:     "filter override",
+			override: map[string]string{"include_resources": "duplocloud_aws_sqs_queue"},
+			check: func(config *Config) string {
+				if !reflect.DeepEqual(config.IncludeResources, []string{"duplocloud_aws_sqs_queue"}) {
+					return "filters passed as flags should replace the filters of the file"
+				}
+				return ""
+			},
+		},
+		{
+			name: "file filters",
+			check: func(config *Config) string {
+				if !reflect.DeepEqual(config.IncludeResources, []string{"duplocloud_s3_bucket"}) || config.ResourceAllowed("duplocloud_aws_sqs_queue", "orders", nil) {
+					return "the filters of the file should be applied"
+				}
+				return ""
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator := FileValidator{Path: path}
+			if test.override != nil {
+				validator.Override = func(key string) (string, bool) {
+					value, ok := test.override[key]
+					return value, ok
+				}
+			}
+			config, err := validator.Validate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.ConfigFile != path {
+				t.Errorf("got config file %q, expected %q", config.ConfigFile, path)
+			}
+			if problem := test.check(config); len(problem) > 0 {
+				t.Errorf("%s, got %+v", problem, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package common
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"tenant-terraform-generator/duplosdk"
+)
+
+// DefaultExcludeResources are the system resources managed by DuploCloud itself. They are
+// excluded on top of the configured exclude rules.
+var DefaultExcludeResources = []string{
+	"duplocloud_duplo_service.*duploinfrasvc*",
+	"duplocloud_duplo_service.*dockerservices-shell*",
+	"duplocloud_duplo_service.*system-svc-*",
+	"duplocloud_k8_secret.*default-token*",
+	"duplocloud_k8_secret.*duploservices-*",
+	"duplocloud_k8_secret.*filebeat-token-*",
+	"duplocloud_k8_config_map.*kube-root-ca.crt*",
+}
+
+var defaultResourceFilter = mustResourceFilter(nil, nil)
+
+// ResourceFilter decides which resources are generated from include and exclude rules.
+//
+// A rule is "<resource type>", "<resource type>.<name>" or "tag:<key>=<value>". Resource types,
+// names and tag values are globs. A name written as /<regex>/ is matched as a regular expression.
+// When include rules are present, only matching resources are generated. Exclude rules always win.
+type ResourceFilter struct {
+	include []filterRule
+	exclude []filterRule
+}
+
+type filterRule struct {
+	resourceType string
+	name         string
+	nameRegex    *regexp.Regexp
+	tagKey       string
+	tagValue     string
+}
+
+// NewResourceFilter compiles the include and exclude rules along with DefaultExcludeResources.
+func NewResourceFilter(include, exclude []string) (*ResourceFilter, error) {
+	filter := &ResourceFilter{}
+	problems := []string{}
+	for _, rule := range include {
+		r, err := parseFilterRule(rule)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		filter.include = append(filter.include, r)
+	}
+	for _, rule := range append(append([]string{}, DefaultExcludeResources...), exclude...) {
+		r, err := parseFilterRule(rule)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		filter.exclude = append(filter.exclude, r)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid resource filters: %s", strings.Join(problems, ", "))
+	}
+	return filter, nil
+}
+
+func mustResourceFilter(include, exclude []string) *ResourceFilter {
+	filter, err := NewResourceFilter(include, exclude)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+// ValidateFilterRule reports whether a single include or exclude rule is well formed.
+func ValidateFilterRule(rule string) error {
+	_, err := parseFilterRule(rule)
+	return err
+}
+
+func parseFilterRule(rule string) (filterRule, error) {
+	r := filterRule{}
+	rule = strings.TrimSpace(rule)
+	if tag, ok := strings.CutPrefix(rule, "tag:"); ok {
+		key, value, hasValue := strings.Cut(tag, "=")
+		if len(key) == 0 {
+			return r, fmt.Errorf("%q must be written as tag:<key>=<value>", rule)
+		}
+		r.tagKey = key
+		r.tagValue = "*"
+		if hasValue {
+			r.tagValue = value
+		}
+		return r, checkGlob(rule, r.tagValue)
+	}
+
+	resourceType, name, _ := strings.Cut(rule, ".")
+	if len(resourceType) == 0 {
+		return r, fmt.Errorf("%q must start with a resource type", rule)
+	}
+	r.resourceType = resourceType
+	if len(name) > 1 && strings.HasPrefix(name, "/") && strings.HasSuffix(name, "/") {
+		nameRegex, err := regexp.Compile(name[1 : len(name)-1])
+		if err != nil {
+			return r, fmt.Errorf("%q has an invalid regex: %s", rule, err)
+		}
+		r.nameRegex = nameRegex
+		return r, checkGlob(rule, resourceType)
+	}
+	r.name = name
+	if err := checkGlob(rule, resourceType); err != nil {
+		return r, err
+	}
+	return r, checkGlob(rule, name)
+}
+
+func checkGlob(rule, pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q has an invalid pattern %q", rule, pattern)
+	}
+	return nil
+}
+
+func (r filterRule) matches(resourceType, name string, tags map[string]string) bool {
+	if len(r.tagKey) > 0 {
+		value, ok := tags[r.tagKey]
+		if !ok {
+			return false
+		}
+		matched, _ := path.Match(r.tagValue, value)
+		return matched
+	}
+	if matched, _ := path.Match(r.resourceType, resourceType); !matched {
+		return false
+	}
+	if r.nameRegex != nil {
+		return r.nameRegex.MatchString(name)
+	}
+	if len(r.name) == 0 {
+		return true
+	}
+	matched, _ := path.Match(r.name, name)
+	return matched
+}
+
+// Allows reports whether a resource of the given type, Duplo name and tags is generated. A nil
+// filter only applies DefaultExcludeResources.
+func (f *ResourceFilter) Allows(resourceType, name string, tags map[string]string) bool {
+	if f == nil {
+		f = defaultResourceFilter
+	}
+	if len(f.include) > 0 {
+		included := false
+		for _, r := range f.include {
+			if r.matches(resourceType, name, tags) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, r := range f.exclude {
+		if r.matches(resourceType, name, tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// TagsFromKeyValues converts Duplo key value tags for the resource filters.
+func TagsFromKeyValues(tags *[]duplosdk.DuploKeyStringValue) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	result := map[string]string{}
+	for _, tag := range *tags {
+		result[tag.Key] = tag.Value
+	}
+	return result
+}
+
+// TagsFromMap converts Duplo tags read as a generic map for the resource filters.
+func TagsFromMap(tags map[string]interface{}) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	result := map[string]string{}
+	for key, value := range tags {
+		result[key] = fmt.Sprint(value)
+	}
+	return result
+}
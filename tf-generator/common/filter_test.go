@@ -0,0 +1,103 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestParseFilterRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected filterRule
+		invalid  bool
+	}{
+		{rule: "duplocloud_s3_bucket", expected: filterRule{resourceType: "duplocloud_s3_bucket"}},
+		{rule: " duplocloud_s3_bucket.logs-* ", expected: filterRule{resourceType: "duplocloud_s3_bucket", name: "logs-*"}},
+		{rule: "duplocloud_*.orders", expected: filterRule{resourceType: "duplocloud_*", name: "orders"}},
+		{rule: "tag:team=payments", expected: filterRule{tagKey: "team", tagValue: "payments"}},
+		{rule: "tag:team", expected: filterRule{tagKey: "team", tagValue: "*"}},
+		{rule: "tag:env=", expected: filterRule{tagKey: "env", tagValue: ""}},
+		{rule: "tag:=payments", invalid: true},
+		{rule: ".orders", invalid: true},
+		{rule: "duplocloud_s3_bucket.[logs", invalid: true},
+		{rule: "duplocloud_[s3.logs", invalid: true},
+		{rule: "tag:team=[pay", invalid: true},
+		{rule: "duplocloud_s3_bucket./(logs/", invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			rule, err := parseFilterRule(test.rule)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("the rule should be rejected, got %+v", rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule != test.expected {
+				t.Errorf("got %+v, expected %+v", rule, test.expected)
+			}
+		})
+	}
+
+	rule, err := parseFilterRule("duplocloud_s3_bucket./^logs-(dev|prod)$/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.nameRegex == nil || rule.nameRegex.String() != "^logs-(dev|prod)$" || len(rule.name) > 0 {
+		t.Errorf("the name should be a regex, got %+v", rule)
+	}
+}
+
+func TestResourceFilterAllows(t *testing.T) {
+	payments := map[string]string{"team": "payments", "env": "prod"}
+	tests := []struct {
+		name         string
+		include      []string
+		exclude      []string
+		resourceType string
+		resource     string
+		tags         map[string]string
+		allowed      bool
+	}{
+		{name: "no rules", resourceType: "duplocloud_s3_bucket", resource: "logs", allowed: true},
+		{name: "default exclude", resourceType: "duplocloud_duplo_service", resource: "duploinfrasvc", allowed: false},
+		{name: "type glob", include: []string{"duplocloud_aws_*"}, resourceType: "duplocloud_aws_sqs_queue", resource: "orders", allowed: true},
+		{name: "type glob miss", include: []string{"duplocloud_aws_*"}, resourceType: "duplocloud_s3_bucket", resource: "logs", allowed: false},
+		{name: "name glob", include: []string{"duplocloud_s3_bucket.logs-*"}, resourceType: "duplocloud_s3_bucket", resource: "logs-dev", allowed: true},
+		{name: "name glob miss", include: []string{"duplocloud_s3_bucket.logs-*"}, resourceType: "duplocloud_s3_bucket", resource: "assets", allowed: false},
+		{name: "name regex", include: []string{"duplocloud_s3_bucket./^logs-(dev|prod)$/"}, resourceType: "duplocloud_s3_bucket", resource: "logs-prod", allowed: true},
+		{name: "name regex miss", include: []string{"duplocloud_s3_bucket./^logs-(dev|prod)$/"}, resourceType: "duplocloud_s3_bucket", resource: "logs-prod-old", allowed: false},
+		{name: "tag", include: []string{"tag:team=payments"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: true},
+		{name: "tag glob", include: []string{"tag:env=pr*"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: true},
+		{name: "tag key", include: []string{"tag:team"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: true},
+		{name: "tag miss", include: []string{"tag:team=data"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: false},
+		{name: "tag without tags", include: []string{"tag:team"}, resourceType: "duplocloud_s3_bucket", resource: "logs", allowed: false},
+		{name: "any include", include: []string{"tag:team=data", "duplocloud_s3_bucket"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: true},
+		{name: "exclude", exclude: []string{"duplocloud_s3_bucket.logs"}, resourceType: "duplocloud_s3_bucket", resource: "logs", allowed: false},
+		{name: "exclude other", exclude: []string{"duplocloud_s3_bucket.logs"}, resourceType: "duplocloud_s3_bucket", resource: "assets", allowed: true},
+		{name: "exclude over include", include: []string{"duplocloud_s3_bucket"}, exclude: []string{"tag:env=prod"}, resourceType: "duplocloud_s3_bucket", resource: "logs", tags: payments, allowed: false},
+		{name: "exclude over include name", include: []string{"duplocloud_s3_bucket.logs"}, exclude: []string{"duplocloud_s3_bucket.logs"}, resourceType: "duplocloud_s3_bucket", resource: "logs", allowed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewResourceFilter(test.include, test.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed := filter.Allows(test.resourceType, test.resource, test.tags); allowed != test.allowed {
+				t.Errorf("got %t, expected %t", allowed, test.allowed)
+			}
+		})
+	}
+
+	var filter *ResourceFilter
+	if filter.Allows("duplocloud_k8_secret", "default-token-abcde", nil) || !filter.Allows("duplocloud_k8_secret", "orders", nil) {
+		t.Errorf("a nil filter should only apply the default exclude rules")
+	}
+	_, err := NewResourceFilter([]string{"tag:=payments"}, []string{".orders"})
+	if err == nil {
+		t.Errorf("invalid rules should be rejected")
+	}
+}
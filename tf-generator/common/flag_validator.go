@@ -0,0 +1,139 @@
+package common
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ConfigFlag maps a command line flag onto the env variable read by EnvVarValidator.
+type ConfigFlag struct {
+	Name   string
+	EnvVar string
+	Usage  string
+	IsBool bool
+}
+
+var ConfigFlags = []ConfigFlag{
+	{Name: "config-file", EnvVar: "config_file", Usage: "Yaml or json config file. Flags take precedence over the file and the file over env variables."},
+	{Name: "duplo-host", EnvVar: "duplo_host", Usage: "DuploCloud portal url. (required)"},
+	{Name: "duplo-token", EnvVar: "duplo_token", Usage: "DuploCloud API token. (required)"},
+	{Name: "tenant-name", EnvVar: "tenant_name", Usage: "Name of the tenant to export. (required unless tenant-names or infra-name is set)"},
+	{Name: "tenant-names", EnvVar: "tenant_names", Usage: "Comma separated names of the tenants to export in a single run."},
+	{Name: "infra-name", EnvVar: "infra_name", Usage: "Export every tenant of this infrastructure."},
+	{Name: "customer-name", EnvVar: "customer_name", Usage: "Customer name used for the output folder. (required)"},
+	{Name: "duplo-provider-version", EnvVar: "duplo_provider_version", Usage: "DuploCloud provider version to be used. (default 0.10.0)"},
+	{Name: "tf-version", EnvVar: "tf_version", Usage: "Terraform version to be used. (default " + TF_DEFAULT_VERSION + ")"},
+	{Name: "tenant-project", EnvVar: "tenant_project", Usage: "Project name for tenant. (default admin-tenant)"},
+	{Name: "aws-services-project", EnvVar: "aws_services_project", Usage: "Project name for aws services. (default aws-services)"},
+	{Name: "app-project", EnvVar: "app_project", Usage: "Project name for app. (default app)"},
+	{Name: "admin-infra", EnvVar: "admin_infra", Usage: "Project name for admin infra. (default admin-infra)"},
+	{Name: "target-dir", EnvVar: "target_dir", Usage: "Folder where terraform projects are generated. (default target)"},
+	{Name: "parallelism", EnvVar: "parallelism", Usage: "Number of generators run concurrently for a project. (default 4)"},
+	{Name: "continue-on-error", EnvVar: "continue_on_error", Usage: "Keep generating when a resource fails and report the failures at the end.", IsBool: true},
+	{Name: "max-failures", EnvVar: "max_failures", Usage: "Number of failures tolerated before the run exits with an error. (default 0)"},
+	{Name: "enable-generators", EnvVar: "enable_generators", Usage: "Comma separated generators to run on top of the ones enabled by default. See list-generators."},
+	{Name: "disable-generators", EnvVar: "disable_generators", Usage: "Comma separated generators to leave out. See list-generators."},
+	{Name: "include-resources", EnvVar: "include_resources", Usage: "Comma separated filters of the resources to generate, like duplocloud_rds_instance, duplocloud_s3_bucket.logs-* or tag:team=data."},
+	{Name: "exclude-resources", EnvVar: "exclude_resources", Usage: "Comma separated filters of the resources to leave out. Same format as include-resources."},
+	{Name: "generate-tf-state", EnvVar: "generate_tf_state", Usage: "Import generated tf resources using terraform import.", IsBool: true},
+	{Name: "generate-import-blocks", EnvVar: "generate_import_blocks", Usage: "Write imports.tf with terraform import blocks instead of running terraform import.", IsBool: true},
+	{Name: "module-mode", EnvVar: "module_mode", Usage: "Move each file of resources into a local module instantiated by the project.", IsBool: true},
+	{Name: "collection-mode", EnvVar: "collection_mode", Usage: "Write the SQS queues, SNS topics, S3 buckets and config maps of a project as one resource with for_each per type.", IsBool: true},
+	{Name: "s3-backend", EnvVar: "s3_backend", Usage: "Generate the backend of the projects. See backend. (default true)", IsBool: true},
+	{Name: "backend", EnvVar: "backend", Usage: "Backend keeping the state of the projects, s3, local, gcs, azurerm, http or cloud. (default s3)"},
+	{Name: "backend-bucket", EnvVar: "backend_bucket", Usage: "Bucket of the s3 or gcs backend. (default duplo-tfstate-<account id> for s3)"},
+	{Name: "backend-region", EnvVar: "backend_region", Usage: "Region of the s3 backend. (default region of the default infrastructure)"},
+	{Name: "backend-dynamodb-table", EnvVar: "backend_dynamodb_table", Usage: "DynamoDB table locking the state of the s3 backend."},
+	{Name: "backend-key-prefix", EnvVar: "backend_key_prefix", Usage: "Prefix of the state keys of the s3, gcs and azurerm backends, or of the workspaces of the cloud backend."},
+	{Name: "backend-path", EnvVar: "backend_path", Usage: "Folder of the state files of the local backend, relative to the project folders."},
+	{Name: "backend-resource-group", EnvVar: "backend_resource_group", Usage: "Resource group of the storage account of the azurerm backend."},
+	{Name: "backend-storage-account", EnvVar: "backend_storage_account", Usage: "Storage account of the azurerm backend."},
+	{Name: "backend-container", EnvVar: "backend_container", Usage: "Container of the azurerm backend."},
+	{Name: "backend-address", EnvVar: "backend_address", Usage: "Base URL of the states of the http backend."},
+	{Name: "backend-organization", EnvVar: "backend_organization", Usage: "Organization of the cloud backend."},
+	{Name: "backend-hostname", EnvVar: "backend_hostname", Usage: "Hostname of the cloud backend. (default app.terraform.io)"},
+	{Name: "project-contract", EnvVar: "project_contract", Usage: "How the other projects read the values of the tenant project, remote_state, data_source, tfvars or ssm. (default remote_state)"},
+	{Name: "tenant-settings", EnvVar: "tenant_settings", Usage: "Comma separated tenant config settings written besides block_master_vpn, delete_protection, enable_alerting and enable_host_other_tenants."},
+	{Name: "validate-tf", EnvVar: "validate_tf", Usage: "Validate and format the generated tf code. (default true)", IsBool: true},
+	{Name: "skip-admin-tenant", EnvVar: "skip_admin_tenant", Usage: "Skip tf generation for admin-tenant.", IsBool: true},
+	{Name: "skip-aws-services", EnvVar: "skip_aws_services", Usage: "Skip tf generation for aws-services.", IsBool: true},
+	{Name: "skip-app", EnvVar: "skip_app", Usage: "Skip tf generation for app.", IsBool: true},
+	{Name: "skip-admin-infra", EnvVar: "skip_admin_infra", Usage: "Skip tf generation for admin-infra.", IsBool: true},
+	{Name: "secret-policy", EnvVar: "secret_policy", Usage: "Policy of secret values, actual, placeholder, variable, random_password or external. (default variable)"},
+	{Name: "secret-policies", EnvVar: "secret_policies", Usage: "Comma separated policies of resource types or attributes, like duplocloud_byoh.password=random_password."},
+	{Name: "secret-store", EnvVar: "secret_store", Usage: "Store read by the external secret policy, secretsmanager or ssm. (default secretsmanager)"},
+	{Name: "secret-store-prefix", EnvVar: "secret_store_prefix", Usage: "Prefix of the secrets or parameters read by the external secret policy. (default /duplocloud/)"},
+	{Name: "enable-k8s-secret-placeholder", EnvVar: "enable_k8s_secret_placeholder", Usage: "Same as secret-policies duplocloud_k8_secret=placeholder.", IsBool: true},
+	{Name: "secret-scan", EnvVar: "secret_scan", Usage: "Scan of the generated files for secrets, off, report, redact or fail. (default report)"},
+	{Name: "secret-scan-allow", EnvVar: "secret_scan_allow", Usage: "Comma separated regular expressions of the values ignored by the secret scan."},
+	{Name: "k8s-secret-placeholder", EnvVar: "k8s_secret_placeholder", Usage: "Placeholder written by the placeholder secret policy. (default replace-me)"},
+	{Name: "max-retries", EnvVar: "max_retries", Usage: "Retries of a DuploCloud API read failing with a timeout, 429 or 502-504. (default 3)"},
+	{Name: "request-timeout", EnvVar: "request_timeout", Usage: "Timeout in seconds of a single DuploCloud API request. (default 20)"},
+	{Name: "requests-per-second", EnvVar: "requests_per_second", Usage: "Limit of DuploCloud API requests per second across generators, 0 for no limit. (default 0)"},
+	{Name: "api-cache", EnvVar: "api_cache", Usage: "Read each DuploCloud API path once per run and share concurrent reads. (default true)", IsBool: true},
+	{Name: "api-cache-file", EnvVar: "api_cache_file", Usage: "Keep the DuploCloud API reads in this file across runs. It holds secret values so it is only readable by the owner."},
+	{Name: "api-cache-ttl", EnvVar: "api_cache_ttl", Usage: "Age after which api-cache-file is read from the portal again, like 30m or 12h. (default 1h)"},
+	{Name: "record-fixtures", EnvVar: "record_fixtures", Usage: "Record the DuploCloud API responses of the run into this fixture bundle. Secret values are redacted."},
+	{Name: "replay-fixtures", EnvVar: "replay_fixtures", Usage: "Generate from a recorded fixture bundle instead of the DuploCloud portal, duplo-host and duplo-token are not needed."},
+	{Name: "output-format", EnvVar: "output_format", Usage: "Format of the drift report, text or json. (default text)"},
+	{Name: "ssl-no-verify", EnvVar: "ssl_no_verify", Usage: "Skip TLS certificate verification for the DuploCloud portal.", IsBool: true},
+}
+
+// FlagValidator reads the config from command line flags. Env variables are used
+// for every flag which is not passed.
+type FlagValidator struct {
+	values map[string]string
+}
+
+// RegisterFlags adds a flag for each config key to the flag set.
+func (fv *FlagValidator) RegisterFlags(fs *flag.FlagSet) {
+	for _, cf := range ConfigFlags {
+		usage := fmt.Sprintf("%s Env: %s", cf.Usage, cf.EnvVar)
+		if cf.IsBool {
+			fs.Bool(cf.Name, false, usage)
+		} else {
+			fs.String(cf.Name, "", usage)
+		}
+	}
+}
+
+// Collect records the flags explicitly passed on the parsed flag set.
+func (fv *FlagValidator) Collect(fs *flag.FlagSet) {
+	fv.values = map[string]string{}
+	envVars := map[string]string{}
+	for _, cf := range ConfigFlags {
+		envVars[cf.Name] = cf.EnvVar
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if envVar, ok := envVars[f.Name]; ok {
+			fv.values[envVar] = f.Value.String()
+		}
+	})
+}
+
+func (fv *FlagValidator) flag(key string) (string, bool) {
+	value, ok := fv.values[key]
+	return value, ok
+}
+
+func (fv *FlagValidator) lookup(key string) (string, bool) {
+	if value, ok := fv.flag(key); ok {
+		return value, true
+	}
+	return os.LookupEnv(key)
+}
+
+func (fv *FlagValidator) Validate() (*Config, error) {
+	if configFile, _ := fv.lookup("config_file"); len(configFile) > 0 {
+		fileValidator := FileValidator{
+			Path:     configFile,
+			Override: fv.flag,
+		}
+		return fileValidator.Validate()
+	}
+	envVarValidator := EnvVarValidator{
+		Lookup: fv.lookup,
+	}
+	return envVarValidator.Validate()
+}
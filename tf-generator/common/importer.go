@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"tenant-terraform-generator/duplosdk"
 
@@ -39,11 +40,7 @@ func (i *Importer) Import(config *Config, importConfig *ImportConfig) {
 	}
 	//backend := "-backend-config=bucket=duplo-tfstate-" + config.AccountID + " -backend-config=dynamodb_table=duplo-tfstate-" + config.AccountID + "-lock"
 	//err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.BackendConfig("bucket=duplo-tfstate-"+config.AccountID), tfexec.BackendConfig("dynamodb_table=duplo-tfstate-"+config.AccountID+"-lock"))
-	if config.S3Backend {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.BackendConfig("bucket=duplo-tfstate-"+config.AccountID))
-	} else {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true))
-	}
+	err = tf.Init(context.Background(), config.InitOptions()...)
 
 	if err != nil {
 		log.Fatalf("error running Init: %s", err)
@@ -88,17 +85,17 @@ func (i *Importer) Import(config *Config, importConfig *ImportConfig) {
 	log.Println("[TRACE] <====================================================================>")
 }
 
-func (i *Importer) ImportWithoutInit(config *Config, importConfig *ImportConfig, tf *tfexec.Terraform) {
+func (i *Importer) ImportWithoutInit(config *Config, importConfig *ImportConfig, tf *tfexec.Terraform) error {
 	log.Println("[TRACE] <================================== TF Import in progress. ==================================>")
 	log.Printf("[TRACE] Importing terraform resource  : (%s, %s).", importConfig.ResourceAddress, importConfig.ResourceId)
 
 	err := tf.Import(context.Background(), importConfig.ResourceAddress, importConfig.ResourceId)
 	if err != nil {
-		log.Fatalf("error running Import: %s", err)
+		return fmt.Errorf("error running Import: %s", err)
 	}
 	_, err = tf.Show(context.Background())
 	if err != nil {
-		log.Fatalf("error running Show: %s", err)
+		return fmt.Errorf("error running Show: %s", err)
 	}
 
 	//_, err = json.Marshal(state.Values)
@@ -106,4 +103,5 @@ func (i *Importer) ImportWithoutInit(config *Config, importConfig *ImportConfig,
 
 	log.Printf("[TRACE] Terraform resource (%s, %s) is imported.", importConfig.ResourceAddress, importConfig.ResourceId)
 	log.Println("[TRACE] <====================================================================>")
+	return nil
 }
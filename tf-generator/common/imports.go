@@ -0,0 +1,79 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+type ImportBlocks struct {
+	TargetLocation string
+	ImportConfigs  []ImportConfig
+}
+
+// Generate writes imports.tf with one terraform import block per import config, so the
+// imports can be reviewed and are performed by "terraform plan/apply" instead of tfexec.
+func (ib *ImportBlocks) Generate() error {
+	if len(ib.ImportConfigs) == 0 {
+		return nil
+	}
+	log.Println("[TRACE] <====== Import blocks TF generation started. =====>")
+
+	hclFile := hclwrite.NewEmptyFile()
+	path := filepath.Join(ib.TargetLocation, "imports.tf")
+	tfFile, err := os.Create(path)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	defer tfFile.Close()
+
+	rootBody := hclFile.Body()
+	imported := map[string]bool{}
+	importConfigs := append([]ImportConfig{}, ib.ImportConfigs...)
+	sort.SliceStable(importConfigs, func(i, j int) bool { return importConfigs[i].ResourceAddress < importConfigs[j].ResourceAddress })
+	for _, ic := range importConfigs {
+		if len(ic.ResourceAddress) == 0 || imported[ic.ResourceAddress] {
+			continue
+		}
+		imported[ic.ResourceAddress] = true
+		importBlock := rootBody.AppendNewBlock("import", nil)
+		importBody := importBlock.Body()
+		importBody.SetAttributeTraversal("to", hcl.Traversal{
+			hcl.TraverseRoot{
+				Name: ic.ResourceAddress,
+			},
+		})
+		importBody.SetAttributeValue("id",
+			cty.StringVal(ic.ResourceId))
+		rootBody.AppendNewline()
+	}
+
+	_, err = tfFile.Write(hclFile.Bytes())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	log.Println("[TRACE] <====== Import blocks TF generation done. =====>")
+	return nil
+}
+
+// ValidateImportBlocksVersion makes sure the terraform version used for the generated
+// projects understands import blocks.
+func ValidateImportBlocksVersion(tfVersion string) error {
+	current, err := version.NewVersion(tfVersion)
+	if err != nil {
+		return fmt.Errorf("error - invalid terraform version \"%s\": %s", tfVersion, err)
+	}
+	if current.LessThan(version.Must(version.NewVersion(TF_IMPORT_BLOCKS_MIN_VERSION))) {
+		return fmt.Errorf("error - import blocks require terraform %s or later, \"%s\" is set as tf_version", TF_IMPORT_BLOCKS_MIN_VERSION, tfVersion)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -39,7 +40,9 @@ func (ov *OutputVars) Generate() {
 
 		// initialize the body of the new file object
 		rootBody := hclFile.Body()
-		for _, outVarConfig := range ov.OutputVars {
+		outVarConfigs := append([]OutputVarConfig{}, ov.OutputVars...)
+		sort.SliceStable(outVarConfigs, func(i, j int) bool { return outVarConfigs[i].Name < outVarConfigs[j].Name })
+		for _, outVarConfig := range outVarConfigs {
 			if len(outVarConfig.Name) > 0 {
 				outputVarblock := rootBody.AppendNewBlock("output",
 					[]string{outVarConfig.Name})
@@ -0,0 +1,395 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Policies writing a secret value into the generated code.
+const (
+	// SECRET_POLICY_ACTUAL writes the value returned by DuploCloud.
+	SECRET_POLICY_ACTUAL = "actual"
+	// SECRET_POLICY_PLACEHOLDER writes the configured placeholder.
+	SECRET_POLICY_PLACEHOLDER = "placeholder"
+	// SECRET_POLICY_VARIABLE reads a sensitive variable without default.
+	SECRET_POLICY_VARIABLE = "variable"
+	// SECRET_POLICY_RANDOM_PASSWORD reads a random_password resource.
+	SECRET_POLICY_RANDOM_PASSWORD = "random_password"
+	// SECRET_POLICY_EXTERNAL reads an AWS Secrets Manager secret or SSM parameter data source.
+	SECRET_POLICY_EXTERNAL = "external"
+)
+
+// Stores read by the external secret policy.
+const (
+	SECRET_STORE_SECRETS_MANAGER = "secretsmanager"
+	SECRET_STORE_SSM             = "ssm"
+)
+
+const (
+	DEFAULT_SECRET_POLICY       = SECRET_POLICY_VARIABLE
+	DEFAULT_SECRET_PLACEHOLDER  = "replace-me"
+	DEFAULT_SECRET_STORE        = SECRET_STORE_SECRETS_MANAGER
+	DEFAULT_SECRET_STORE_PREFIX = "/duplocloud/"
+	SECRETS_REPORT_FILE         = "secrets.txt"
+	SECRETS_REPORT_JSON_FILE    = "secrets.json"
+)
+
+// Modes of the secret scan run over the generated files.
+const (
+	SECRET_SCAN_OFF    = "off"
+	SECRET_SCAN_REPORT = "report"
+	// SECRET_SCAN_REDACT replaces the values found in the generated files.
+	SECRET_SCAN_REDACT = "redact"
+	// SECRET_SCAN_FAIL fails the run when a value is found.
+	SECRET_SCAN_FAIL    = "fail"
+	DEFAULT_SECRET_SCAN = SECRET_SCAN_REPORT
+)
+
+var SecretScanModes = []string{SECRET_SCAN_OFF, SECRET_SCAN_REPORT, SECRET_SCAN_REDACT, SECRET_SCAN_FAIL}
+
+var SecretPolicyNames = []string{SECRET_POLICY_ACTUAL, SECRET_POLICY_PLACEHOLDER, SECRET_POLICY_VARIABLE, SECRET_POLICY_RANDOM_PASSWORD, SECRET_POLICY_EXTERNAL}
+
+// DefaultSecretPolicyRules apply unless a configured rule is set for the same key, RDS passwords
+// have always been generated.
+var DefaultSecretPolicyRules = []string{
+	"duplocloud_rds_instance.master_password=" + SECRET_POLICY_RANDOM_PASSWORD,
+}
+
+var defaultSecretPolicies = mustSecretPolicies(DEFAULT_SECRET_POLICY, nil, DEFAULT_SECRET_PLACEHOLDER, DEFAULT_SECRET_STORE, DEFAULT_SECRET_STORE_PREFIX)
+
+var secretNameReplacer = regexp.MustCompile("[^a-z0-9_]+")
+
+// SecretPolicies decides how the secret values of the generated resources are written, and lists
+// the values it replaced. It is safe for concurrent use.
+//
+// A rule is "<resource type>=<policy>" or "<resource type>.<attribute>=<policy>", where the
+// attribute is a path like "password", "authenticate_oidc.client_secret" or "secret_data.<key>"
+// for a key of a map. The most specific rule wins. Values without a rule use the default policy.
+type SecretPolicies struct {
+	defaultPolicy string
+	rules         map[string]string
+	placeholder   string
+	store         string
+	storePrefix   string
+
+	mutex        sync.Mutex
+	replacements []SecretReplacement
+	// names are the names given to the attributes of each resource, so that two attributes cleaned
+	// into the same name do not share a variable.
+	names map[string]map[string]string
+}
+
+// Secret is a secret value of a generated resource.
+type Secret struct {
+	Project      string
+	ResourceType string
+	// ResourceName is the terraform name of the resource.
+	ResourceName string
+	// Attribute is the path of the value in the resource, like "password" or "secret_data.<key>".
+	Attribute string
+	Value     string
+	// Name of the variable or block generated for the value. It defaults to the resource type,
+	// name and attribute.
+	Name string
+}
+
+// SecretReplacement is a secret value written according to its policy. The value itself is never
+// part of it.
+type SecretReplacement struct {
+	Tenant    string `json:"tenant"`
+	Project   string `json:"project"`
+	Resource  string `json:"resource"`
+	Attribute string `json:"attribute"`
+	Policy    string `json:"policy"`
+	// Reference is the expression written instead of the value and Source the secret or parameter
+	// an external value is read from.
+	Reference string `json:"reference,omitempty"`
+	Source    string `json:"source,omitempty"`
+	// SuppliedAtApply is set when the value is not part of the code and must be supplied at apply
+	// time, as a variable, in the external store or in place of the placeholder.
+	SuppliedAtApply bool `json:"supplied_at_apply"`
+}
+
+// NewSecretPolicies compiles the rules on top of DefaultSecretPolicyRules.
+func NewSecretPolicies(defaultPolicy string, rules []string, placeholder, store, storePrefix string) (*SecretPolicies, error) {
+	policies := &SecretPolicies{
+		defaultPolicy: defaultPolicy,
+		rules:         map[string]string{},
+		placeholder:   placeholder,
+		store:         store,
+		storePrefix:   storePrefix,
+	}
+	problems := []string{}
+	if !Contains(SecretPolicyNames, defaultPolicy) {
+		problems = append(problems, fmt.Sprintf("policy must be one of %s, got %q", strings.Join(SecretPolicyNames, ", "), defaultPolicy))
+	}
+	switch store {
+	case SECRET_STORE_SECRETS_MANAGER, SECRET_STORE_SSM:
+	default:
+		problems = append(problems, fmt.Sprintf("store must be one of %s, %s, got %q", SECRET_STORE_SECRETS_MANAGER, SECRET_STORE_SSM, store))
+	}
+	for _, rule := range append(append([]string{}, DefaultSecretPolicyRules...), rules...) {
+		key, policy, err := parseSecretPolicyRule(rule)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		policies.rules[key] = policy
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid secret policies: %s", strings.Join(problems, ", "))
+	}
+	return policies, nil
+}
+
+func mustSecretPolicies(defaultPolicy string, rules []string, placeholder, store, storePrefix string) *SecretPolicies {
+	policies, err := NewSecretPolicies(defaultPolicy, rules, placeholder, store, storePrefix)
+	if err != nil {
+		panic(err)
+	}
+	return policies
+}
+
+// ValidateSecretPolicyRule reports whether a single secret policy rule is well formed.
+func ValidateSecretPolicyRule(rule string) error {
+	_, _, err := parseSecretPolicyRule(rule)
+	return err
+}
+
+func parseSecretPolicyRule(rule string) (string, string, error) {
+	key, policy, ok := strings.Cut(strings.TrimSpace(rule), "=")
+	key, policy = strings.TrimSpace(key), strings.TrimSpace(policy)
+	if !ok || len(key) == 0 || strings.HasPrefix(key, ".") {
+		return "", "", fmt.Errorf("%q must be written as <resource type>[.<attribute>]=<policy>", rule)
+	}
+	if !Contains(SecretPolicyNames, policy) {
+		return "", "", fmt.Errorf("%q has an unknown policy %q", rule, policy)
+	}
+	return key, policy, nil
+}
+
+// Policy returns the policy of an attribute of a resource type.
+func (p *SecretPolicies) Policy(resourceType, attribute string) string {
+	if p == nil {
+		p = defaultSecretPolicies
+	}
+	key := resourceType + "." + attribute
+	for {
+		if policy, ok := p.rules[key]; ok {
+			return policy
+		}
+		if key == resourceType {
+			return p.defaultPolicy
+		}
+		key = key[:strings.LastIndex(key, ".")]
+	}
+}
+
+// Tokens writes a secret value according to its policy. The variables it reads are added to the
+// context and the blocks to rootBody.
+func (p *SecretPolicies) Tokens(tenant string, rootBody *hclwrite.Body, tfContext *TFContext, secret Secret) hclwrite.Tokens {
+	if p == nil {
+		p = defaultSecretPolicies
+	}
+	name := secret.Name
+	if len(name) == 0 {
+		name = strings.TrimPrefix(secret.ResourceType, "duplocloud_") + "_" + secret.ResourceName + "_" + secret.Attribute
+	}
+	name = strings.Trim(secretNameReplacer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	name = p.uniqueName(tenant, secret, name)
+	replacement := SecretReplacement{
+		Tenant:    tenant,
+		Project:   secret.Project,
+		Resource:  secret.ResourceType + "." + secret.ResourceName,
+		Attribute: secret.Attribute,
+		Policy:    p.Policy(secret.ResourceType, secret.Attribute),
+	}
+
+	var tokens hclwrite.Tokens
+	switch replacement.Policy {
+	case SECRET_POLICY_ACTUAL:
+		tokens = hclwrite.TokensForValue(cty.StringVal(secret.Value))
+	case SECRET_POLICY_PLACEHOLDER:
+		tokens = hclwrite.TokensForValue(cty.StringVal(p.placeholder))
+	case SECRET_POLICY_VARIABLE:
+		description := "Value of " + secret.Attribute + " of " + replacement.Resource + "."
+		if secret.Attribute == "value" {
+			description = "Value of " + replacement.Resource + "."
+		}
+		tfContext.InputVars = append(tfContext.InputVars, VarConfig{
+			Name:      name,
+			TypeVal:   "string",
+			DescVal:   description,
+			Sensitive: true,
+		})
+		tokens = hclwrite.TokensForTraversal(hcl.Traversal{
+			hcl.TraverseRoot{Name: "var"},
+			hcl.TraverseAttr{Name: name},
+		})
+	case SECRET_POLICY_RANDOM_PASSWORD:
+		randomBody := rootBody.AppendNewBlock("resource", []string{"random_password", name}).Body()
+		randomBody.SetAttributeValue("length", cty.NumberIntVal(16))
+		randomBody.SetAttributeValue("special", cty.BoolVal(false))
+		tokens = hclwrite.TokensForTraversal(hcl.Traversal{
+			hcl.TraverseRoot{Name: "random_password"},
+			hcl.TraverseAttr{Name: name},
+			hcl.TraverseAttr{Name: "result"},
+		})
+	case SECRET_POLICY_EXTERNAL:
+		replacement.Source = p.storePrefix + tenant + "/" + strings.TrimPrefix(secret.ResourceType, "duplocloud_") + "/" + secret.ResourceName + "/" + secret.Attribute
+		dataType, attribute := "aws_secretsmanager_secret_version", "secret_string"
+		if p.store == SECRET_STORE_SSM {
+			dataType, attribute = "aws_ssm_parameter", "value"
+		}
+		dataBody := rootBody.AppendNewBlock("data", []string{dataType, name}).Body()
+		if p.store == SECRET_STORE_SSM {
+			dataBody.SetAttributeValue("name", cty.StringVal(replacement.Source))
+			dataBody.SetAttributeValue("with_decryption", cty.True)
+		} else {
+			dataBody.SetAttributeValue("secret_id", cty.StringVal(replacement.Source))
+		}
+		tokens = hclwrite.TokensForTraversal(hcl.Traversal{
+			hcl.TraverseRoot{Name: "data"},
+			hcl.TraverseAttr{Name: dataType},
+			hcl.TraverseAttr{Name: name},
+			hcl.TraverseAttr{Name: attribute},
+		})
+	}
+	if replacement.Policy != SECRET_POLICY_ACTUAL {
+		replacement.Reference = string(tokens.Bytes())
+	}
+	switch replacement.Policy {
+	case SECRET_POLICY_VARIABLE, SECRET_POLICY_EXTERNAL, SECRET_POLICY_PLACEHOLDER:
+		replacement.SuppliedAtApply = true
+	}
+	p.mutex.Lock()
+	p.replacements = append(p.replacements, replacement)
+	p.mutex.Unlock()
+	return tokens
+}
+
+// uniqueName adds a suffix to the name of an attribute when another attribute of the resource
+// already has it, like tls_crt_2 for "tls_crt" after "tls.crt". The callers write the attributes
+// of a resource in a stable order, so the suffixes are the same from one run to the next.
+func (p *SecretPolicies) uniqueName(tenant string, secret Secret, name string) string {
+	resource := tenant + "/" + secret.Project + "/" + secret.ResourceType + "." + secret.ResourceName
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.names == nil {
+		p.names = map[string]map[string]string{}
+	}
+	attributes, ok := p.names[resource]
+	if !ok {
+		attributes = map[string]string{}
+		p.names[resource] = attributes
+	}
+	unique := name
+	for i := 2; ; i++ {
+		attribute, used := attributes[unique]
+		if !used || attribute == secret.Attribute {
+			break
+		}
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	attributes[unique] = secret.Attribute
+	return unique
+}
+
+// Replacements returns the secret values written for a tenant, sorted by project, resource and
+// attribute.
+func (p *SecretPolicies) Replacements(tenant string) []SecretReplacement {
+	if p == nil {
+		p = defaultSecretPolicies
+	}
+	p.mutex.Lock()
+	replacements := []SecretReplacement{}
+	for _, replacement := range p.replacements {
+		if replacement.Tenant == tenant {
+			replacements = append(replacements, replacement)
+		}
+	}
+	p.mutex.Unlock()
+	sort.Slice(replacements, func(i, j int) bool {
+		a, b := replacements[i], replacements[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Attribute < b.Attribute
+	})
+	return replacements
+}
+
+// WriteReport stores the secret values written for a tenant as secrets.txt and secrets.json in
+// the given folder.
+func (p *SecretPolicies) WriteReport(dir, tenant string) error {
+	replacements := p.Replacements(tenant)
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d secret value(s) are written by policy in the generated code.\n", len(replacements))
+	if len(replacements) > 0 {
+		text.WriteString("\n")
+	}
+	for _, r := range replacements {
+		fmt.Fprintf(&text, "- %s %s %s: %s", r.Project, r.Resource, r.Attribute, r.Policy)
+		if len(r.Reference) > 0 {
+			fmt.Fprintf(&text, " %s", r.Reference)
+		}
+		if len(r.Source) > 0 {
+			fmt.Fprintf(&text, " from %s", r.Source)
+		}
+		text.WriteString("\n")
+	}
+	supplied := []SecretReplacement{}
+	for _, r := range replacements {
+		if r.SuppliedAtApply {
+			supplied = append(supplied, r)
+		}
+	}
+	if len(supplied) > 0 {
+		fmt.Fprintf(&text, "\n%d value(s) must be supplied at apply time.\n\n", len(supplied))
+	}
+	for _, r := range supplied {
+		fmt.Fprintf(&text, "- %s %s %s: ", r.Project, r.Resource, r.Attribute)
+		switch r.Policy {
+		case SECRET_POLICY_VARIABLE:
+			fmt.Fprintf(&text, "set %s\n", r.Reference)
+		case SECRET_POLICY_EXTERNAL:
+			fmt.Fprintf(&text, "store it in %s\n", r.Source)
+		default:
+			text.WriteString("replace the placeholder\n")
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, SECRETS_REPORT_FILE), []byte(text.String()), 0644)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{"count": len(replacements), "secrets": replacements}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, SECRETS_REPORT_JSON_FILE), content, 0644)
+}
+
+// SecretTokens writes a secret value of a resource according to the secret policies.
+func (c *Config) SecretTokens(rootBody *hclwrite.Body, tfContext *TFContext, secret Secret) hclwrite.Tokens {
+	return c.Secrets.Tokens(c.TenantName, rootBody, tfContext, secret)
+}
+
+// SetSecretAttribute sets the attribute of body named by the last element of the secret attribute
+// path to the secret value.
+func (c *Config) SetSecretAttribute(body, rootBody *hclwrite.Body, tfContext *TFContext, secret Secret) {
+	name := secret.Attribute[strings.LastIndex(secret.Attribute, ".")+1:]
+	body.SetAttributeRaw(name, c.SecretTokens(rootBody, tfContext, secret))
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+func TestSecretTokensCollidingKeys(t *testing.T) {
+	policies, err := NewSecretPolicies(SECRET_POLICY_VARIABLE, nil, DEFAULT_SECRET_PLACEHOLDER, DEFAULT_SECRET_STORE, DEFAULT_SECRET_STORE_PREFIX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := func(key string) Secret {
+		return Secret{
+			Project:      "app",
+			ResourceType: "duplocloud_k8_secret",
+			ResourceName: "orders_tls",
+			Attribute:    "secret_data." + key,
+			Value:        "value of " + key,
+		}
+	}
+	for run := 0; run < 2; run++ {
+		tfContext := TFContext{}
+		references := []string{}
+		for _, key := range []string{"tls.crt", "tls_crt", "tls.crt"} {
+			tokens := policies.Tokens("dev01", hclwrite.NewEmptyFile().Body(), &tfContext, secret(key))
+			references = append(references, strings.TrimSpace(string(tokens.Bytes())))
+		}
+		expected := []string{"var.k8_secret_orders_tls_secret_data_tls_crt", "var.k8_secret_orders_tls_secret_data_tls_crt_2", "var.k8_secret_orders_tls_secret_data_tls_crt"}
+		if strings.Join(references, ",") != strings.Join(expected, ",") {
+			t.Errorf("run %d: got %v, expected %v", run, references, expected)
+		}
+	}
+
+	// The same attribute of another resource keeps its name.
+	other := secret("tls_crt")
+	other.ResourceName = "billing_tls"
+	tokens := policies.Tokens("dev01", hclwrite.NewEmptyFile().Body(), &TFContext{}, other)
+	if reference := strings.TrimSpace(string(tokens.Bytes())); reference != "var.k8_secret_billing_tls_secret_data_tls_crt" {
+		t.Errorf("got %s", reference)
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"tenant-terraform-generator/duplosdk"
 
@@ -16,37 +17,40 @@ type TfInitializer struct {
 	Config     *Config
 }
 
-func (tfi *TfInitializer) InitWithWorkspace() *tfexec.Terraform {
+func (tfi *TfInitializer) InitWithWorkspace() (*tfexec.Terraform, error) {
 	log.Println("[TRACE] <================================== TF init in progress. ==================================>")
-	tfVersion := GetEnv("tf_version", TF_DEFAULT_VERSION)
+	tfVersion, err := version.NewVersion(tfi.Config.TFVersion)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing terraform version %s: %s", tfi.Config.TFVersion, err)
+	}
 	installer := &releases.ExactVersion{
 		Product: product.Terraform,
-		Version: version.Must(version.NewVersion(tfVersion)),
+		Version: tfVersion,
 	}
 
 	execPath, err := installer.Install(context.Background())
 	if err != nil {
-		log.Fatalf("error installing Terraform: %s", err)
+		return nil, fmt.Errorf("error installing Terraform: %s", err)
 	}
 	tf, err := tfexec.NewTerraform(tfi.WorkingDir, execPath)
 	if err != nil {
-		log.Fatalf("error running NewTerraform: %s", err)
+		return nil, fmt.Errorf("error running NewTerraform: %s", err)
 	}
 	//backend := "-backend-config=bucket=duplo-tfstate-" + config.AccountID + " -backend-config=dynamodb_table=duplo-tfstate-" + config.AccountID + "-lock"
 	//err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.BackendConfig("bucket=duplo-tfstate-"+config.AccountID), tfexec.BackendConfig("dynamodb_table=duplo-tfstate-"+config.AccountID+"-lock"))
-	if tfi.Config.S3Backend {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.BackendConfig("bucket=duplo-tfstate-"+tfi.Config.AccountID))
-	} else {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true))
-	}
-
+	err = tf.Init(context.Background(), tfi.Config.InitOptions()...)
 	if err != nil {
-		log.Fatalf("error running Init: %s", err)
+		return nil, fmt.Errorf("error running Init: %s", err)
+	}
+	if !tfi.Config.StateBackend().Workspaces() {
+		// The state of the tenant is named after it. See HTTPBackend and CloudBackend.
+		log.Println("[TRACE] Terraform initialized. The backend has no workspaces.")
+		return tf, nil
 	}
 
 	workspaceList, activeWorkspace, err := tf.WorkspaceList(context.Background())
 	if err != nil {
-		log.Fatalf("error running tf workspace list: %s", err)
+		return nil, fmt.Errorf("error running tf workspace list: %s", err)
 	}
 	if len(workspaceList) > 0 {
 		log.Printf("[TRACE] Workspace List (%s).", workspaceList)
@@ -56,19 +60,19 @@ func (tfi *TfInitializer) InitWithWorkspace() *tfexec.Terraform {
 	if duplosdk.Contains(workspaceList, tfi.Config.TenantName) {
 		err = tf.WorkspaceSelect(context.Background(), tfi.Config.TenantName)
 		if err != nil {
-			log.Fatalf("error running tf workspace select: %s", err)
+			return nil, fmt.Errorf("error running tf workspace select: %s", err)
 		}
 		log.Printf("[TRACE] (%s) workspace is selected.", tfi.Config.TenantName)
 	} else {
 		err := tf.WorkspaceNew(context.Background(), tfi.Config.TenantName)
 		if err != nil {
-			log.Fatalf("error running tf workspace new: %s", err)
+			return nil, fmt.Errorf("error running tf workspace new: %s", err)
 		}
 		log.Printf("[TRACE] (%s) workspace is created.", tfi.Config.TenantName)
 	}
 	log.Printf("[TRACE] Terraform initialized with new workspace - %s", tfi.Config.TenantName)
 	log.Println("[TRACE] <====================================================================>")
-	return tf
+	return tf, nil
 }
 
 func (tfi *TfInitializer) Init(config *Config, workingDir string) *tfexec.Terraform {
@@ -86,11 +90,7 @@ func (tfi *TfInitializer) Init(config *Config, workingDir string) *tfexec.Terraf
 	if err != nil {
 		log.Fatalf("error running NewTerraform: %s", err)
 	}
-	if config.S3Backend {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true), tfexec.BackendConfig("bucket=duplo-tfstate-"+config.AccountID), tfexec.BackendConfig("dynamodb_table=duplo-tfstate-"+config.AccountID+"-lock"))
-	} else {
-		err = tf.Init(context.Background(), tfexec.Upgrade(true))
-	}
+	err = tf.Init(context.Background(), config.InitOptions()...)
 
 	if err != nil {
 		log.Fatalf("error running Init: %s", err)
@@ -2,11 +2,14 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -115,27 +118,28 @@ func Contains(s []string, str string) bool {
 	return false
 }
 
-func RepalceStringInFile(file string, stringsToRepalce map[string]string) {
+func RepalceStringInFile(file string, stringsToRepalce map[string]string) error {
 	input, err := ioutil.ReadFile(file)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	newStr := string(input)
 	for key, element := range stringsToRepalce {
 		newStr = strings.Replace(newStr, key, element, -1)
 	}
 
-	err = ioutil.WriteFile(file, []byte(newStr), 0644)
-	if err != nil {
-		log.Fatalln(err)
-	}
+	return ioutil.WriteFile(file, []byte(newStr), 0644)
 }
 
-func ValidateAndFormatTfCode(tfDir, tfVersion string) {
+func ValidateAndFormatTfCode(tfDir, tfVersion string) error {
 	log.Printf("[TRACE] Validation and formatting of terraform code generated at %s is started.", tfDir)
+	parsedVersion, err := version.NewVersion(tfVersion)
+	if err != nil {
+		return fmt.Errorf("error parsing terraform version %s: %s", tfVersion, err)
+	}
 	installer := &releases.ExactVersion{
 		Product: product.Terraform,
-		Version: version.Must(version.NewVersion(tfVersion)),
+		Version: parsedVersion,
 		//Version: version.NewConstraint(">= 1.0, < 1.4"),
 	}
 	// constraint, _ := version.NewConstraint(">= 1.2.8")
@@ -146,25 +150,26 @@ func ValidateAndFormatTfCode(tfDir, tfVersion string) {
 
 	execPath, err := installer.Install(context.Background())
 	if err != nil {
-		log.Fatalf("error installing Terraform: %s", err)
+		return fmt.Errorf("error installing Terraform: %s", err)
 	}
 	tf, err := tfexec.NewTerraform(tfDir, execPath)
 	if err != nil {
-		log.Fatalf("error running NewTerraform: %s", err)
+		return fmt.Errorf("error running NewTerraform: %s", err)
 	}
 	log.Printf("[TRACE] Validation of terraform code generated at %s is started.", tfDir)
 	_, err = tf.Validate(context.Background())
 	if err != nil {
-		log.Fatalf("error running terraform validate: %s", err)
+		return fmt.Errorf("error running terraform validate: %s", err)
 	}
 	log.Printf("[TRACE] Validation of terraform code generated at %s is done.", tfDir)
 	log.Printf("[TRACE] Formatting of terraform code generated at %s is started.", tfDir)
 	err = tf.FormatWrite(context.Background())
 	if err != nil {
-		log.Fatalf("error running terraform format: %s", err)
+		return fmt.Errorf("error running terraform format: %s", err)
 	}
 	log.Printf("[TRACE] Formatting of terraform code generated at %s is done.", tfDir)
 	log.Printf("[TRACE] Validation and formatting of terraform code generated at %s is done.", tfDir)
+	return nil
 }
 
 func ExtractResourceNameFromArn(arn string) string {
@@ -256,8 +261,12 @@ func Int64SliceToListVal(ele []int64) []cty.Value {
 func ConstructConfigVars(vars []VarConfig) map[string]interface{} {
 	m := make(map[string]interface{})
 	for _, v := range vars {
-		if v.Name != "" {
-			m[v.Name] = v.DefaultVal
+		if v.Name != "" && !v.Sensitive {
+			if v.ConfigVal != nil {
+				m[v.Name] = v.ConfigVal
+			} else {
+				m[v.Name] = v.DefaultVal
+			}
 		}
 	}
 	return m
@@ -270,3 +279,65 @@ func MapStringToMapVal(m map[string]string) map[string]cty.Value {
 	return mp
 
 }
+
+// ListResourceAddresses returns the addresses of all resources and data sources declared in the
+// terraform files of a project folder and in the local modules it instantiates.
+func ListResourceAddresses(projectDir string) ([]string, error) {
+	return listResourceAddresses(projectDir, "")
+}
+
+func listResourceAddresses(projectDir, prefix string) ([]string, error) {
+	addresses := []string{}
+	files, err := filepath.Glob(filepath.Join(projectDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, file, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for _, block := range hclFile.Body.(*hclsyntax.Body).Blocks {
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				addresses = append(addresses, prefix+block.Labels[0]+"."+block.Labels[1])
+			case block.Type == "data" && len(block.Labels) == 2:
+				addresses = append(addresses, prefix+"data."+block.Labels[0]+"."+block.Labels[1])
+			case block.Type == "module" && len(block.Labels) == 1:
+				moduleDir, ok := LocalModuleDir(projectDir, block)
+				if !ok {
+					continue
+				}
+				moduleAddresses, err := listResourceAddresses(moduleDir, prefix+"module."+block.Labels[0]+".")
+				if err != nil {
+					return nil, err
+				}
+				addresses = append(addresses, moduleAddresses...)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// LocalModuleDir returns the folder of a module block whose source is a local path, like the
+// modules written by module mode.
+func LocalModuleDir(dir string, block *hclsyntax.Block) (string, bool) {
+	attribute, ok := block.Body.Attributes["source"]
+	if !ok {
+		return "", false
+	}
+	source, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() || source.Type() != cty.String || source.IsNull() {
+		return "", false
+	}
+	path := source.AsString()
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		return "", false
+	}
+	return filepath.Join(dir, filepath.FromSlash(path)), true
+}
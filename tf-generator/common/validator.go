@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type IValidator interface {
@@ -12,73 +15,112 @@ type IValidator interface {
 }
 
 type EnvVarValidator struct {
+	// Lookup resolves a config key. Defaults to os.LookupEnv.
+	Lookup func(key string) (string, bool)
+}
+
+func (envVar *EnvVarValidator) getenv(key string) string {
+	if envVar.Lookup != nil {
+		value, _ := envVar.Lookup(key)
+		return value
+	}
+	return os.Getenv(key)
 }
 
 func (envVar *EnvVarValidator) Validate() (*Config, error) {
-	host := os.Getenv("duplo_host")
-	if len(host) == 0 {
-		err := fmt.Errorf("error - Please provide \"%s\" as env variable", "duplo_host")
+	recordFixtures := envVar.getenv("record_fixtures")
+	replayFixtures := envVar.getenv("replay_fixtures")
+	if len(recordFixtures) > 0 && len(replayFixtures) > 0 {
+		err := fmt.Errorf("error - \"record_fixtures\" and \"replay_fixtures\" can not be used together")
 		log.Printf("[TRACE] - %s", err)
 		return nil, err
 	}
-	token := os.Getenv("duplo_token")
+	// A replayed run does not talk to the portal, so it needs no host or token.
+	host := envVar.getenv("duplo_host")
+	if len(host) == 0 {
+		if len(replayFixtures) == 0 {
+			err := missingConfigError("duplo_host")
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		host = REPLAY_DUPLO_HOST
+	}
+	token := envVar.getenv("duplo_token")
 	if len(token) == 0 {
-		err := fmt.Errorf("error - Please provide \"%s\" as env variable", "duplo_token")
+		if len(replayFixtures) == 0 {
+			err := missingConfigError("duplo_token")
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		token = REPLAY_DUPLO_TOKEN
+	}
+
+	tenantName := envVar.getenv("tenant_name")
+	tenantNames := []string{}
+	for _, name := range splitList(envVar.getenv("tenant_names")) {
+		if !Contains(tenantNames, name) {
+			tenantNames = append(tenantNames, name)
+		}
+	}
+	infraName := envVar.getenv("infra_name")
+	if len(tenantNames) > 0 && len(infraName) > 0 {
+		err := fmt.Errorf("error - \"tenant_names\" and \"infra_name\" can not be used together")
 		log.Printf("[TRACE] - %s", err)
 		return nil, err
 	}
-
-	tenantName := os.Getenv("tenant_name")
-	if len(tenantName) == 0 {
-		err := fmt.Errorf("error - please provide \"%s\" as env variable", "tenant_name")
+	if len(tenantNames) > 0 {
+		tenantName = tenantNames[0]
+	}
+	if len(tenantName) == 0 && len(infraName) == 0 {
+		err := fmt.Errorf("%s, or \"tenant_names\" or \"infra_name\" to export multiple tenants", missingConfigError("tenant_name"))
 		log.Printf("[TRACE] - %s", err)
 		return nil, err
 	}
-	custName := os.Getenv("customer_name")
+	custName := envVar.getenv("customer_name")
 	if len(custName) == 0 {
-		err := fmt.Errorf("error - please provide \"%s\" as env variable", "customer_name")
+		err := missingConfigError("customer_name")
 		log.Printf("[TRACE] - %s", err)
 		return nil, err
 	}
 
-	// certArn := os.Getenv("cert_arn")
+	// certArn := envVar.getenv("cert_arn")
 	// if len(certArn) == 0 {
 	// 	err := fmt.Errorf("error - please provide \"%s\" as env variable", "cert_arn")
 	// 	log.Printf("[TRACE] - %s", err)
 	// 	return nil, err
 	// }
 
-	duploProviderVersion := os.Getenv("duplo_provider_version")
+	duploProviderVersion := envVar.getenv("duplo_provider_version")
 	if len(duploProviderVersion) == 0 {
 		duploProviderVersion = "0.10.0"
 	}
 
-	tfVersion := os.Getenv("tf_version")
+	tfVersion := envVar.getenv("tf_version")
 	if len(tfVersion) == 0 {
 		tfVersion = TF_DEFAULT_VERSION
 	}
 
-	tenantProject := os.Getenv("tenant_project")
+	tenantProject := envVar.getenv("tenant_project")
 	if len(tenantProject) == 0 {
 		tenantProject = "admin-tenant"
 	}
 
-	awsServicesProject := os.Getenv("aws_services_project")
+	awsServicesProject := envVar.getenv("aws_services_project")
 	if len(awsServicesProject) == 0 {
 		awsServicesProject = "aws-services"
 	}
 
-	appProject := os.Getenv("app_project")
+	appProject := envVar.getenv("app_project")
 	if len(appProject) == 0 {
 		appProject = "app"
 	}
-	admininfra := os.Getenv("admin_infra")
+	admininfra := envVar.getenv("admin_infra")
 	if len(admininfra) == 0 {
 		admininfra = "admin-infra"
 	}
 	generateTfState := false
 
-	generateTfStateStr := os.Getenv("generate_tf_state")
+	generateTfStateStr := envVar.getenv("generate_tf_state")
 	if len(generateTfStateStr) == 0 {
 		generateTfState = false
 	} else {
@@ -91,16 +133,60 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 		generateTfState = generateTfStateBool
 	}
 
+	generateImportBlocks := false
+	generateImportBlocksStr := envVar.getenv("generate_import_blocks")
+	if len(generateImportBlocksStr) != 0 {
+		generateImportBlocksBool, err := strconv.ParseBool(generateImportBlocksStr)
+		if err != nil {
+			err = fmt.Errorf("error while reading generate_import_blocks from env vars %s", err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		generateImportBlocks = generateImportBlocksBool
+	}
+	if generateImportBlocks {
+		if len(envVar.getenv("tf_version")) == 0 {
+			tfVersion = TF_IMPORT_BLOCKS_DEFAULT_VERSION
+		} else if err := ValidateImportBlocksVersion(tfVersion); err != nil {
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+	}
+
+	moduleMode := false
+	moduleModeStr := envVar.getenv("module_mode")
+	if len(moduleModeStr) != 0 {
+		moduleModeBool, err := strconv.ParseBool(moduleModeStr)
+		if err != nil {
+			err = fmt.Errorf("error while reading module_mode from env vars %s", err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		moduleMode = moduleModeBool
+	}
+
+	collectionMode := false
+	collectionModeStr := envVar.getenv("collection_mode")
+	if len(collectionModeStr) != 0 {
+		collectionModeBool, err := strconv.ParseBool(collectionModeStr)
+		if err != nil {
+			err = fmt.Errorf("error while reading collection_mode from env vars %s", err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		collectionMode = collectionModeBool
+	}
+
 	validateTf := true
-	validateTfStr := os.Getenv("validate_tf")
+	validateTfStr := envVar.getenv("validate_tf")
 	if len(validateTfStr) == 0 {
 		validateTf = true
 	} else {
-		validateTf, _ = strconv.ParseBool(generateTfStateStr)
+		validateTf, _ = strconv.ParseBool(validateTfStr)
 	}
 
 	s3Backend := true
-	s3BackendStr := os.Getenv("s3_backend")
+	s3BackendStr := envVar.getenv("s3_backend")
 	if len(s3BackendStr) == 0 {
 		s3Backend = true
 	} else {
@@ -112,9 +198,39 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 		}
 		s3Backend = s3BackendBool
 	}
+	backendType := envVar.getenv("backend")
+	if len(backendType) == 0 {
+		backendType = DEFAULT_BACKEND
+	}
+	backendSettings := map[string]string{}
+	for _, setting := range BackendSettings {
+		backendSettings[setting] = envVar.getenv("backend_" + setting)
+	}
+	backend, err := NewBackend(backendType, backendSettings)
+	if err != nil {
+		err = fmt.Errorf("error while reading backend from env vars, %s", err)
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+
+	tenantSettings := append([]string{}, DEFAULT_TENANT_SETTINGS...)
+	for _, setting := range splitList(envVar.getenv("tenant_settings")) {
+		if !Contains(tenantSettings, setting) {
+			tenantSettings = append(tenantSettings, setting)
+		}
+	}
+
+	projectContract := envVar.getenv("project_contract")
+	if len(projectContract) == 0 {
+		projectContract = DEFAULT_CONTRACT
+	} else if !Contains(ContractTypes, projectContract) {
+		err = fmt.Errorf("error while reading project_contract from env vars, it must be one of %s: %q", strings.Join(ContractTypes, ", "), projectContract)
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
 
 	skipTenant := false
-	skipTenantStr := os.Getenv("skip_admin_tenant")
+	skipTenantStr := envVar.getenv("skip_admin_tenant")
 	if len(skipTenantStr) == 0 {
 		skipTenant = false
 	} else {
@@ -122,23 +238,62 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 	}
 
 	enableSecretPlaceholder := false
-	enableSecretPlaceholderStr := os.Getenv("enable_k8s_secret_placeholder")
+	enableSecretPlaceholderStr := envVar.getenv("enable_k8s_secret_placeholder")
 	if len(enableSecretPlaceholderStr) == 0 {
 		enableSecretPlaceholder = false
 	} else {
 		enableSecretPlaceholder, _ = strconv.ParseBool(enableSecretPlaceholderStr)
 	}
 
-	k8sSecretPlaceholder := "replace-me"
-	k8sSecretPlaceholderStr := os.Getenv("k8s_secret_placeholder")
+	k8sSecretPlaceholder := DEFAULT_SECRET_PLACEHOLDER
+	k8sSecretPlaceholderStr := envVar.getenv("k8s_secret_placeholder")
 	if len(k8sSecretPlaceholderStr) == 0 {
-		k8sSecretPlaceholder = "replace-me"
+		k8sSecretPlaceholder = DEFAULT_SECRET_PLACEHOLDER
 	} else {
 		k8sSecretPlaceholder = k8sSecretPlaceholderStr
 	}
 
+	secretPolicy := envVar.getenv("secret_policy")
+	if len(secretPolicy) == 0 {
+		secretPolicy = DEFAULT_SECRET_POLICY
+	}
+	secretPolicyRules := splitList(envVar.getenv("secret_policies"))
+	if enableSecretPlaceholder {
+		// Configured rules come later, so they still win over this one.
+		secretPolicyRules = append([]string{"duplocloud_k8_secret=" + SECRET_POLICY_PLACEHOLDER}, secretPolicyRules...)
+	}
+	secretStore := envVar.getenv("secret_store")
+	if len(secretStore) == 0 {
+		secretStore = DEFAULT_SECRET_STORE
+	}
+	secretStorePrefix := envVar.getenv("secret_store_prefix")
+	if len(secretStorePrefix) == 0 {
+		secretStorePrefix = DEFAULT_SECRET_STORE_PREFIX
+	}
+	secrets, err := NewSecretPolicies(secretPolicy, secretPolicyRules, k8sSecretPlaceholder, secretStore, secretStorePrefix)
+	if err != nil {
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+	secretScan := envVar.getenv("secret_scan")
+	if len(secretScan) == 0 {
+		secretScan = DEFAULT_SECRET_SCAN
+	} else if !Contains(SecretScanModes, secretScan) {
+		err = fmt.Errorf("error while reading secret_scan from env vars, it must be one of %s: %q", strings.Join(SecretScanModes, ", "), secretScan)
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+	secretScanAllow := splitList(envVar.getenv("secret_scan_allow"))
+	for _, pattern := range secretScanAllow {
+		if _, err = regexp.Compile(pattern); err != nil {
+			err = fmt.Errorf("error while reading secret_scan_allow from env vars, %q is not a regular expression: %s", pattern, err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+	}
+
 	skipAwsServices := false
-	skipAwsServicesStr := os.Getenv("skip_aws_services")
+	skipAwsServicesStr := envVar.getenv("skip_aws_services")
 	if len(skipAwsServicesStr) == 0 {
 		skipAwsServices = false
 	} else {
@@ -146,7 +301,7 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 	}
 
 	skipApp := false
-	skipAppStr := os.Getenv("skip_app")
+	skipAppStr := envVar.getenv("skip_app")
 	if len(skipAppStr) == 0 {
 		skipApp = false
 	} else {
@@ -154,17 +309,157 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 	}
 
 	skipAdminInfra := false
-	skipAdminInfraStr := os.Getenv("skip_admin_infra")
+	skipAdminInfraStr := envVar.getenv("skip_admin_infra")
 	if len(skipAdminInfraStr) == 0 {
 		skipAdminInfra = false
 	} else {
 		skipAdminInfra, _ = strconv.ParseBool(skipAdminInfraStr)
 	}
 
+	sslNoVerify := false
+	sslNoVerifyStr := envVar.getenv("ssl_no_verify")
+	if len(sslNoVerifyStr) != 0 {
+		sslNoVerifyBool, err := strconv.ParseBool(sslNoVerifyStr)
+		// Any non empty value used to disable ssl verification.
+		sslNoVerify = err != nil || sslNoVerifyBool
+	}
+
+	parallelism := DEFAULT_PARALLELISM
+	parallelismStr := envVar.getenv("parallelism")
+	if len(parallelismStr) != 0 {
+		parallelismInt, err := strconv.Atoi(parallelismStr)
+		if err != nil || parallelismInt < 1 {
+			err = fmt.Errorf("error while reading parallelism from env vars, it must be a positive number: %q", parallelismStr)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		parallelism = parallelismInt
+	}
+
+	continueOnError := false
+	continueOnErrorStr := envVar.getenv("continue_on_error")
+	if len(continueOnErrorStr) != 0 {
+		continueOnErrorBool, err := strconv.ParseBool(continueOnErrorStr)
+		if err != nil {
+			err = fmt.Errorf("error while reading continue_on_error from env vars %s", err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		continueOnError = continueOnErrorBool
+	}
+
+	maxFailures := 0
+	maxFailuresStr := envVar.getenv("max_failures")
+	if len(maxFailuresStr) != 0 {
+		maxFailuresInt, err := strconv.Atoi(maxFailuresStr)
+		if err != nil || maxFailuresInt < 0 {
+			err = fmt.Errorf("error while reading max_failures from env vars, it must be zero or a positive number: %q", maxFailuresStr)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		maxFailures = maxFailuresInt
+	}
+
+	maxRetries := DEFAULT_MAX_RETRIES
+	maxRetriesStr := envVar.getenv("max_retries")
+	if len(maxRetriesStr) != 0 {
+		maxRetriesInt, err := strconv.Atoi(maxRetriesStr)
+		if err != nil || maxRetriesInt < 0 {
+			err = fmt.Errorf("error while reading max_retries from env vars, it must be zero or a positive number: %q", maxRetriesStr)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		maxRetries = maxRetriesInt
+	}
+
+	requestTimeout := DEFAULT_REQUEST_TIMEOUT
+	requestTimeoutStr := envVar.getenv("request_timeout")
+	if len(requestTimeoutStr) != 0 {
+		requestTimeoutInt, err := strconv.Atoi(requestTimeoutStr)
+		if err != nil || requestTimeoutInt < 1 {
+			err = fmt.Errorf("error while reading request_timeout from env vars, it must be a positive number of seconds: %q", requestTimeoutStr)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		requestTimeout = requestTimeoutInt
+	}
+
+	requestsPerSecond := 0.0
+	requestsPerSecondStr := envVar.getenv("requests_per_second")
+	if len(requestsPerSecondStr) != 0 {
+		requestsPerSecondFloat, err := strconv.ParseFloat(requestsPerSecondStr, 64)
+		if err != nil || requestsPerSecondFloat < 0 {
+			err = fmt.Errorf("error while reading requests_per_second from env vars, it must be zero or a positive number: %q", requestsPerSecondStr)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		requestsPerSecond = requestsPerSecondFloat
+	}
+
+	apiCache := true
+	apiCacheStr := envVar.getenv("api_cache")
+	if len(apiCacheStr) != 0 {
+		apiCacheBool, err := strconv.ParseBool(apiCacheStr)
+		if err != nil {
+			err = fmt.Errorf("error while reading api_cache from env vars %s", err)
+			log.Printf("[TRACE] - %s", err)
+			return nil, err
+		}
+		apiCache = apiCacheBool
+	}
+
+	apiCacheFile := envVar.getenv("api_cache_file")
+	if len(apiCacheFile) > 0 && len(recordFixtures) > 0 {
+		// Reads served from the file would be missing from the recorded bundle.
+		err := fmt.Errorf("error - \"api_cache_file\" and \"record_fixtures\" can not be used together")
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+
+	apiCacheTTLStr := envVar.getenv("api_cache_ttl")
+	if len(apiCacheTTLStr) == 0 {
+		apiCacheTTLStr = DEFAULT_API_CACHE_TTL
+	}
+	apiCacheTTL, err := time.ParseDuration(apiCacheTTLStr)
+	if err != nil || apiCacheTTL < 0 {
+		err = fmt.Errorf("error while reading api_cache_ttl from env vars, it must be a duration like 30m or 12h: %q", apiCacheTTLStr)
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+
+	outputFormat := envVar.getenv("output_format")
+	switch outputFormat {
+	case "":
+		outputFormat = OUTPUT_FORMAT_TEXT
+	case OUTPUT_FORMAT_TEXT, OUTPUT_FORMAT_JSON:
+	default:
+		err := fmt.Errorf("error while reading output_format from env vars, it must be one of text, json: %q", outputFormat)
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+
+	enableGenerators := splitList(envVar.getenv("enable_generators"))
+	disableGenerators := splitList(envVar.getenv("disable_generators"))
+
+	includeResources := splitList(envVar.getenv("include_resources"))
+	excludeResources := splitList(envVar.getenv("exclude_resources"))
+	resourceFilter, err := NewResourceFilter(includeResources, excludeResources)
+	if err != nil {
+		log.Printf("[TRACE] - %s", err)
+		return nil, err
+	}
+
+	targetDir := envVar.getenv("target_dir")
+	if len(targetDir) == 0 {
+		targetDir = "target"
+	}
+
 	return &Config{
 		DuploHost:               host,
 		DuploToken:              token,
 		TenantName:              tenantName,
+		TenantNames:             tenantNames,
+		InfraName:               infraName,
 		CustomerName:            custName,
 		DuploProviderVersion:    duploProviderVersion,
 		TenantProject:           tenantProject,
@@ -179,6 +474,58 @@ func (envVar *EnvVarValidator) Validate() (*Config, error) {
 		SkipApp:                 skipApp,
 		EnableSecretPlaceholder: enableSecretPlaceholder,
 		K8sSecretPlaceholder:    k8sSecretPlaceholder,
+		SecretPolicy:            secretPolicy,
+		SecretPolicyRules:       secretPolicyRules,
+		SecretStore:             secretStore,
+		SecretStorePrefix:       secretStorePrefix,
+		Secrets:                 secrets,
 		SkipAdminInfra:          skipAdminInfra,
+		GenerateImportBlocks:    generateImportBlocks,
+		SslNoVerify:             sslNoVerify,
+		TargetDir:               targetDir,
+		Parallelism:             parallelism,
+		ContinueOnError:         continueOnError,
+		MaxFailures:             maxFailures,
+		EnableGenerators:        enableGenerators,
+		DisableGenerators:       disableGenerators,
+		IncludeResources:        includeResources,
+		ExcludeResources:        excludeResources,
+		ResourceFilter:          resourceFilter,
+		MaxRetries:              maxRetries,
+		RequestTimeout:          requestTimeout,
+		RequestsPerSecond:       requestsPerSecond,
+		APICache:                apiCache,
+		APICacheFile:            apiCacheFile,
+		APICacheTTL:             apiCacheTTL,
+		OutputFormat:            outputFormat,
+		RecordFixtures:          recordFixtures,
+		ReplayFixtures:          replayFixtures,
+		SecretScan:              secretScan,
+		SecretScanAllow:         secretScanAllow,
+		ModuleMode:              moduleMode,
+		CollectionMode:          collectionMode,
+		Backend:                 backend,
+		ProjectContract:         projectContract,
+		TenantSettings:          tenantSettings,
 	}, nil
 }
+
+func missingConfigError(key string) error {
+	for _, cf := range ConfigFlags {
+		if cf.EnvVar == key {
+			return fmt.Errorf("error - please provide \"%s\" as env variable or --%s flag", key, cf.Name)
+		}
+	}
+	return fmt.Errorf("error - please provide \"%s\" as env variable", key)
+}
+
+// splitList splits a comma separated value. Empty entries are dropped.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
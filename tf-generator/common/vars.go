@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -16,6 +18,10 @@ type VarConfig struct {
 	TypeVal    string
 	DefaultVal string
 	DescVal    string
+	// Sensitive variables hold secret values. They have no default and are left out of the config.
+	Sensitive bool
+	// ConfigVal is written to the config instead of DefaultVal, like the items of a collection.
+	ConfigVal interface{}
 }
 
 type Vars struct {
@@ -40,7 +46,10 @@ func (v *Vars) Generate() {
 
 		// initialize the body of the new file object
 		rootBody := hclFile.Body()
-		for _, varConfig := range v.Vars {
+		// Sorted by name so that vars.tf does not depend on the order of the generators.
+		varConfigs := append([]VarConfig{}, v.Vars...)
+		sort.SliceStable(varConfigs, func(i, j int) bool { return varConfigs[i].Name < varConfigs[j].Name })
+		for _, varConfig := range varConfigs {
 			if len(varConfig.Name) > 0 {
 				varblock := rootBody.AppendNewBlock("variable",
 					[]string{varConfig.Name})
@@ -68,17 +77,24 @@ func (v *Vars) Generate() {
 						cty.StringVal(varConfig.DescVal))
 				}
 
-				varBody.SetAttributeTraversal("type", hcl.Traversal{
-					hcl.TraverseRoot{
-						Name: varConfig.TypeVal,
-					},
-				})
+				if typeTokens := typeConstraintTokens(varConfig.TypeVal); typeTokens != nil {
+					varBody.SetAttributeRaw("type", typeTokens)
+				} else {
+					varBody.SetAttributeTraversal("type", hcl.Traversal{
+						hcl.TraverseRoot{
+							Name: varConfig.TypeVal,
+						},
+					})
+				}
+				if varConfig.Sensitive {
+					varBody.SetAttributeValue("sensitive", cty.True)
+				}
 			}
 
 		}
 
 		fmt.Printf("%s", hclFile.Bytes())
-		_, err = tfFile.Write(hclFile.Bytes())
+		_, err = tfFile.Write(hclwrite.Format(hclFile.Bytes()))
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -86,3 +102,16 @@ func (v *Vars) Generate() {
 		log.Println("[TRACE] <====== Variables TF generation done. =====>")
 	}
 }
+
+// typeConstraintTokens returns the tokens of a type constraint which is not a single keyword, like
+// map(object({ name = string })).
+func typeConstraintTokens(typeVal string) hclwrite.Tokens {
+	if !strings.ContainsAny(typeVal, "({") {
+		return nil
+	}
+	file, diags := hclwrite.ParseConfig([]byte("type = "+typeVal+"\n"), "type", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil
+	}
+	return file.Body().GetAttribute("type").Expr().BuildTokens(nil)
+}
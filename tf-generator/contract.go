@@ -0,0 +1,327 @@
+package tfgenerator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// CONTRACT_FILE holds the SSM parameters written by the tenant project for the ssm contract.
+const CONTRACT_FILE = "contract.tf"
+
+// contractRemoteState is the data source reading the state of the tenant project. See
+// common.TenantProjectState.
+var contractRemoteState = []string{"terraform_remote_state", "tenant"}
+
+// ApplyProjectContract makes a project read the outputs of the tenant project through the contract
+// of the config instead of its remote state, keeping only the outputs the project uses. It returns
+// them. A project which does not read the remote state of the tenant project is left as is.
+func ApplyProjectContract(projectDir string, tfContext *common.TFContext, config *common.Config) ([]string, error) {
+	path := filepath.Join(projectDir, "main.tf")
+	src, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	mainFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var remoteState *hclsyntax.Block
+	// contractLocals maps the locals reading the remote state onto the outputs they read.
+	contractLocals := map[string]string{}
+	for _, block := range mainFile.Body.(*hclsyntax.Body).Blocks {
+		switch {
+		case block.Type == "data" && strings.Join(block.Labels, ".") == strings.Join(contractRemoteState, "."):
+			remoteState = block
+		case block.Type == "locals":
+			for name, attribute := range block.Body.Attributes {
+				if output, ok := remoteStateOutput(attribute.Expr); ok {
+					contractLocals[name] = output
+				}
+			}
+		}
+	}
+	if remoteState == nil {
+		return nil, nil
+	}
+	// Locals like tfstate_bucket are only there for the remote state.
+	remoteStateLocals := map[string]bool{}
+	walkAttributes(remoteState.Body, func(_ string, attribute *hclsyntax.Attribute, _ bool) {
+		for _, traversal := range attribute.Expr.Variables() {
+			if name, ok := localName(traversal); ok {
+				remoteStateLocals[name] = true
+			}
+		}
+	})
+
+	used, err := usedLocals(projectDir, remoteState, contractLocals, tfContext)
+	if err != nil {
+		return nil, err
+	}
+	outputs := []string{}
+	for _, name := range sortedKeys(contractLocals) {
+		if used[name] {
+			outputs = appendMissing(outputs, contractLocals[name])
+		}
+	}
+	sort.Strings(outputs)
+
+	hclFile, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	rootBody := hclFile.Body()
+	contractBlocks := hclwrite.NewEmptyFile()
+	inputVars := map[string]bool{}
+	for _, inputVar := range tfContext.InputVars {
+		inputVars[inputVar.Name] = true
+	}
+	values := common.ContractValues(config)
+	for _, block := range rootBody.Blocks() {
+		switch {
+		case block.Type() == "data" && strings.Join(block.Labels(), ".") == strings.Join(contractRemoteState, "."):
+			rootBody.RemoveBlock(block)
+		case block.Type() == "locals":
+			localsBody := block.Body()
+			for name := range localsBody.Attributes() {
+				if remoteStateLocals[name] && !used[name] {
+					localsBody.RemoveAttribute(name)
+				}
+				output, ok := contractLocals[name]
+				if !ok {
+					continue
+				}
+				if !used[name] {
+					localsBody.RemoveAttribute(name)
+					continue
+				}
+				switch {
+				case output == "tenant_name" && config.ProjectContract != common.CONTRACT_TFVARS:
+					common.SetTenantName(localsBody, name, config)
+				case output == "tenant_id" && config.ProjectContract == common.CONTRACT_DATA_SOURCE:
+					localsBody.SetAttributeTraversal(name, hcl.Traversal{
+						hcl.TraverseRoot{Name: "data"},
+						hcl.TraverseAttr{Name: "duplocloud_tenant"},
+						hcl.TraverseAttr{Name: "tenant"},
+						hcl.TraverseAttr{Name: "id"},
+					})
+				case config.ProjectContract == common.CONTRACT_SSM:
+					localsBody.SetAttributeRaw(name, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(
+						`jsondecode(nonsensitive(data.aws_ssm_parameter.contract.value))["` + output + `"]`,
+					)}})
+				default:
+					localsBody.SetAttributeTraversal(name, hcl.Traversal{
+						hcl.TraverseRoot{Name: "var"},
+						hcl.TraverseAttr{Name: output},
+					})
+					if !inputVars[output] {
+						inputVars[output] = true
+						tfContext.InputVars = append(tfContext.InputVars, common.VarConfig{
+							Name:       output,
+							DefaultVal: values[output],
+							TypeVal:    "string",
+							DescVal:    "The " + output + " output of the " + config.TenantProject + " project.",
+						})
+					}
+				}
+			}
+		}
+	}
+	switch config.ProjectContract {
+	case common.CONTRACT_DATA_SOURCE:
+		if common.Contains(outputs, "tenant_id") {
+			tenantBody := contractBlocks.Body().AppendNewBlock("data", []string{"duplocloud_tenant", "tenant"}).Body()
+			common.SetTenantName(tenantBody, "name", config)
+		}
+	case common.CONTRACT_SSM:
+		if len(contractParameterOutputs(outputs)) > 0 {
+			parameterBody := contractBlocks.Body().AppendNewBlock("data", []string{"aws_ssm_parameter", "contract"}).Body()
+			parameterBody.SetAttributeRaw("name", contractParameterName(filepath.Base(projectDir), config))
+		}
+	}
+
+	out := strings.TrimRight(string(hclFile.Bytes()), "\n") + "\n"
+	if len(contractBlocks.Body().Blocks()) > 0 {
+		out += "\n" + string(contractBlocks.Bytes())
+	}
+	err = os.WriteFile(path, hclwrite.Format([]byte(out)), 0644)
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// remoteStateOutput returns the output read by data.terraform_remote_state.tenant.outputs["name"].
+func remoteStateOutput(expr hclsyntax.Expression) (string, bool) {
+	scope, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(scope.Traversal) != 5 || scope.Traversal.RootName() != "data" {
+		return "", false
+	}
+	for i, name := range append(append([]string{}, contractRemoteState...), "outputs") {
+		attr, ok := scope.Traversal[i+1].(hcl.TraverseAttr)
+		if !ok || attr.Name != name {
+			return "", false
+		}
+	}
+	index, ok := scope.Traversal[4].(hcl.TraverseIndex)
+	if !ok || index.Key.Type() != cty.String {
+		return "", false
+	}
+	return index.Key.AsString(), true
+}
+
+// localName returns the name of the local of a traversal like local.tenant_id.
+func localName(traversal hcl.Traversal) (string, bool) {
+	if traversal.RootName() != "local" || len(traversal) < 2 {
+		return "", false
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	return attr.Name, ok
+}
+
+// usedLocals returns the locals read by the files and outputs of a project, apart from the remote
+// state of the tenant project. The contract locals must be the only readers of the remote state.
+func usedLocals(projectDir string, remoteState *hclsyntax.Block, contractLocals map[string]string, tfContext *common.TFContext) (map[string]bool, error) {
+	paths, err := filepath.Glob(filepath.Join(projectDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for _, block := range hclFile.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == remoteState.Type && strings.Join(block.Labels, ".") == strings.Join(remoteState.Labels, ".") {
+				continue
+			}
+			walkAttributes(block.Body, func(name string, attribute *hclsyntax.Attribute, _ bool) {
+				_, contractLocal := contractLocals[name]
+				for _, traversal := range attribute.Expr.Variables() {
+					if local, ok := localName(traversal); ok {
+						used[local] = true
+					}
+					if block.Type == "locals" && contractLocal {
+						continue
+					}
+					if strings.HasPrefix(traversalString(traversal), "data."+strings.Join(contractRemoteState, ".")) {
+						err = fmt.Errorf("%s reads the remote state of the tenant project outside of the locals", filepath.Base(path))
+					}
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, outputVar := range tfContext.OutputVars {
+		expr, diags := hclsyntax.ParseExpression([]byte(outputVar.ActualVal), outputVar.Name, hcl.InitialPos)
+		if diags.HasErrors() {
+			continue
+		}
+		for _, traversal := range expr.Variables() {
+			if local, ok := localName(traversal); ok {
+				used[local] = true
+			}
+		}
+	}
+	return used, nil
+}
+
+// traversalString returns the attributes of a traversal joined by dots, like data.aws_region.current.
+func traversalString(traversal hcl.Traversal) string {
+	names := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		attr, ok := step.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+		names = append(names, attr.Name)
+	}
+	return strings.Join(names, ".")
+}
+
+// contractParameterOutputs are the outputs read from the SSM parameter of a project. The tenant name
+// is known to every project as the parameter is named after it.
+func contractParameterOutputs(outputs []string) []string {
+	parameterOutputs := []string{}
+	for _, output := range outputs {
+		if output != "tenant_name" {
+			parameterOutputs = append(parameterOutputs, output)
+		}
+	}
+	return parameterOutputs
+}
+
+// contractParameterName is the name of the SSM parameter holding the outputs a project reads.
+func contractParameterName(project string, config *common.Config) hclwrite.Tokens {
+	tenant := config.TenantName
+	if config.StateBackend().Workspaces() {
+		tenant = "${terraform.workspace}"
+	}
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(common.CONTRACT_SSM_PREFIX + "/" + tenant + "/contract/" + project)},
+		{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
+	}
+}
+
+// WriteContractParameters writes contract.tf into the tenant project for the ssm contract, with an
+// SSM parameter per project holding the outputs the project reads.
+func WriteContractParameters(tenantProjectDir string, projectOutputs map[string][]string, config *common.Config) error {
+	path := filepath.Join(tenantProjectDir, "outputs.tf")
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	outputsFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return diags
+	}
+	values := map[string]string{}
+	for _, block := range outputsFile.Body.(*hclsyntax.Body).Blocks {
+		if value, ok := block.Body.Attributes["value"]; ok && block.Type == "output" {
+			values[block.Labels[0]] = exprSource(value.Expr, src)
+		}
+	}
+
+	hclFile := hclwrite.NewEmptyFile()
+	for _, project := range sortedKeys(projectOutputs) {
+		outputs := contractParameterOutputs(projectOutputs[project])
+		if len(outputs) == 0 {
+			continue
+		}
+		attributes := []common.ObjectAttrTokens{}
+		for _, output := range outputs {
+			value, ok := values[output]
+			if !ok {
+				return fmt.Errorf("the %s project reads the output %s which the tenant project does not have", project, output)
+			}
+			attributes = append(attributes, common.ObjectAttrTokens{
+				Name:  hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: output}}),
+				Value: hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(value)}},
+			})
+		}
+		parameterBody := hclFile.Body().AppendNewBlock("resource", []string{"aws_ssm_parameter", "contract_" + strings.ReplaceAll(project, "-", "_")}).Body()
+		parameterBody.SetAttributeRaw("name", contractParameterName(project, config))
+		parameterBody.SetAttributeValue("type", cty.StringVal("String"))
+		parameterBody.SetAttributeRaw("value", hclwrite.TokensForFunctionCall("jsonencode", common.TokensForObject(attributes)))
+	}
+	return writeModuleFile(filepath.Join(tenantProjectDir, CONTRACT_FILE), hclFile)
+}
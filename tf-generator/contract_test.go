@@ -0,0 +1,109 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateContracts checks that each contract replaces the remote state of the tenant project, and
+// that the projects only read the outputs they use.
+func TestGenerateContracts(t *testing.T) {
+	tests := []struct {
+		contract string
+		locals   string
+		reads    string
+	}{
+		{
+			contract: "data_source",
+			locals:   "  tenant_id   = data.duplocloud_tenant.tenant.id\n  cert_arn    = var.cert_arn\n  tenant_name = terraform.workspace\n",
+			reads:    "data \"duplocloud_tenant\" \"tenant\" {\n  name = terraform.workspace\n}\n",
+		},
+		{
+			contract: "tfvars",
+			locals:   "  tenant_id   = var.tenant_id\n  cert_arn    = var.cert_arn\n  tenant_name = var.tenant_name\n",
+		},
+		{
+			contract: "ssm",
+			locals:   "  tenant_id   = jsondecode(nonsensitive(data.aws_ssm_parameter.contract.value))[\"tenant_id\"]\n  cert_arn    = jsondecode(nonsensitive(data.aws_ssm_parameter.contract.value))[\"cert_arn\"]\n  tenant_name = terraform.workspace\n",
+			reads:    "data \"aws_ssm_parameter\" \"contract\" {\n  name = \"/duplocloud/${terraform.workspace}/contract/app\"\n}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.contract, func(t *testing.T) {
+			targetDir := t.TempDir()
+			config := testConfig(t, targetDir, map[string]string{"project_contract": test.contract})
+			client, _ := testClient(t)
+			generateFixtureTenant(t, config, client)
+			files, err := readFiles(targetDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			appMain := files[filepath.Join("terraform", config.AppProject, "main.tf")]
+			if !strings.Contains(appMain, "locals {\n  region      = var.region\n"+test.locals+"}\n") {
+				t.Errorf("got app main.tf\n%s\nexpected the locals\n%s", appMain, test.locals)
+			}
+			if !strings.Contains(appMain, test.reads) {
+				t.Errorf("got app main.tf\n%s\nexpected\n%s", appMain, test.reads)
+			}
+			awsServicesMain := files[filepath.Join("terraform", config.AwsServicesProject, "main.tf")]
+			for _, main := range []string{appMain, awsServicesMain} {
+				if strings.Contains(main, "terraform_remote_state") || strings.Contains(main, "tfstate_bucket") {
+					t.Errorf("the remote state should be replaced:\n%s", main)
+				}
+			}
+			if strings.Contains(awsServicesMain, "cert_arn") {
+				t.Errorf("aws-services does not read the certificate:\n%s", awsServicesMain)
+			}
+
+			contract, hasContract := files[filepath.Join("terraform", config.TenantProject, CONTRACT_FILE)]
+			if hasContract != (test.contract == "ssm") {
+				t.Errorf("only the ssm contract writes %s, got\n%s", CONTRACT_FILE, contract)
+			}
+			if test.contract == "ssm" {
+				parameter := "resource \"aws_ssm_parameter\" \"contract_aws_services\" {\n  name = \"/duplocloud/${terraform.workspace}/contract/aws-services\"\n  type = \"String\"\n  value = jsonencode({\n    tenant_id = duplocloud_tenant.tenant.tenant_id\n  })\n}\n"
+				if !strings.Contains(contract, parameter) || !strings.Contains(contract, "cert_arn  = var.cert_arn") {
+					t.Errorf("got %s\n%s", CONTRACT_FILE, contract)
+				}
+			}
+			if test.contract == "tfvars" {
+				var tfvars map[string]interface{}
+				err = json.Unmarshal([]byte(files[filepath.Join("config", testTenantName, config.AwsServicesProject+".tfvars.json")]), &tfvars)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tfvars["tenant_id"] != testTenantId || tfvars["tenant_name"] != testTenantName {
+					t.Errorf("the values of the tenant should be in the config, got %v", tfvars)
+				}
+				if _, ok := tfvars["cert_arn"]; ok {
+					t.Errorf("aws-services does not read the certificate, got %v", tfvars)
+				}
+			}
+		})
+	}
+}
+
+// TestContractOutputsPerTenant checks that the ssm contract of a tenant only holds the parameters
+// of its own projects when the same run generated other tenants before.
+func TestContractOutputsPerTenant(t *testing.T) {
+	client, _ := testClient(t)
+	service := &TfGeneratorService{Report: &ErrorReport{}}
+	first := testConfig(t, t.TempDir(), map[string]string{"project_contract": "ssm"})
+	generateFixtureTenantWith(t, service, first, client)
+
+	targetDir := t.TempDir()
+	second := testConfig(t, targetDir, map[string]string{"project_contract": "ssm", "skip_app": "true"})
+	generateFixtureTenantWith(t, service, second, client)
+	files, err := readFiles(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract := files[filepath.Join("terraform", second.TenantProject, CONTRACT_FILE)]
+	if !strings.Contains(contract, "contract_aws_services") {
+		t.Errorf("expected the parameter of aws-services, got\n%s", contract)
+	}
+	if strings.Contains(contract, "contract_app") {
+		t.Errorf("the app project was not generated for this tenant, got\n%s", contract)
+	}
+}
@@ -0,0 +1,504 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Drift actions. Added and removed are seen from the live DuploCloud state: an added resource
+// exists in DuploCloud but not in the code.
+const (
+	DRIFT_ADDED   = "added"
+	DRIFT_REMOVED = "removed"
+	DRIFT_CHANGED = "changed"
+)
+
+// ResourceDrift is a resource whose code differs from the code generated from the live state.
+type ResourceDrift struct {
+	Project    string           `json:"project"`
+	Address    string           `json:"address"`
+	Action     string           `json:"action"`
+	Attributes []AttributeDrift `json:"attributes,omitempty"`
+}
+
+// AttributeDrift is an attribute or nested block of a changed resource. Path is like
+// "other_docker_config" or "lifecycle.ignore_changes". Repeated nested blocks are compared as a
+// set, so a changed one is reported as removed and added.
+type AttributeDrift struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Code   string `json:"code,omitempty"`
+	Live   string `json:"live,omitempty"`
+}
+
+// hclBlock is the body of a block reduced to what terraform reads. Attribute and block order,
+// whitespace and comments are dropped.
+type hclBlock struct {
+	attributes map[string]hclValue
+	blocks     map[string][]*hclBlock
+}
+
+type hclValue struct {
+	// key compares two values, text shows one.
+	key  string
+	text string
+}
+
+// DetectDrift compares the resources and data sources declared in the terraform files of a
+// committed project folder with the ones generated from the live state. The values of the
+// committed tfvars.json file are compared too. A changed value is reported under each resource or
+// module reading the variable, or under the variable when none reads it.
+func DetectDrift(project, codeDir, liveDir, codeVarsFile, liveVarsFile string) ([]ResourceDrift, error) {
+	code, err := readProjectBlocks(codeDir)
+	if err != nil {
+		return nil, err
+	}
+	live, err := readProjectBlocks(liveDir)
+	if err != nil {
+		return nil, err
+	}
+	codeVars, err := readVarValues(codeVarsFile)
+	if err != nil {
+		return nil, err
+	}
+	liveVars, err := readVarValues(liveVarsFile)
+	if err != nil {
+		return nil, err
+	}
+	varDrifts := map[string][]AttributeDrift{}
+	for _, varDrift := range diffVarValues(codeVars, liveVars) {
+		name := strings.TrimPrefix(varDrift.Path, "var.")
+		readers := code.readers[name]
+		for _, reader := range live.readers[name] {
+			readers = appendMissing(readers, reader)
+		}
+		if len(readers) == 0 {
+			readers = []string{varDrift.Path}
+		}
+		for _, reader := range readers {
+			varDrifts[reader] = append(varDrifts[reader], varDrift)
+		}
+	}
+
+	addresses := []string{}
+	for address := range code.resources {
+		addresses = append(addresses, address)
+	}
+	for address := range live.resources {
+		if _, ok := code.resources[address]; !ok {
+			addresses = append(addresses, address)
+		}
+	}
+	for address := range varDrifts {
+		_, inCode := code.resources[address]
+		_, inLive := live.resources[address]
+		if !inCode && !inLive {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	drifts := []ResourceDrift{}
+	for _, address := range addresses {
+		codeBlock, inCode := code.resources[address]
+		liveBlock, inLive := live.resources[address]
+		switch {
+		case !inCode && !inLive:
+			drifts = append(drifts, ResourceDrift{Project: project, Address: address, Action: DRIFT_CHANGED, Attributes: varDrifts[address]})
+		case !inCode:
+			drifts = append(drifts, ResourceDrift{Project: project, Address: address, Action: DRIFT_ADDED})
+		case !inLive:
+			drifts = append(drifts, ResourceDrift{Project: project, Address: address, Action: DRIFT_REMOVED})
+		default:
+			attributes := append(diffBlocks("", codeBlock, liveBlock), varDrifts[address]...)
+			sortAttributeDrifts(attributes)
+			if len(attributes) > 0 {
+				drifts = append(drifts, ResourceDrift{Project: project, Address: address, Action: DRIFT_CHANGED, Attributes: attributes})
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// projectBlocks are the resource blocks of a project keyed by address.
+type projectBlocks struct {
+	resources map[string]*hclBlock
+	// readers are the addresses of the resources, data sources and modules of the root module
+	// reading each variable directly or through locals.
+	readers map[string][]string
+}
+
+// blockReferences are the variables and locals read by a block.
+type blockReferences struct {
+	vars   map[string]bool
+	locals map[string]bool
+}
+
+func newBlockReferences() *blockReferences {
+	return &blockReferences{vars: map[string]bool{}, locals: map[string]bool{}}
+}
+
+// readProjectBlocks parses the terraform files of a folder and of its local modules. A missing
+// folder has no resources.
+func readProjectBlocks(dir string) (*projectBlocks, error) {
+	project := &projectBlocks{resources: map[string]*hclBlock{}, readers: map[string][]string{}}
+	references := map[string]*blockReferences{}
+	locals := map[string]*blockReferences{}
+	err := readModuleResourceBlocks(dir, "", project.resources, references, locals)
+	if err != nil {
+		return nil, err
+	}
+	addresses := sortedKeys(references)
+	for _, address := range addresses {
+		vars := map[string]bool{}
+		for name := range references[address].vars {
+			vars[name] = true
+		}
+		for name := range references[address].locals {
+			localVars(name, locals, vars, map[string]bool{})
+		}
+		for _, name := range sortedKeys(vars) {
+			project.readers[name] = append(project.readers[name], address)
+		}
+	}
+	return project, nil
+}
+
+// localVars adds the variables read by a local, including through other locals.
+func localVars(name string, locals map[string]*blockReferences, vars, seen map[string]bool) {
+	local, ok := locals[name]
+	if !ok || seen[name] {
+		return
+	}
+	seen[name] = true
+	for varName := range local.vars {
+		vars[varName] = true
+	}
+	for localName := range local.locals {
+		localVars(localName, locals, vars, seen)
+	}
+}
+
+// readModuleResourceBlocks adds the resource blocks of a folder. The references of the blocks and
+// locals are only kept for the root module, whose variables are set by the tfvars file.
+func readModuleResourceBlocks(dir, prefix string, resources map[string]*hclBlock, references, locals map[string]*blockReferences) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, file, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return diags
+		}
+		for _, block := range hclFile.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == "locals" && len(prefix) == 0 {
+				for name, attribute := range block.Body.Attributes {
+					locals[name] = newBlockReferences()
+					addExprReferences(attribute.Expr, locals[name])
+				}
+				continue
+			}
+			if block.Type == "module" && len(block.Labels) == 1 {
+				if len(prefix) == 0 {
+					references["module."+block.Labels[0]] = newBlockReferences()
+					addBodyReferences(block.Body, references["module."+block.Labels[0]])
+				}
+				if moduleDir, ok := common.LocalModuleDir(dir, block); ok {
+					err = readModuleResourceBlocks(moduleDir, prefix+"module."+block.Labels[0]+".", resources, nil, nil)
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if len(block.Labels) != 2 {
+				continue
+			}
+			address := block.Labels[0] + "." + block.Labels[1]
+			switch block.Type {
+			case "resource":
+			case "data":
+				address = "data." + address
+			default:
+				continue
+			}
+			resources[prefix+address] = newHclBlock(block.Body, src)
+			if len(prefix) == 0 {
+				references[address] = newBlockReferences()
+				addBodyReferences(block.Body, references[address])
+			}
+		}
+	}
+	return nil
+}
+
+// addBodyReferences adds the variables and locals read by the attributes of a body and of its
+// nested blocks.
+func addBodyReferences(body *hclsyntax.Body, references *blockReferences) {
+	for _, attribute := range body.Attributes {
+		addExprReferences(attribute.Expr, references)
+	}
+	for _, nested := range body.Blocks {
+		addBodyReferences(nested.Body, references)
+	}
+}
+
+func addExprReferences(expr hclsyntax.Expression, references *blockReferences) {
+	for _, traversal := range expr.Variables() {
+		if len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		switch traversal.RootName() {
+		case "var":
+			references.vars[attr.Name] = true
+		case "local":
+			references.locals[attr.Name] = true
+		}
+	}
+}
+
+// readVarValues reads the values of a tfvars.json file as compact json keyed by variable. Object
+// keys are sorted. A missing file has no values.
+func readVarValues(path string) (map[string]string, error) {
+	values := map[string]string{}
+	if len(path) == 0 {
+		return values, nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{}
+	err = json.Unmarshal(content, &vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	for name, value := range vars {
+		text, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = string(text)
+	}
+	return values, nil
+}
+
+// diffVarValues lists the variables whose values differ. Their paths are like "var.replicas".
+func diffVarValues(code, live map[string]string) []AttributeDrift {
+	drifts := []AttributeDrift{}
+	for name, codeValue := range code {
+		liveValue, ok := live[name]
+		switch {
+		case !ok:
+			drifts = append(drifts, AttributeDrift{Path: "var." + name, Action: DRIFT_REMOVED, Code: codeValue})
+		case codeValue != liveValue:
+			drifts = append(drifts, AttributeDrift{Path: "var." + name, Action: DRIFT_CHANGED, Code: codeValue, Live: liveValue})
+		}
+	}
+	for name, liveValue := range live {
+		if _, ok := code[name]; !ok {
+			drifts = append(drifts, AttributeDrift{Path: "var." + name, Action: DRIFT_ADDED, Live: liveValue})
+		}
+	}
+	sortAttributeDrifts(drifts)
+	return drifts
+}
+
+func newHclBlock(body *hclsyntax.Body, src []byte) *hclBlock {
+	block := &hclBlock{attributes: map[string]hclValue{}, blocks: map[string][]*hclBlock{}}
+	for name, attribute := range body.Attributes {
+		block.attributes[name] = hclValue{
+			key:  renderExpr(attribute.Expr, src, ""),
+			text: renderExpr(attribute.Expr, src, " "),
+		}
+	}
+	for _, nested := range body.Blocks {
+		name := strings.Join(append([]string{nested.Type}, nested.Labels...), ".")
+		block.blocks[name] = append(block.blocks[name], newHclBlock(nested.Body, src))
+	}
+	return block
+}
+
+// renderExpr writes an expression without its formatting. Object keys are sorted. Tokens are
+// joined with sep so that an empty sep gives a key which ignores spacing.
+func renderExpr(expr hclsyntax.Expression, src []byte, sep string) string {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		items := []string{}
+		for _, item := range e.Items {
+			items = append(items, renderExpr(item.KeyExpr, src, sep)+sep+"="+sep+renderExpr(item.ValueExpr, src, sep))
+		}
+		sort.Strings(items)
+		if len(items) == 0 {
+			return "{}"
+		}
+		return "{" + sep + strings.Join(items, ","+sep) + sep + "}"
+	case *hclsyntax.TupleConsExpr:
+		items := []string{}
+		for _, item := range e.Exprs {
+			items = append(items, renderExpr(item, src, sep))
+		}
+		return "[" + strings.Join(items, ","+sep) + "]"
+	case *hclsyntax.FunctionCallExpr:
+		args := []string{}
+		for _, arg := range e.Args {
+			args = append(args, renderExpr(arg, src, sep))
+		}
+		if e.ExpandFinal && len(args) > 0 {
+			args[len(args)-1] += "..."
+		}
+		return e.Name + "(" + strings.Join(args, ","+sep) + ")"
+	}
+	content := expr.Range().SliceBytes(src)
+	tokens, _ := hclsyntax.LexExpression(content, "", expr.Range().Start)
+	text := strings.Builder{}
+	end := -1
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment, hclsyntax.TokenEOF:
+			continue
+		}
+		if end >= 0 && token.Range.Start.Byte > end {
+			text.WriteString(sep)
+		}
+		text.Write(token.Bytes)
+		end = token.Range.End.Byte
+	}
+	return text.String()
+}
+
+// render writes a block for a report with its attributes and blocks sorted.
+func (b *hclBlock) render(sep string) string {
+	items := []string{}
+	for name, value := range b.attributes {
+		if len(sep) == 0 {
+			items = append(items, name+"="+value.key)
+		} else {
+			items = append(items, name+" = "+value.text)
+		}
+	}
+	for name, blocks := range b.blocks {
+		for _, block := range blocks {
+			items = append(items, name+sep+block.render(sep))
+		}
+	}
+	sort.Strings(items)
+	if len(items) == 0 {
+		return "{}"
+	}
+	return "{" + sep + strings.Join(items, ","+sep) + sep + "}"
+}
+
+// diffBlocks lists the attributes and nested blocks which differ between two blocks.
+func diffBlocks(prefix string, code, live *hclBlock) []AttributeDrift {
+	drifts := []AttributeDrift{}
+	for name, codeValue := range code.attributes {
+		liveValue, ok := live.attributes[name]
+		switch {
+		case !ok:
+			drifts = append(drifts, AttributeDrift{Path: prefix + name, Action: DRIFT_REMOVED, Code: codeValue.text})
+		case codeValue.key != liveValue.key:
+			drifts = append(drifts, AttributeDrift{Path: prefix + name, Action: DRIFT_CHANGED, Code: codeValue.text, Live: liveValue.text})
+		}
+	}
+	for name, liveValue := range live.attributes {
+		if _, ok := code.attributes[name]; !ok {
+			drifts = append(drifts, AttributeDrift{Path: prefix + name, Action: DRIFT_ADDED, Live: liveValue.text})
+		}
+	}
+
+	names := map[string]bool{}
+	for name := range code.blocks {
+		names[name] = true
+	}
+	for name := range live.blocks {
+		names[name] = true
+	}
+	for name := range names {
+		codeBlocks, liveBlocks := code.blocks[name], live.blocks[name]
+		if len(codeBlocks) == 1 && len(liveBlocks) == 1 {
+			drifts = append(drifts, diffBlocks(prefix+name+".", codeBlocks[0], liveBlocks[0])...)
+			continue
+		}
+		drifts = append(drifts, diffBlockSets(prefix+name, codeBlocks, liveBlocks)...)
+	}
+	sortAttributeDrifts(drifts)
+	return drifts
+}
+
+// sortAttributeDrifts sorts drifts by path. A removed block comes before the block replacing it.
+func sortAttributeDrifts(drifts []AttributeDrift) {
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Path != drifts[j].Path {
+			return drifts[i].Path < drifts[j].Path
+		}
+		return drifts[i].Action > drifts[j].Action
+	})
+}
+
+// diffBlockSets compares repeated nested blocks regardless of their order.
+func diffBlockSets(path string, code, live []*hclBlock) []AttributeDrift {
+	count := map[string]int{}
+	for _, block := range live {
+		count[block.render("")]++
+	}
+	drifts := []AttributeDrift{}
+	for _, block := range code {
+		key := block.render("")
+		if count[key] > 0 {
+			count[key]--
+			continue
+		}
+		drifts = append(drifts, AttributeDrift{Path: path, Action: DRIFT_REMOVED, Code: block.render(" ")})
+	}
+	for _, block := range live {
+		key := block.render("")
+		if count[key] > 0 {
+			count[key]--
+			drifts = append(drifts, AttributeDrift{Path: path, Action: DRIFT_ADDED, Live: block.render(" ")})
+		}
+	}
+	return drifts
+}
+
+// String describes an attribute drift on a line, like "~ replicas: 2 => 3".
+func (d AttributeDrift) String() string {
+	switch d.Action {
+	case DRIFT_ADDED:
+		return fmt.Sprintf("+ %s: %s", d.Path, d.Live)
+	case DRIFT_REMOVED:
+		return fmt.Sprintf("- %s: %s", d.Path, d.Code)
+	}
+	return fmt.Sprintf("~ %s: %s => %s", d.Path, d.Code, d.Live)
+}
+
+// String describes a resource drift on a line. Its attributes are listed separately.
+func (d ResourceDrift) String() string {
+	switch d.Action {
+	case DRIFT_ADDED:
+		return fmt.Sprintf("+ %s %s (in DuploCloud, missing from the code)", d.Project, d.Address)
+	case DRIFT_REMOVED:
+		return fmt.Sprintf("- %s %s (in the code, missing from DuploCloud)", d.Project, d.Address)
+	}
+	return fmt.Sprintf("~ %s %s", d.Project, d.Address)
+}
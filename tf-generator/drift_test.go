@@ -0,0 +1,220 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+const driftCode = `
+resource "duplocloud_duplo_service" "orders_api" {
+  tenant_id = local.tenant_id
+  name      = "orders-api"
+  replicas  = 2
+  other_docker_config = jsonencode({
+    "Env": [{ "Name": "DB_HOST", "Value": "orders-db.internal" }],
+    "Resources": { "limits": { "memory": "512Mi" } }
+  })
+}
+
+resource "duplocloud_k8_ingress" "orders" {
+  tenant_id = local.tenant_id
+  name      = "orders"
+  labels    = { app = "orders", team = "payments" }
+  lbconfig {
+    dns_prefix = "orders-${local.tenant_name}"
+    http_port  = 80
+  }
+  rule {
+    path = "/"
+    port = 8080
+  }
+  rule {
+    path = "/admin"
+    port = 8081
+  }
+}
+
+resource "duplocloud_s3_bucket" "logs" {
+  tenant_id = local.tenant_id
+  name      = "logs"
+}
+`
+
+// driftLive is driftCode reformatted and reordered, with the replicas, a label, the lbconfig port
+// and a rule changed in DuploCloud. The logs bucket was deleted and a queue created.
+const driftLive = `
+resource "duplocloud_k8_ingress" "orders" {
+  labels = {
+    team = "data"
+    app  = "orders"
+  }
+  name      = "orders"
+  tenant_id = local.tenant_id
+  rule {
+    port = 8081
+    path = "/admin"
+  }
+  rule {
+    path = "/"
+    port = 9090
+  }
+  lbconfig {
+    http_port  = 8080
+    dns_prefix = "orders-${local.tenant_name}"
+  }
+}
+
+resource "duplocloud_duplo_service" "orders_api" {
+  name = "orders-api"
+  other_docker_config = jsonencode({
+    "Resources" = { "limits" = { "memory" = "512Mi" } },
+    "Env" = [
+      { "Value" = "orders-db.internal", "Name" = "DB_HOST" },
+    ]
+  })
+  replicas  = 3 # Scaled in the UI.
+  tenant_id = local.tenant_id
+}
+
+resource "duplocloud_aws_sqs_queue" "orders_events" {
+  tenant_id = local.tenant_id
+  name      = "orders-events"
+}
+`
+
+func writeDriftProject(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDetectDrift(t *testing.T) {
+	drifts, err := DetectDrift("dev01/app", writeDriftProject(t, driftCode), writeDriftProject(t, driftLive), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ResourceDrift{
+		{Project: "dev01/app", Address: "duplocloud_aws_sqs_queue.orders_events", Action: DRIFT_ADDED},
+		{Project: "dev01/app", Address: "duplocloud_duplo_service.orders_api", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "replicas", Action: DRIFT_CHANGED, Code: "2", Live: "3"},
+		}},
+		{Project: "dev01/app", Address: "duplocloud_k8_ingress.orders", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "labels", Action: DRIFT_CHANGED, Code: `{ app = "orders", team = "payments" }`, Live: `{ app = "orders", team = "data" }`},
+			{Path: "lbconfig.http_port", Action: DRIFT_CHANGED, Code: "80", Live: "8080"},
+			{Path: "rule", Action: DRIFT_REMOVED, Code: `{ path = "/", port = 8080 }`},
+			{Path: "rule", Action: DRIFT_ADDED, Live: `{ path = "/", port = 9090 }`},
+		}},
+		{Project: "dev01/app", Address: "duplocloud_s3_bucket.logs", Action: DRIFT_REMOVED},
+	}
+	got, _ := json.MarshalIndent(drifts, "", "  ")
+	want, _ := json.MarshalIndent(expected, "", "  ")
+	if string(got) != string(want) {
+		t.Errorf("got drift\n%s\nexpected\n%s", got, want)
+	}
+}
+
+// TestDetectDriftIgnoresFormatting compares the golden projects with their terraform fmt output.
+func TestDetectDriftIgnoresFormatting(t *testing.T) {
+	golden := filepath.Join("testdata", "golden")
+	formatted := t.TempDir()
+	files, err := readFiles(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		if filepath.Ext(path) == ".tf" {
+			content = string(hclwrite.Format([]byte(content)))
+		}
+		err = os.MkdirAll(filepath.Join(formatted, filepath.Dir(path)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(formatted, path), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, project := range []string{"terraform/admin-tenant", "terraform/aws-services", "terraform/app", "admin-infra/terraform"} {
+		drifts, err := DetectDrift(project, filepath.Join(golden, project), filepath.Join(formatted, project), "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, drift := range drifts {
+			t.Errorf("unexpected drift %s %v", drift, drift.Attributes)
+		}
+	}
+	drifts, err := DetectDrift("missing", filepath.Join(formatted, "missing"), filepath.Join(golden, "terraform/app"), "", "")
+	if err != nil || len(drifts) == 0 || drifts[0].Action != DRIFT_ADDED {
+		t.Errorf("a project missing from the code should have its resources added, got %v, %v", drifts, err)
+	}
+}
+
+const driftVarsCode = `
+locals {
+  image = "${var.registry}/orders:${var.tag}"
+}
+
+resource "duplocloud_duplo_service" "orders_api" {
+  tenant_id    = local.tenant_id
+  name         = "orders-api"
+  replicas     = var.orders_api_replicas
+  docker_image = local.image
+}
+
+resource "duplocloud_s3_bucket" "logs" {
+  tenant_id = local.tenant_id
+  name      = "logs"
+}
+
+module "queues" {
+  source = "./modules/queues"
+  queues = var.queues
+}
+`
+
+// TestDetectDriftVars checks that a changed value of the tfvars file is reported under the
+// resources reading the variable, directly or through a local.
+func TestDetectDriftVars(t *testing.T) {
+	dir := writeDriftProject(t, driftVarsCode)
+	writeVars := func(content string) string {
+		path := filepath.Join(t.TempDir(), "app.tfvars.json")
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	codeVars := writeVars(`{"orders_api_replicas": 2, "registry": "acme", "tag": "v1", "queues": {"events": {"delay": 0}}, "unused": "a"}`)
+	liveVars := writeVars(`{"queues": {"events": {"delay": 5}}, "registry": "acme", "tag": "v2", "orders_api_replicas": 2, "unread": true}`)
+	drifts, err := DetectDrift("dev01/app", dir, dir, codeVars, liveVars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ResourceDrift{
+		{Project: "dev01/app", Address: "duplocloud_duplo_service.orders_api", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "var.tag", Action: DRIFT_CHANGED, Code: `"v1"`, Live: `"v2"`},
+		}},
+		{Project: "dev01/app", Address: "module.queues", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "var.queues", Action: DRIFT_CHANGED, Code: `{"events":{"delay":0}}`, Live: `{"events":{"delay":5}}`},
+		}},
+		{Project: "dev01/app", Address: "var.unread", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "var.unread", Action: DRIFT_ADDED, Live: "true"},
+		}},
+		{Project: "dev01/app", Address: "var.unused", Action: DRIFT_CHANGED, Attributes: []AttributeDrift{
+			{Path: "var.unused", Action: DRIFT_REMOVED, Code: `"a"`},
+		}},
+	}
+	got, _ := json.MarshalIndent(drifts, "", "  ")
+	want, _ := json.MarshalIndent(expected, "", "  ")
+	if string(got) != string(want) {
+		t.Errorf("got drift\n%s\nexpected\n%s", got, want)
+	}
+}
@@ -1,13 +1,129 @@
 package tfgenerator
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"tenant-terraform-generator/duplosdk"
+	"tenant-terraform-generator/tf-generator/common"
+)
+
 type TFGeneratorError struct {
-	ErrorMessage string
+	ErrorMessage string `json:"error"`
+	Tenant       string `json:"tenant,omitempty"`
+	Project      string `json:"project,omitempty"`
+	Generator    string `json:"generator,omitempty"`
+	Resource     string `json:"resource,omitempty"`
+	URL          string `json:"url,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	Attempts     int    `json:"attempts,omitempty"`
 }
 
 func (e *TFGeneratorError) Error() string {
-	return e.ErrorMessage
+	location := []string{}
+	for _, part := range []string{e.Tenant, e.Project, e.Generator, e.Resource} {
+		if len(part) > 0 {
+			location = append(location, part)
+		}
+	}
+	message := e.ErrorMessage
+	if len(e.URL) > 0 {
+		message = fmt.Sprintf("%s (%d %s)", message, e.Status, e.URL)
+	}
+	if len(location) == 0 {
+		return message
+	}
+	return strings.Join(location, "/") + ": " + message
 }
 
 func ThrowError(error string) error {
 	return &TFGeneratorError{ErrorMessage: error}
 }
+
+// NewTFGeneratorError records where an error happened. The resource and the Duplo API call
+// are taken from the error when available.
+func NewTFGeneratorError(config *common.Config, project, generator string, err error) *TFGeneratorError {
+	generatorErr := &TFGeneratorError{
+		ErrorMessage: err.Error(),
+		Tenant:       config.TenantName,
+		Project:      project,
+		Generator:    generator,
+	}
+	var resourceErr *common.ResourceError
+	if errors.As(err, &resourceErr) {
+		generatorErr.Resource = resourceErr.Resource
+		generatorErr.ErrorMessage = resourceErr.Err.Error()
+	}
+	var clientErr duplosdk.ClientError
+	if errors.As(err, &clientErr) {
+		generatorErr.URL = clientErr.URL()
+		generatorErr.Status = clientErr.Status()
+		generatorErr.Attempts = clientErr.Attempts()
+	}
+	return generatorErr
+}
+
+// joinedErrors splits the errors joined by errors.Join so that each one is reported on its own.
+func joinedErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// ErrorReport collects the failures of a run. It is safe for concurrent use.
+type ErrorReport struct {
+	mutex  sync.Mutex
+	Errors []*TFGeneratorError `json:"errors"`
+}
+
+func (r *ErrorReport) Add(err *TFGeneratorError) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Errors = append(r.Errors, err)
+}
+
+func (r *ErrorReport) Count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.Errors)
+}
+
+// Write stores the report as errors.txt and errors.json in the given folder.
+func (r *ErrorReport) Write(dir string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	var text strings.Builder
+	if len(r.Errors) == 0 {
+		text.WriteString("Terraform generation finished without errors.\n")
+	} else {
+		fmt.Fprintf(&text, "Terraform generation finished with %d error(s).\n\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Fprintf(&text, "- %s\n", e.Error())
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, "errors.txt"), []byte(text.String()), 0644)
+	if err != nil {
+		return err
+	}
+	errs := r.Errors
+	if errs == nil {
+		errs = []*TFGeneratorError{}
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{"count": len(errs), "errors": errs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "errors.json"), content, 0644)
+}
@@ -1,57 +1,351 @@
 package tfgenerator
 
 import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	adminInfra "tenant-terraform-generator/tf-generator/admin-infra"
 	"tenant-terraform-generator/tf-generator/app"
 	awsservices "tenant-terraform-generator/tf-generator/aws-services"
+	"tenant-terraform-generator/tf-generator/common"
 	"tenant-terraform-generator/tf-generator/tenant"
 )
 
-var TenantGenerators = []Generator{
-	&tenant.Tenant{},
-	&tenant.TenantSGRule{},
-}
-
-var AWSServicesGenerators = []Generator{
-	&awsservices.AwsServicesMain{},
-	&awsservices.Hosts{},
-	&awsservices.ASG{},
-	&awsservices.Rds{},
-	&awsservices.Redis{},
-	&awsservices.Kafka{},
-	&awsservices.S3Bucket{},
-	&awsservices.SQS{},
-	&awsservices.SNS{},
-	&awsservices.MWAA{},
-	&awsservices.ES{},
-	&awsservices.SsmParams{},
-	&awsservices.LoadBalancer{},
-	&awsservices.ApiGatewayIntegration{},
-	&awsservices.CFD{},
-	&awsservices.LambdaFunction{},
-	&awsservices.DynamoDB{},
-	&awsservices.BYOH{},
-	&awsservices.EMR{},
-	&awsservices.CloudwatchMetrics{},
-	&awsservices.ECR{},
-	&awsservices.BatchSP{},
-	&awsservices.BatchCE{},
-	&awsservices.BatchQ{},
-	&awsservices.BatchJD{},
-	&awsservices.TimestreamDB{},
-}
-
-var AppGenerators = []Generator{
-	&app.AppMain{},
-	&app.Services{},
-	&app.ECS{},
-	&app.K8sConfig{},
-	&app.K8sSecret{},
-	&app.K8sIngress{},
-	&app.K8sSecretProviderClass{},
-	&app.K8sCronJob{},
-	&app.K8sJob{},
-}
-
-var AdminInfraGenerator = []Generator{
-	// &adminInfra.Infra{},
+// Projects generators are registered for.
+const (
+	PROJECT_ADMIN_TENANT = "admin-tenant"
+	PROJECT_AWS_SERVICES = "aws-services"
+	PROJECT_APP          = "app"
+	PROJECT_ADMIN_INFRA  = "admin-infra"
+)
+
+var Projects = []string{PROJECT_ADMIN_TENANT, PROJECT_AWS_SERVICES, PROJECT_APP, PROJECT_ADMIN_INFRA}
+
+// GeneratorInfo describes a registered generator.
+type GeneratorInfo struct {
+	// Name identifies the generator in the config and in the error report.
+	Name    string
+	Project string
+	// ResourceTypes are the terraform resource types written by the generator.
+	ResourceTypes []string
+	// DependsOn lists generators of the same project which must run first.
+	DependsOn        []string
+	EnabledByDefault bool
+	// S3Backend generators only run when the backend of the projects is generated. See common.Backend.
+	S3Backend bool
+	Generator Generator
+}
+
+// GeneratorRegistry holds the generators of every project. Results of a project are merged
+// in registration order.
+type GeneratorRegistry struct {
+	generators []*GeneratorInfo
+}
+
+func NewGeneratorRegistry(generators ...GeneratorInfo) *GeneratorRegistry {
+	registry := &GeneratorRegistry{}
+	for _, g := range generators {
+		registry.Register(g)
+	}
+	return registry
+}
+
+// Register adds a generator. Conflicts with already registered generators are reported by Validate.
+func (r *GeneratorRegistry) Register(info GeneratorInfo) {
+	r.generators = append(r.generators, &info)
+}
+
+func (r *GeneratorRegistry) All() []*GeneratorInfo {
+	return r.generators
+}
+
+func (r *GeneratorRegistry) Get(name string) (*GeneratorInfo, bool) {
+	for _, g := range r.generators {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+// Validate reports duplicate names or generators, unknown projects and dependencies, and dependency cycles.
+func (r *GeneratorRegistry) Validate() error {
+	problems := []string{}
+	names := map[string]*GeneratorInfo{}
+	types := map[reflect.Type]string{}
+	for _, g := range r.generators {
+		if len(g.Name) == 0 {
+			problems = append(problems, fmt.Sprintf("generator %s has no name", generatorType(g.Generator)))
+			continue
+		}
+		if _, ok := names[g.Name]; ok {
+			problems = append(problems, fmt.Sprintf("generator %s is registered more than once", g.Name))
+			continue
+		}
+		names[g.Name] = g
+		if !common.Contains(Projects, g.Project) {
+			problems = append(problems, fmt.Sprintf("generator %s has unknown project %q", g.Name, g.Project))
+		}
+		if g.Generator == nil {
+			problems = append(problems, fmt.Sprintf("generator %s has no implementation", g.Name))
+			continue
+		}
+		t := reflect.TypeOf(g.Generator)
+		if other, ok := types[t]; ok {
+			problems = append(problems, fmt.Sprintf("generators %s and %s are both %s", other, g.Name, generatorType(g.Generator)))
+		}
+		types[t] = g.Name
+	}
+	for _, g := range r.generators {
+		for _, dependency := range g.DependsOn {
+			other, ok := names[dependency]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("generator %s depends on unknown generator %s", g.Name, dependency))
+			} else if other.Project != g.Project {
+				problems = append(problems, fmt.Sprintf("generator %s depends on %s of project %s", g.Name, dependency, other.Project))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		for _, g := range r.generators {
+			if cycle := r.findCycle(g, []string{}); cycle != nil {
+				problems = append(problems, fmt.Sprintf("generators have a dependency cycle %s", strings.Join(cycle, " -> ")))
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid generator registry:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func (r *GeneratorRegistry) findCycle(g *GeneratorInfo, path []string) []string {
+	for i, name := range path {
+		if name == g.Name {
+			return append(path[i:], g.Name)
+		}
+	}
+	path = append(path, g.Name)
+	for _, dependency := range g.DependsOn {
+		other, _ := r.Get(dependency)
+		if cycle := r.findCycle(other, path); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// CheckNames reports the names which are not registered.
+func (r *GeneratorRegistry) CheckNames(names []string) error {
+	unknown := []string{}
+	for _, name := range names {
+		if _, ok := r.Get(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown generators %s, run list-generators to see the registered ones", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Enabled reports whether the config turns the generator on, regardless of its dependencies.
+func (g *GeneratorInfo) Enabled(config *common.Config) bool {
+	if g.S3Backend && !config.S3Backend {
+		return false
+	}
+	if common.Contains(config.DisableGenerators, g.Name) {
+		return false
+	}
+	return g.EnabledByDefault || common.Contains(config.EnableGenerators, g.Name)
+}
+
+// ForProject returns the generators to run for a project. Dependencies of enabled generators are
+// turned on unless they are explicitly disabled. Generators come after their dependencies.
+func (r *GeneratorRegistry) ForProject(config *common.Config, project string) ([]*GeneratorInfo, error) {
+	selected := map[string]bool{}
+	var enable func(g *GeneratorInfo, dependent string) error
+	enable = func(g *GeneratorInfo, dependent string) error {
+		if selected[g.Name] {
+			return nil
+		}
+		if common.Contains(config.DisableGenerators, g.Name) {
+			return fmt.Errorf("generator %s is disabled but %s depends on it", g.Name, dependent)
+		}
+		if len(dependent) > 0 && !g.Enabled(config) {
+			log.Printf("[TRACE] Generator %s is enabled as %s depends on it.", g.Name, dependent)
+		}
+		selected[g.Name] = true
+		for _, dependency := range g.DependsOn {
+			other, ok := r.Get(dependency)
+			if !ok {
+				return fmt.Errorf("generator %s depends on unknown generator %s", g.Name, dependency)
+			}
+			err := enable(other, g.Name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, g := range r.generators {
+		if g.Project == project && g.Enabled(config) {
+			err := enable(g, "")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	generators := []*GeneratorInfo{}
+	added := map[string]bool{}
+	var add func(g *GeneratorInfo)
+	add = func(g *GeneratorInfo) {
+		if added[g.Name] {
+			return
+		}
+		added[g.Name] = true
+		for _, dependency := range g.DependsOn {
+			other, _ := r.Get(dependency)
+			add(other)
+		}
+		generators = append(generators, g)
+	}
+	for _, g := range r.generators {
+		if selected[g.Name] {
+			add(g)
+		}
+	}
+	return generators, nil
+}
+
+func generatorType(g Generator) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", g), "*")
+}
+
+// Registry holds the generators run by the TfGeneratorService.
+var Registry = NewGeneratorRegistry(
+	// admin-tenant
+	GeneratorInfo{
+		Name:             "tenant",
+		Project:          PROJECT_ADMIN_TENANT,
+		ResourceTypes:    []string{"duplocloud_tenant", "duplocloud_tenant_config", "duplocloud_tenant_tag"},
+		EnabledByDefault: true,
+		Generator:        &tenant.Tenant{},
+	},
+	GeneratorInfo{
+		Name:             "tenant-sg-rule",
+		Project:          PROJECT_ADMIN_TENANT,
+		ResourceTypes:    []string{"duplocloud_tenant_network_security_rule"},
+		DependsOn:        []string{"tenant"},
+		EnabledByDefault: true,
+		Generator:        &tenant.TenantSGRule{},
+	},
+	GeneratorInfo{
+		Name:             "tenant-backend",
+		Project:          PROJECT_ADMIN_TENANT,
+		EnabledByDefault: true,
+		S3Backend:        true,
+		Generator:        &tenant.TenantBackend{},
+	},
+
+	// aws-services
+	GeneratorInfo{
+		Name:             "aws-services-main",
+		Project:          PROJECT_AWS_SERVICES,
+		EnabledByDefault: true,
+		Generator:        &awsservices.AwsServicesMain{},
+	},
+	awsServicesGenerator("kms", &awsservices.KMS{}),
+	awsServicesGenerator("hosts", &awsservices.Hosts{}, "duplocloud_aws_host"),
+	awsServicesGenerator("asg", &awsservices.ASG{}, "duplocloud_asg_profile"),
+	awsServicesGenerator("rds", &awsservices.Rds{}, "duplocloud_rds_instance", "duplocloud_rds_read_replica", "random_password"),
+	awsServicesGenerator("redis", &awsservices.Redis{}, "duplocloud_ecache_instance"),
+	awsServicesGenerator("kafka", &awsservices.Kafka{}, "duplocloud_aws_kafka_cluster"),
+	awsServicesGenerator("s3", &awsservices.S3Bucket{}, "duplocloud_s3_bucket"),
+	awsServicesGenerator("sqs", &awsservices.SQS{}, "duplocloud_aws_sqs_queue"),
+	awsServicesGenerator("sns", &awsservices.SNS{}, "duplocloud_aws_sns_topic"),
+	awsServicesGenerator("mwaa", &awsservices.MWAA{}, "duplocloud_aws_mwaa_environment"),
+	awsServicesGenerator("elasticsearch", &awsservices.ES{}, "duplocloud_aws_elasticsearch"),
+	awsServicesGenerator("ssm-params", &awsservices.SsmParams{}, "duplocloud_aws_ssm_parameter"),
+	awsServicesGenerator("load-balancer", &awsservices.LoadBalancer{}, "duplocloud_aws_load_balancer", "duplocloud_aws_load_balancer_listener", "duplocloud_aws_target_group_attributes", "duplocloud_aws_lb_listener_rule"),
+	awsServicesGenerator("api-gateway-integration", &awsservices.ApiGatewayIntegration{}, "duplocloud_aws_api_gateway_integration"),
+	awsServicesGenerator("cloudfront", &awsservices.CFD{}, "duplocloud_aws_cloudfront_distribution"),
+	awsServicesGenerator("lambda-function", &awsservices.LambdaFunction{}, "duplocloud_aws_lambda_function", "duplocloud_aws_lambda_permission"),
+	awsServicesGenerator("dynamodb", &awsservices.DynamoDB{}, "duplocloud_aws_dynamodb_table_v2"),
+	awsServicesGenerator("byoh", &awsservices.BYOH{}, "duplocloud_byoh"),
+	awsServicesGenerator("emr", &awsservices.EMR{}, "duplocloud_emr_cluster"),
+	awsServicesGenerator("cloudwatch-metrics", &awsservices.CloudwatchMetrics{}, "duplocloud_aws_cloudwatch_metric_alarm"),
+	awsServicesGenerator("ecr", &awsservices.ECR{}, "duplocloud_aws_ecr_repository"),
+	awsServicesGenerator("batch-scheduling-policy", &awsservices.BatchSP{}, "duplocloud_aws_batch_scheduling_policy"),
+	awsServicesGenerator("batch-compute-environment", &awsservices.BatchCE{}, "duplocloud_aws_batch_compute_environment"),
+	awsServicesGenerator("batch-job-queue", &awsservices.BatchQ{}, "duplocloud_aws_batch_job_queue"),
+	awsServicesGenerator("batch-job-definition", &awsservices.BatchJD{}, "duplocloud_aws_batch_job_definition"),
+	awsServicesGenerator("timestream-db", &awsservices.TimestreamDB{}, "duplocloud_aws_timestreamwrite_database", "duplocloud_aws_timestreamwrite_table"),
+	awsServicesGenerator("cloudwatch-event-rule", &awsservices.CloudwatchEventRule{}, "duplocloud_aws_cloudwatch_event_rule", "duplocloud_aws_cloudwatch_event_target"),
+	GeneratorInfo{
+		Name:             "aws-services-backend",
+		Project:          PROJECT_AWS_SERVICES,
+		EnabledByDefault: true,
+		S3Backend:        true,
+		Generator:        &awsservices.AwsServicesBackend{},
+	},
+
+	// app
+	GeneratorInfo{
+		Name:             "app-main",
+		Project:          PROJECT_APP,
+		EnabledByDefault: true,
+		Generator:        &app.AppMain{},
+	},
+	appGenerator("services", &app.Services{}, "duplocloud_duplo_service", "duplocloud_duplo_service_lbconfigs", "duplocloud_duplo_service_params"),
+	appGenerator("ecs", &app.ECS{}, "duplocloud_ecs_service", "duplocloud_ecs_task_definition"),
+	appGenerator("k8s-config", &app.K8sConfig{}, "duplocloud_k8_config_map"),
+	appGenerator("k8s-secret", &app.K8sSecret{}, "duplocloud_k8_secret"),
+	appGenerator("k8s-ingress", &app.K8sIngress{}, "duplocloud_k8_ingress"),
+	appGenerator("k8s-secret-provider-class", &app.K8sSecretProviderClass{}, "duplocloud_k8_secret_provider_class"),
+	appGenerator("k8s-cron-job", &app.K8sCronJob{}, "duplocloud_k8s_cron_job"),
+	appGenerator("k8s-job", &app.K8sJob{}, "duplocloud_k8s_job"),
+	GeneratorInfo{
+		Name:             "app-backend",
+		Project:          PROJECT_APP,
+		EnabledByDefault: true,
+		S3Backend:        true,
+		Generator:        &app.AppBackend{},
+	},
+
+	// admin-infra
+	GeneratorInfo{
+		Name:    "infra",
+		Project: PROJECT_ADMIN_INFRA,
+		ResourceTypes: []string{"duplocloud_infrastructure", "duplocloud_infrastructure_subnet", "duplocloud_plan_configs",
+			"duplocloud_plan_waf", "duplocloud_plan_certificate"},
+		EnabledByDefault: true,
+		S3Backend:        true,
+		Generator:        &adminInfra.Infra{},
+	},
+)
+
+func awsServicesGenerator(name string, g Generator, resourceTypes ...string) GeneratorInfo {
+	return GeneratorInfo{
+		Name:             name,
+		Project:          PROJECT_AWS_SERVICES,
+		ResourceTypes:    resourceTypes,
+		DependsOn:        []string{"aws-services-main"},
+		EnabledByDefault: true,
+		Generator:        g,
+	}
+}
+
+func appGenerator(name string, g Generator, resourceTypes ...string) GeneratorInfo {
+	return GeneratorInfo{
+		Name:             name,
+		Project:          PROJECT_APP,
+		ResourceTypes:    resourceTypes,
+		DependsOn:        []string{"app-main"},
+		EnabledByDefault: true,
+		Generator:        g,
+	}
 }
@@ -0,0 +1,113 @@
+package tfgenerator
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// unregisteredGenerators are run by other generators instead of the registry.
+var unregisteredGenerators = map[string]bool{
+	"admininfra.InfraSubnet":     true,
+	"admininfra.PlanConfig":      true,
+	"admininfra.PlanWaf":         true,
+	"admininfra.PlanCertificate": true,
+	"admininfra.PlanImage":       true,
+	"admininfra.PlanSetting":     true,
+}
+
+func TestRegistryIsValid(t *testing.T) {
+	err := Registry.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRegistryHasEveryGenerator scans the generator packages for types implementing Generator, so
+// that a new generator can not be added without registering it.
+func TestRegistryHasEveryGenerator(t *testing.T) {
+	registered := map[string]bool{}
+	for _, g := range Registry.All() {
+		registered[generatorType(g.Generator)] = true
+	}
+	generators, err := findGenerators(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generators) == 0 {
+		t.Fatal("no generators found in the source")
+	}
+	for _, generator := range generators {
+		if !registered[generator] && !unregisteredGenerators[generator] {
+			t.Errorf("generator %s is not registered in Registry", generator)
+		}
+	}
+}
+
+// findGenerators returns the package qualified types with a Generate method matching the
+// Generator interface, as printed by generatorType.
+func findGenerators(root string) ([]string, error) {
+	generators := []string{}
+	fset := token.NewFileSet()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".go" || filepath.Dir(path) == root {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "Generate" || !isGeneratorSignature(fn.Type) {
+				continue
+			}
+			receiver := fn.Recv.List[0].Type
+			if star, ok := receiver.(*ast.StarExpr); ok {
+				receiver = star.X
+			}
+			if ident, ok := receiver.(*ast.Ident); ok {
+				generators = append(generators, file.Name.Name+"."+ident.Name)
+			}
+		}
+		return nil
+	})
+	sort.Strings(generators)
+	return generators, err
+}
+
+// isGeneratorSignature matches Generate(*common.Config, *duplosdk.Client) (*common.TFContext, error).
+func isGeneratorSignature(fn *ast.FuncType) bool {
+	params := []string{}
+	for _, field := range fn.Params.List {
+		for range max(len(field.Names), 1) {
+			params = append(params, typeString(field.Type))
+		}
+	}
+	results := []string{}
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			for range max(len(field.Names), 1) {
+				results = append(results, typeString(field.Type))
+			}
+		}
+	}
+	return len(params) == 2 && params[0] == "*common.Config" && params[1] == "*duplosdk.Client" &&
+		len(results) == 2 && results[0] == "*common.TFContext" && results[1] == "error"
+}
+
+func typeString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + typeString(e.X)
+	case *ast.SelectorExpr:
+		return typeString(e.X) + "." + e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
@@ -6,13 +6,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"tenant-terraform-generator/duplosdk"
-	adminInfra "tenant-terraform-generator/tf-generator/admin-infra"
-	"tenant-terraform-generator/tf-generator/app"
-	awsservices "tenant-terraform-generator/tf-generator/aws-services"
 	"tenant-terraform-generator/tf-generator/common"
-	"tenant-terraform-generator/tf-generator/tenant"
+
+	"github.com/ghodss/yaml"
+	tfjson "github.com/hashicorp/terraform-json"
 )
 
 type IGeneratorService interface {
@@ -22,38 +23,42 @@ type IGeneratorService interface {
 }
 
 type TfGeneratorService struct {
+	// Report collects the failures of the run.
+	Report *ErrorReport
+	// contractOutputs are the outputs of the tenant project read by each project through the contract.
+	contractOutputs map[string][]string
 }
 
 func (tfg *TfGeneratorService) PreProcess(config *common.Config, client *duplosdk.Client) error {
 	log.Println("[TRACE] <====== Initialize target directory with customer name and tenant id. =====>")
-	config.TFCodePath = filepath.Join("target", config.CustomerName, config.TenantName, "terraform")
-	config.ConfigVars = filepath.Join("target", config.CustomerName, config.TenantName, "config", config.TenantName)
+	config.TFCodePath = filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, "terraform")
+	config.ConfigVars = filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, "config", config.TenantName)
 	tenantProject := filepath.Join(config.TFCodePath, config.TenantProject)
-	err := os.RemoveAll(filepath.Join("target", config.CustomerName, config.TenantName))
+	err := os.RemoveAll(filepath.Join(config.TargetDir, config.CustomerName, config.TenantName))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	err = os.RemoveAll(tenantProject)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	err = os.MkdirAll(tenantProject, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	config.AdminTenantDir = tenantProject
 
 	err = os.RemoveAll(config.ConfigVars)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Remove Completed \n Creating ", config.ConfigVars)
 
 	err = os.MkdirAll(config.ConfigVars, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	fmt.Println("Created")
 	fmt.Println("Creating env folder under config")
@@ -61,111 +66,166 @@ func (tfg *TfGeneratorService) PreProcess(config *common.Config, client *duplosd
 	awsServicesProject := filepath.Join(config.TFCodePath, config.AwsServicesProject)
 	err = os.RemoveAll(awsServicesProject)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = os.MkdirAll(awsServicesProject, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	config.AwsServicesDir = awsServicesProject
 
 	appProject := filepath.Join(config.TFCodePath, config.AppProject)
 	err = os.RemoveAll(appProject)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = os.MkdirAll(appProject, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	config.AppDir = appProject
 
-	scriptsPath := filepath.Join("target", config.CustomerName, config.TenantName, "scripts")
+	scriptsPath := filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, "scripts")
 	err = os.RemoveAll(scriptsPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = os.MkdirAll(scriptsPath, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = duplosdk.CopyDirectory("./scripts", scriptsPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	var mapToRepalce = map[string]string{
-		"<--admin-tenant-->": config.TenantProject,
-		"<--aws-services-->": config.AwsServicesProject,
-		"<--app-->":          config.AppProject,
-		"<--admin-infra-->":  config.AdminInfra,
+	var mapToRepalce = scriptReplacements(config)
+	for _, script := range scriptsWithPlaceholders {
+		err = common.RepalceStringInFile(filepath.Join(scriptsPath, script), mapToRepalce)
+		if err != nil {
+			return err
+		}
 	}
-	common.RepalceStringInFile(filepath.Join(scriptsPath, "plan.sh"), mapToRepalce)
-	common.RepalceStringInFile(filepath.Join(scriptsPath, "apply.sh"), mapToRepalce)
-	common.RepalceStringInFile(filepath.Join(scriptsPath, "destroy.sh"), mapToRepalce)
 
-	err = duplosdk.Copy(".gitignore", filepath.Join("target", config.CustomerName, config.TenantName, ".gitignore"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = duplosdk.Copy(".envrc", filepath.Join("target", config.CustomerName, config.TenantName, ".envrc"))
+	err = duplosdk.Copy(".gitignore", filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, ".gitignore"))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	envFile, err := os.OpenFile(filepath.Join("target", config.CustomerName, config.TenantName, ".envrc"), os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer envFile.Close()
-	if _, err := envFile.WriteString("\nexport tenant_id=\"" + config.TenantId + "\""); err != nil {
-		log.Fatal(err)
+	if len(config.ConfigFile) > 0 {
+		err = writeConfigFile(config)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = duplosdk.Copy(".envrc", filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, ".envrc"))
+		if err != nil {
+			return err
+		}
+		envFile, err := os.OpenFile(filepath.Join(config.TargetDir, config.CustomerName, config.TenantName, ".envrc"), os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer envFile.Close()
+		if _, err := envFile.WriteString("\nexport tenant_id=\"" + config.TenantId + "\""); err != nil {
+			return err
+		}
 	}
 	//========
 
-	config.AdminInfraPath = filepath.Join("target", config.CustomerName, "admin-infra")
+	config.AdminInfraPath = filepath.Join(config.TargetDir, config.CustomerName, "admin-infra")
 	adminInfra := filepath.Join(config.AdminInfraPath + "/terraform")
+	config.AdminInfraDir = adminInfra
+	// admin-infra is shared by the tenants of a customer, so it is only recreated when generated.
+	if config.SkipAdminInfra {
+		log.Println("[TRACE] <====== Initialized target directory with customer name and tenant id. =====>")
+		return nil
+	}
 	err = os.RemoveAll(config.AdminInfraPath)
 	if err != nil {
-		log.Fatal(err)
-	}
-	//	err = os.RemoveAll(filepath.Join("target", config.CustomerName))
-	//	if err != nil {
-	//		log.Fatal(err)
-	//	}
-	//	err = os.RemoveAll(adminInfra)
-	//	if err != nil {
-	//		log.Fatal(err)
-	//	}
+		return err
+	}
 	err = os.MkdirAll(adminInfra, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	err = os.MkdirAll(filepath.Join(config.AdminInfraPath, "config", config.DuploPlanId), os.ModePerm)
+	if err != nil {
+		return err
 	}
 	adminScriptsPath := filepath.Join(config.AdminInfraPath, "/scripts")
 	err = os.RemoveAll(adminScriptsPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = os.MkdirAll(adminScriptsPath, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	err = duplosdk.CopyDirectory("./scripts", adminScriptsPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	for _, script := range scriptsWithPlaceholders {
+		err = common.RepalceStringInFile(filepath.Join(adminScriptsPath, script), mapToRepalce)
+		if err != nil {
+			return err
+		}
 	}
-	common.RepalceStringInFile(filepath.Join(adminScriptsPath, "plan.sh"), mapToRepalce)
-	common.RepalceStringInFile(filepath.Join(adminScriptsPath, "apply.sh"), mapToRepalce)
-	common.RepalceStringInFile(filepath.Join(adminScriptsPath, "destroy.sh"), mapToRepalce)
-
-	config.AdminInfraDir = adminInfra
 
 	log.Println("[TRACE] <====== Initialized target directory with customer name and tenant id. =====>")
 	return nil
 }
 
+// scriptsWithPlaceholders are the scripts whose placeholders are replaced when they are copied.
+var scriptsWithPlaceholders = []string{"_env.sh", "plan.sh", "apply.sh", "destroy.sh", "import.sh"}
+
+// scriptReplacements are the values of the placeholders of the scripts. The terraform init
+// arguments and the workspace step come from the backend of the projects.
+func scriptReplacements(config *common.Config) map[string]string {
+	var backendConfig []string
+	if config.S3Backend {
+		// The scripts read the account from AWS when they run.
+		scriptConfig := *config
+		scriptConfig.AccountID = "${AWS_ACCOUNT_ID}"
+		for _, setting := range config.StateBackend().BackendConfig(&scriptConfig) {
+			backendConfig = append(backendConfig, "-backend-config="+setting)
+		}
+	}
+	return map[string]string{
+		"<--admin-tenant-->":   config.TenantProject,
+		"<--aws-services-->":   config.AwsServicesProject,
+		"<--app-->":            config.AppProject,
+		"<--admin-infra-->":    config.AdminInfra,
+		"<--backend-config-->": strings.Join(backendConfig, " "),
+		"<--workspaces-->":     strconv.FormatBool(config.StateBackend().Workspaces()),
+	}
+}
+
+// writeConfigFile stores the config file used for the run, without credentials, next to the
+// generated code and writes the .envrc used by the scripts.
+func writeConfigFile(config *common.Config) error {
+	fileConfig, err := common.LoadFileConfig(config.ConfigFile)
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(fileConfig.Sanitized())
+	if err != nil {
+		return err
+	}
+	tenantPath := filepath.Join(config.TargetDir, config.CustomerName, config.TenantName)
+	err = os.WriteFile(filepath.Join(tenantPath, "tf-generator.yaml"), content, 0644)
+	if err != nil {
+		return err
+	}
+	envrc := "# Generated from " + filepath.Base(config.ConfigFile) + "\nexport tenant_id=\"" + config.TenantId + "\"\n"
+	return os.WriteFile(filepath.Join(tenantPath, ".envrc"), []byte(envrc), 0644)
+}
+
 func (tfg *TfGeneratorService) StartTFGeneration(config *common.Config, client *duplosdk.Client) error {
 	// var tf *tfexec.Terraform
 	providerGen := &common.Provider{}
 	providerGen.Generate(config, client)
+	// The outputs read by the projects of a previous tenant are not written into this one.
+	tfg.contractOutputs = map[string][]string{}
 
 	// if config.GenerateTfState {
 	// 	tf := tfInit(config, config.AdminTenantDir)
@@ -174,65 +234,101 @@ func (tfg *TfGeneratorService) StartTFGeneration(config *common.Config, client *
 
 	if !config.SkipAdminTenant {
 		log.Println("[TRACE] <====== Start TF generation for tenant project. =====>")
-		// Register New TF generator for Tenant Project
-		tenantGeneratorList := TenantGenerators
-		if config.S3Backend {
-			tenantGeneratorList = append(tenantGeneratorList, &tenant.TenantBackend{})
+		tenantGeneratorList, err := Registry.ForProject(config, PROJECT_ADMIN_TENANT)
+		if err != nil {
+			return err
+		}
+		err = tfg.starTFGenerationForProject(config, client, tenantGeneratorList, config.AdminTenantDir, config.ConfigVars, config.Parallelism)
+		if err != nil {
+			return err
 		}
-
-		starTFGenerationForProject(config, client, tenantGeneratorList, config.AdminTenantDir)
 		if config.ValidateTf {
-			common.ValidateAndFormatTfCode(config.AdminTenantDir, config.TFVersion)
+			err = tfg.validateAndFormat(config, config.AdminTenantDir)
+			if err != nil {
+				return err
+			}
 		}
 		log.Println("[TRACE] <====== End TF generation for tenant project. =====>")
 	}
 
 	if !config.SkipAwsServices {
 		log.Println("[TRACE] <====== Start TF generation for aws services project. =====>")
-		// Register New TF generator for AWS Services project
-		awsServcesGeneratorList := AWSServicesGenerators
-		if config.S3Backend {
-			awsServcesGeneratorList = append(awsServcesGeneratorList, &awsservices.AwsServicesBackend{})
+		awsServcesGeneratorList, err := Registry.ForProject(config, PROJECT_AWS_SERVICES)
+		if err != nil {
+			return err
+		}
+		err = tfg.starTFGenerationForProject(config, client, awsServcesGeneratorList, config.AwsServicesDir, config.ConfigVars, config.Parallelism)
+		if err != nil {
+			return err
 		}
-		starTFGenerationForProject(config, client, awsServcesGeneratorList, config.AwsServicesDir)
 		if config.ValidateTf {
-			common.ValidateAndFormatTfCode(config.AwsServicesDir, config.TFVersion)
+			err = tfg.validateAndFormat(config, config.AwsServicesDir)
+			if err != nil {
+				return err
+			}
 		}
 		log.Println("[TRACE] <====== End TF generation for aws services project. =====>")
 	}
 
 	if !config.SkipApp {
 		log.Println("[TRACE] <====== Start TF generation for app project. =====>")
-		// Register New TF generator for App Services project
-		appGeneratorList := AppGenerators
-		if config.S3Backend {
-			appGeneratorList = append(appGeneratorList, &app.AppBackend{})
+		appGeneratorList, err := Registry.ForProject(config, PROJECT_APP)
+		if err != nil {
+			return err
+		}
+		err = tfg.starTFGenerationForProject(config, client, appGeneratorList, config.AppDir, config.ConfigVars, config.Parallelism)
+		if err != nil {
+			return err
 		}
-		starTFGenerationForProject(config, client, appGeneratorList, config.AppDir)
 		if config.ValidateTf {
-			common.ValidateAndFormatTfCode(config.AppDir, config.TFVersion)
+			err = tfg.validateAndFormat(config, config.AppDir)
+			if err != nil {
+				return err
+			}
 		}
 		log.Println("[TRACE] <====== End TF generation for app project. =====>")
 	}
 
+	// The tenant project writes what the other projects read, so it is completed once they are generated.
+	if config.ProjectContract == common.CONTRACT_SSM && !config.SkipAdminTenant {
+		err := WriteContractParameters(config.AdminTenantDir, tfg.contractOutputs, config)
+		if err != nil {
+			err = tfg.recordError(config, config.TenantProject, "project contract", err)
+			if err != nil {
+				return err
+			}
+		} else if config.ValidateTf {
+			err = tfg.validateAndFormat(config, config.AdminTenantDir)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	if !config.SkipAdminInfra {
 		log.Println("[TRACE] <====== Start TF generation for Admin project. =====>")
-		// Register New TF generator for Admin Services project
-		adminInfraGeneratorList := AdminInfraGenerator
-		if config.S3Backend {
-			adminInfraGeneratorList = append(adminInfraGeneratorList, &adminInfra.Infra{})
+		adminInfraGeneratorList, err := Registry.ForProject(config, PROJECT_ADMIN_INFRA)
+		if err != nil {
+			return err
 		}
 		fmt.Println("adminInfraGeneratorList ", adminInfraGeneratorList)
-		starTFGenerationForProject(config, client, adminInfraGeneratorList, config.AdminInfraDir)
+		// admin-infra generators append to shared files of the infrastructure, so they run one at a time.
+		err = tfg.starTFGenerationForProject(config, client, adminInfraGeneratorList, config.AdminInfraDir, filepath.Join(config.AdminInfraPath, "config", config.DuploPlanId), 1)
+		if err != nil {
+			return err
+		}
 		if config.ValidateTf {
-			common.ValidateAndFormatTfCode(config.AdminInfraDir, config.TFVersion)
+			err = tfg.validateAndFormat(config, config.AdminInfraDir)
+			if err != nil {
+				return err
+			}
 		}
 		log.Println("[TRACE] <====== End TF generation for Admin project. =====>")
 	}
 	return nil
 }
 
-func starTFGenerationForProject(config *common.Config, client *duplosdk.Client, generatorList []Generator, targetLocation string) {
+func (tfg *TfGeneratorService) starTFGenerationForProject(config *common.Config, client *duplosdk.Client, generatorList []*GeneratorInfo, targetLocation string, configVarsLocation string, parallelism int) error {
 
 	tfContext := common.TFContext{
 		TargetLocation: targetLocation,
@@ -241,11 +337,51 @@ func starTFGenerationForProject(config *common.Config, client *duplosdk.Client,
 		ConfgiVars:     common.ConfigVars{},
 	}
 	fmt.Println("TF context ", tfContext)
-	// 1. Generate Duplo TF resources.
+	token := strings.Split(tfContext.TargetLocation, "/")
+	projectName := token[len(token)-1]
+
+	// 1. Generate Duplo TF resources. Generators run concurrently once their dependencies are done
+	// and their results are merged in registry order.
+	contexts := make([]*common.TFContext, len(generatorList))
+	errs := make([]error, len(generatorList))
+	done := map[string]chan struct{}{}
 	for _, g := range generatorList {
-		c, err := g.Generate(config, client)
-		if err != nil {
-			log.Fatalf("error running admin tenant tf generation: %s", err)
+		done[g.Name] = make(chan struct{})
+	}
+	semaphore := make(chan struct{}, max(parallelism, 1))
+	var wg sync.WaitGroup
+	for i, g := range generatorList {
+		wg.Add(1)
+		go func(i int, g *GeneratorInfo) {
+			defer wg.Done()
+			defer close(done[g.Name])
+			for _, dependency := range g.DependsOn {
+				if dependencyDone, ok := done[dependency]; ok {
+					<-dependencyDone
+				}
+			}
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			// A malformed resource must not take the whole run down.
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			// Each generator gets its own copy of the config as some of them update it.
+			generatorConfig := *config
+			contexts[i], errs[i] = g.Generator.Generate(&generatorConfig, client)
+		}(i, g)
+	}
+	wg.Wait()
+	for i, c := range contexts {
+		// A generator which skipped some resources returns their errors along with the context of
+		// the others.
+		for _, generatorErr := range joinedErrors(errs[i]) {
+			err := tfg.recordError(config, projectName, generatorList[i].Name, generatorErr)
+			if err != nil {
+				return err
+			}
 		}
 		fmt.Println("Checking tf context ", c)
 		if c != nil {
@@ -258,10 +394,57 @@ func starTFGenerationForProject(config *common.Config, client *duplosdk.Client,
 			if len(c.ImportConfigs) > 0 {
 				tfContext.ImportConfigs = append(tfContext.ImportConfigs, c.ImportConfigs...)
 			}
+			if len(c.KmsKeys) > 0 {
+				tfContext.KmsKeys = append(tfContext.KmsKeys, c.KmsKeys...)
+			}
+			for arn, ref := range c.ArnRefs {
+				if tfContext.ArnRefs == nil {
+					tfContext.ArnRefs = map[string]string{}
+				}
+				tfContext.ArnRefs[arn] = ref
+			}
 		}
 	}
 	fmt.Println("Checking tf context input vars")
 
+	// Reference the KMS keys and resources of the tenant instead of their ARN so that a copy of the tenant
+	// uses its own.
+	if len(tfContext.KmsKeys) > 0 || len(tfContext.ArnRefs) > 0 {
+		err := tfg.transformProject(config, projectName, "references", &tfContext, func(tfContext *common.TFContext) error {
+			return ReferenceLiterals(tfContext.TargetLocation, tfContext)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Read the outputs of the tenant project through the contract instead of its remote state.
+	if config.ProjectContract != common.CONTRACT_REMOTE_STATE {
+		err := tfg.transformProject(config, projectName, "project contract", &tfContext, func(tfContext *common.TFContext) error {
+			outputs, err := ApplyProjectContract(tfContext.TargetLocation, tfContext, config)
+			if err != nil {
+				return err
+			}
+			if len(outputs) > 0 {
+				tfg.contractOutputs[projectName] = outputs
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Fold the resources of each collection type into a resource with for_each.
+	if config.CollectionMode {
+		err := tfg.transformProject(config, projectName, "collection mode", &tfContext, func(tfContext *common.TFContext) error {
+			return CollectProject(tfContext.TargetLocation, tfContext)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// 2. Generate input vars.
 	if len(tfContext.InputVars) > 0 {
 		varsGenerator := common.Vars{
@@ -280,23 +463,56 @@ func starTFGenerationForProject(config *common.Config, client *duplosdk.Client,
 		outVarsGenerator.Generate()
 	}
 	// 4. Generate json for config folder
-	token := strings.Split(tfContext.TargetLocation, "/")
-	projectName := token[len(token)-1]
-
 	configVarsGenerator := common.ConfigVars{
-		TargetLocation: config.ConfigVars,
+		TargetLocation: configVarsLocation,
 		Config:         common.ConstructConfigVars(tfContext.InputVars),
 		Project:        projectName,
 	}
 	configVarsGenerator.Generate()
 
-	// 5. Import all resources
-	if config.GenerateTfState && len(tfContext.ImportConfigs) > 0 {
+	// 5. Move each file of resources into a module.
+	if config.ModuleMode {
+		err := tfg.transformProject(config, projectName, "module mode", &tfContext, func(tfContext *common.TFContext) error {
+			moved, err := ModularizeProject(tfContext.TargetLocation)
+			if err != nil {
+				return err
+			}
+			for i, ic := range tfContext.ImportConfigs {
+				// Items of a collection keep their key, like duplocloud_s3_bucket.this["assets"].
+				address, key, _ := strings.Cut(ic.ResourceAddress, "[")
+				if newAddress, ok := moved[address]; ok {
+					if len(key) > 0 {
+						newAddress += "[" + key
+					}
+					tfContext.ImportConfigs[i].ResourceAddress = newAddress
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 6. Import all resources
+	if config.GenerateImportBlocks {
+		importBlocksGenerator := common.ImportBlocks{
+			TargetLocation: tfContext.TargetLocation,
+			ImportConfigs:  tfContext.ImportConfigs,
+		}
+		err := importBlocksGenerator.Generate()
+		if err != nil {
+			return tfg.recordError(config, projectName, "import blocks", err)
+		}
+	} else if config.GenerateTfState && len(tfContext.ImportConfigs) > 0 {
 		tfInitializer := common.TfInitializer{
 			WorkingDir: targetLocation,
 			Config:     config,
 		}
-		tf := tfInitializer.InitWithWorkspace()
+		tf, err := tfInitializer.InitWithWorkspace()
+		if err != nil {
+			return tfg.recordError(config, projectName, "terraform init", err)
+		}
 		importer := &common.Importer{}
 		// Get state file if already present.
 		state, err := tf.Show(context.Background())
@@ -305,9 +521,13 @@ func starTFGenerationForProject(config *common.Config, client *duplosdk.Client,
 			fmt.Println(err)
 		}
 		importedResourceAddresses := []string{}
-		if state != nil && state.Values != nil && state.Values.RootModule != nil && len(state.Values.RootModule.Resources) > 0 {
-			for _, r := range state.Values.RootModule.Resources {
-				importedResourceAddresses = append(importedResourceAddresses, r.Address)
+		if state != nil && state.Values != nil && state.Values.RootModule != nil {
+			modules := []*tfjson.StateModule{state.Values.RootModule}
+			for len(modules) > 0 {
+				for _, r := range modules[0].Resources {
+					importedResourceAddresses = append(importedResourceAddresses, r.Address)
+				}
+				modules = append(modules[1:], modules[0].ChildModules...)
 			}
 		}
 		for _, ic := range tfContext.ImportConfigs {
@@ -316,12 +536,115 @@ func starTFGenerationForProject(config *common.Config, client *duplosdk.Client,
 				log.Printf("[TRACE] Resource %s is already imported.", ic.ResourceAddress)
 				continue
 			}
-			importer.ImportWithoutInit(config, &ic, tf)
+			err = importer.ImportWithoutInit(config, &ic, tf)
+			if err != nil {
+				err = tfg.recordError(config, projectName, "terraform import", common.NewResourceError(ic.ResourceAddress, err))
+				if err != nil {
+					return err
+				}
+			}
 		}
 		//tfInitializer.DeleteWorkspace(config, tf)
 	}
+	return nil
 }
 
+func (tfg *TfGeneratorService) validateAndFormat(config *common.Config, tfDir string) error {
+	err := common.ValidateAndFormatTfCode(tfDir, config.TFVersion)
+	if err != nil {
+		return tfg.recordError(config, filepath.Base(tfDir), "terraform validate", err)
+	}
+	return nil
+}
+
+// transformProject runs a transformation of the generated project on a copy of its context. When
+// it fails and the run continues on errors, the project is kept as generated so that its vars,
+// outputs, tfvars and imports are still written.
+func (tfg *TfGeneratorService) transformProject(config *common.Config, project, name string, tfContext *common.TFContext, transform func(tfContext *common.TFContext) error) error {
+	transformed := *tfContext
+	transformed.InputVars = append([]common.VarConfig{}, tfContext.InputVars...)
+	transformed.OutputVars = append([]common.OutputVarConfig{}, tfContext.OutputVars...)
+	transformed.ImportConfigs = append([]common.ImportConfig{}, tfContext.ImportConfigs...)
+	err := transform(&transformed)
+	if err != nil {
+		return tfg.recordError(config, project, name, err)
+	}
+	*tfContext = transformed
+	return nil
+}
+
+// recordError adds the error to the report. It is returned back unless the run continues on errors.
+func (tfg *TfGeneratorService) recordError(config *common.Config, project, generator string, err error) error {
+	if tfg.Report == nil {
+		tfg.Report = &ErrorReport{}
+	}
+	generatorErr := NewTFGeneratorError(config, project, generator, err)
+	log.Printf("[TRACE] - %s", generatorErr)
+	tfg.Report.Add(generatorErr)
+	if config.ContinueOnError {
+		return nil
+	}
+	return generatorErr
+}
+
+// PostProcess writes the secrets report of the tenant, scans the generated files for secrets and
+// saves the generation as the base of the next update.
 func (tfg *TfGeneratorService) PostProcess(config *common.Config, client *duplosdk.Client) error {
+	tenantPath := filepath.Join(config.TargetDir, config.CustomerName, config.TenantName)
+	err := config.Secrets.WriteReport(tenantPath, config.TenantName)
+	if err != nil {
+		return err
+	}
+	err = scanSecrets(config, tenantPath)
+	if err != nil {
+		return err
+	}
+	err = SaveGenerationBase(tenantPath)
+	if err != nil {
+		return err
+	}
+	if config.SkipAdminInfra {
+		return nil
+	}
+	err = scanSecrets(config, config.AdminInfraPath)
+	if err != nil {
+		return err
+	}
+	return SaveGenerationBase(config.AdminInfraPath)
+}
+
+// scanSecrets runs the secret scan of a generated folder and writes its report there. It fails
+// when the fail mode finds a value.
+func scanSecrets(config *common.Config, dir string) error {
+	if config.SecretScan == common.SECRET_SCAN_OFF {
+		return nil
+	}
+	scanner, err := NewSecretScanner(config.SecretScanAllow, config.SecretScan == common.SECRET_SCAN_REDACT)
+	if err != nil {
+		return err
+	}
+	findings, err := scanner.ScanDir(dir)
+	if err != nil {
+		return err
+	}
+	err = WriteSecretScanReport(dir, findings)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	for _, finding := range findings {
+		log.Printf("[TRACE] - secret scan: %s", finding)
+	}
+	// Relative to the target folder, as update mode generates into a temporary one.
+	report := filepath.Join(dir, SECRET_SCAN_REPORT_FILE)
+	if rel, err := filepath.Rel(config.TargetDir, report); err == nil {
+		report = rel
+	}
+	if config.SecretScan == common.SECRET_SCAN_FAIL {
+		return fmt.Errorf("secret scan found %d value(s) looking like secrets, see %s in the target folder", len(findings), report)
+	}
+	fmt.Printf("The secret scan found %d value(s) looking like secrets. See %s in the target folder\n", len(findings), report)
 	return nil
 }
@@ -0,0 +1,811 @@
+package tfgenerator
+
+import (
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"tenant-terraform-generator/duplosdk"
+	"tenant-terraform-generator/tf-generator/app"
+	"tenant-terraform-generator/tf-generator/common"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+const (
+	testTenantId   = "2a5ba6a4-5a4b-4e0e-8e3c-5a6f6c7b2f01"
+	testTenantName = "dev01"
+	testPlanId     = "nonprod"
+)
+
+// testConfig resolves the config like a run against the fixture tenant, without touching the env.
+func testConfig(t *testing.T, targetDir string, values map[string]string) *common.Config {
+	t.Helper()
+	env := map[string]string{
+		"duplo_host":    "https://fixtures.duplocloud.invalid",
+		"duplo_token":   "fixture-token",
+		"tenant_name":   testTenantName,
+		"customer_name": "duplo-fixtures",
+		"target_dir":    targetDir,
+		"validate_tf":   "false",
+		// Every generator runs and each failure is reported by the test.
+		"continue_on_error": "true",
+	}
+	for key, value := range values {
+		env[key] = value
+	}
+	validator := common.EnvVarValidator{
+		Lookup: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+	}
+	config, err := validator.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.TenantId = testTenantId
+	config.DuploPlanId = testPlanId
+	config.AccountID = "123456789012"
+	config.DuploPlanRegion = "us-west-2"
+	config.DuploDefaultPlanRegion = "us-west-2"
+	return config
+}
+
+// testClient returns a client served by the fixtures of testdata/duplo-api.json.
+func testClient(t *testing.T) (*duplosdk.Client, *duplosdk.FixtureTransport) {
+	t.Helper()
+	bundle, err := duplosdk.LoadFixtureBundle(filepath.Join("testdata", "duplo-api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := duplosdk.NewClient("https://fixtures.duplocloud.invalid", "fixture-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := duplosdk.NewFixtureTransport(bundle)
+	client.HTTPClient.Transport = transport
+	// Runs read through the response cache by default, so the goldens are generated with it.
+	client.Cache = duplosdk.NewResponseCache()
+	return client, transport
+}
+
+// generateFixtureTenant runs every project of the fixture tenant into targetDir, the way the
+// generate command does after PreProcess.
+func generateFixtureTenant(t *testing.T, config *common.Config, client *duplosdk.Client) {
+	t.Helper()
+	generateFixtureTenantWith(t, &TfGeneratorService{Report: &ErrorReport{}}, config, client)
+}
+
+// generateFixtureTenantWith is generateFixtureTenant with a service which may have generated other
+// tenants already.
+func generateFixtureTenantWith(t *testing.T, service *TfGeneratorService, config *common.Config, client *duplosdk.Client) {
+	t.Helper()
+	config.TFCodePath = filepath.Join(config.TargetDir, "terraform")
+	config.ConfigVars = filepath.Join(config.TargetDir, "config", config.TenantName)
+	config.AdminTenantDir = filepath.Join(config.TFCodePath, config.TenantProject)
+	config.AwsServicesDir = filepath.Join(config.TFCodePath, config.AwsServicesProject)
+	config.AppDir = filepath.Join(config.TFCodePath, config.AppProject)
+	config.AdminInfraPath = filepath.Join(config.TargetDir, "admin-infra")
+	config.AdminInfraDir = filepath.Join(config.AdminInfraPath, "terraform")
+	for _, dir := range []string{config.ConfigVars, config.AdminTenantDir, config.AwsServicesDir, config.AppDir, config.AdminInfraDir,
+		filepath.Join(config.AdminInfraPath, "config", config.DuploPlanId)} {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := service.StartTFGeneration(config, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range service.Report.Errors {
+		t.Errorf("generation error: %s", e)
+	}
+}
+
+func TestGenerateGolden(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{})
+	client, transport := testClient(t)
+	generateFixtureTenant(t, config, client)
+	for _, missing := range transport.Missing() {
+		t.Logf("no fixture for %s", missing)
+	}
+
+	goldenDir := filepath.Join("testdata", "golden")
+	if *update {
+		err := os.RemoveAll(goldenDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = copyTree(targetDir, goldenDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	compareTrees(t, goldenDir, targetDir)
+}
+
+// TestGoldenVarsDeclared checks that every variable referenced by a golden project is declared in
+// the same directory. Terraform would reject the project otherwise.
+func TestGoldenVarsDeclared(t *testing.T) {
+	declared := map[string]map[string]bool{}
+	referenced := map[string]map[string]hcl.Range{}
+	err := filepath.WalkDir(filepath.Join("testdata", "golden"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".tf" {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, diags := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Errorf("%s is not valid HCL: %s", path, diags)
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if declared[dir] == nil {
+			declared[dir] = map[string]bool{}
+			referenced[dir] = map[string]hcl.Range{}
+		}
+		body := file.Body.(*hclsyntax.Body)
+		for _, block := range body.Blocks {
+			if block.Type == "variable" && len(block.Labels) > 0 {
+				declared[dir][block.Labels[0]] = true
+			}
+		}
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+			if !ok || len(expr.Traversal) < 2 || expr.Traversal.RootName() != "var" {
+				return nil
+			}
+			if attr, ok := expr.Traversal[1].(hcl.TraverseAttr); ok {
+				referenced[dir][attr.Name] = expr.SrcRange
+			}
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referenced) == 0 {
+		t.Fatal("no golden project was found")
+	}
+	for dir, vars := range referenced {
+		for _, name := range sortedKeys(vars) {
+			if !declared[dir][name] {
+				t.Errorf("%s: var.%s is not declared in %s", vars[name], name, dir)
+			}
+		}
+	}
+}
+
+// TestGenerateEveryGenerator makes sure each registered generator writes at least one of its
+// resource types for the fixture tenant, so that a generator without fixtures is noticed.
+func TestGenerateEveryGenerator(t *testing.T) {
+	targetDir := t.TempDir()
+	enable := []string{}
+	for _, g := range Registry.All() {
+		enable = append(enable, g.Name)
+	}
+	config := testConfig(t, targetDir, map[string]string{
+		"enable_generators": strings.Join(enable, ","),
+	})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+
+	written := map[string]bool{}
+	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".tf" {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, diags := hclwrite.ParseConfig(content, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Errorf("%s is not valid HCL: %s", path, diags)
+			return nil
+		}
+		for _, block := range file.Body().Blocks() {
+			if block.Type() == "resource" && len(block.Labels()) > 0 {
+				written[block.Labels()[0]] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range Registry.All() {
+		if len(g.ResourceTypes) == 0 {
+			continue
+		}
+		found := false
+		for _, resourceType := range g.ResourceTypes {
+			found = found || written[resourceType]
+		}
+		if !found {
+			t.Errorf("generator %s wrote none of %s, add fixtures to testdata/duplo-api.json", g.Name, strings.Join(g.ResourceTypes, ", "))
+		}
+	}
+}
+
+// TestGenerateDeterministic generates the fixture tenant twice with every generator. The trees must
+// be byte identical.
+func TestGenerateDeterministic(t *testing.T) {
+	enable := []string{}
+	for _, g := range Registry.All() {
+		enable = append(enable, g.Name)
+	}
+	dirs := []string{t.TempDir(), t.TempDir()}
+	for _, dir := range dirs {
+		config := testConfig(t, dir, map[string]string{
+			"enable_generators": strings.Join(enable, ","),
+		})
+		client, _ := testClient(t)
+		generateFixtureTenant(t, config, client)
+	}
+	compareTrees(t, dirs[0], dirs[1])
+}
+
+// TestGenerateTenantSettings checks that the tenant config only has the allowed settings of the
+// tenant metadata, and that its tags are imported by key.
+func TestGenerateTenantSettings(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{
+		"tenant_settings":        "k8s_namespace",
+		"generate_import_blocks": "true",
+	})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+	files, err := readFiles(filepath.Join(targetDir, "terraform", config.TenantProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(files["main.tf"], "key   = \"k8s_namespace\"\n    value = \"duploservices-dev01\"") {
+		t.Errorf("tenant_settings should add k8s_namespace:\n%s", files["main.tf"])
+	}
+	if strings.Contains(files["main.tf"], "aws_account_id") {
+		t.Errorf("the settings managed by DuploCloud should be left out:\n%s", files["main.tf"])
+	}
+	tag := "to = duplocloud_tenant_tag.team\n  id = \"" + testTenantId + "/team\""
+	if !strings.Contains(files["imports.tf"], tag) {
+		t.Errorf("got imports.tf\n%s\nexpected\n%s", files["imports.tf"], tag)
+	}
+}
+
+// TestGenerateEventRules checks that the targets of an event rule reference the functions, queues
+// and topics of the tenant instead of their ARN, and that the rule and its targets are imported.
+func TestGenerateEventRules(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{
+		"collection_mode":        "true",
+		"generate_import_blocks": "true",
+	})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+	files, err := readFiles(filepath.Join(targetDir, "terraform", config.AwsServicesProject))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := files["cw-event-rule-nightly-cleanup.tf"]
+	for _, targetArn := range []string{
+		"duplocloud_aws_lambda_function.resize_images.arn",
+		`duplocloud_aws_sqs_queue.this["orders_events"].arn`,
+		`duplocloud_aws_sns_topic.this["alerts"].arn`,
+		`"arn:aws:ecs:${local.region}:${data.aws_caller_identity.current.account_id}:cluster/duploservices-${local.tenant_name}"`,
+	} {
+		if !strings.Contains(rule, "target_arn     = "+targetArn+"\n") {
+			t.Errorf("a target should reference %s:\n%s", targetArn, rule)
+		}
+	}
+	for _, id := range []string{
+		"to = duplocloud_aws_cloudwatch_event_rule.nightly_cleanup\n  id = \"" + testTenantId + "/duploservices-dev01-nightly-cleanup\"",
+		"to = duplocloud_aws_cloudwatch_event_target.nightly_cleanup_cleanup_queue\n  id = \"" + testTenantId + "/duploservices-dev01-nightly-cleanup/cleanup-queue\"",
+	} {
+		if !strings.Contains(files["imports.tf"], id) {
+			t.Errorf("got imports.tf\n%s\nexpected\n%s", files["imports.tf"], id)
+		}
+	}
+}
+
+// TestGenerateSecretPolicies checks that no secret of the fixtures is written unless its policy is
+// actual, and that every secret is listed in the report.
+func TestGenerateSecretPolicies(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{
+		"secret_policy":   "external",
+		"secret_store":    "ssm",
+		"secret_policies": "duplocloud_byoh=random_password,duplocloud_k8_secret.secret_data.username=actual,duplocloud_aws_ssm_parameter=placeholder",
+	})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+	files, err := readFiles(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		if strings.Contains(content, "s3cr3t-Db!") || strings.Contains(content, "s3cr3t-Orders!") || strings.Contains(content, "PRIVATE KEY") || strings.Contains(content, "oidc-cl1ent-s3cret") {
+			t.Errorf("%s holds a secret value:\n%s", path, content)
+		}
+	}
+	secret := files[filepath.Join("terraform", "app", "k8s-secret-orders-db.tf")]
+	for _, want := range []string{
+		`"password" = data.aws_ssm_parameter.k8_secret_orders_db_secret_data_password.value`,
+		`"username" = "orders"`,
+		`name            = "/duplocloud/dev01/k8_secret/orders_db/secret_data.password"`,
+		"with_decryption = true",
+	} {
+		if !strings.Contains(secret, want) {
+			t.Errorf("the k8s secret is missing %q:\n%s", want, secret)
+		}
+	}
+	byoh := files[filepath.Join("terraform", "aws-services", "byoh-build-box.tf")]
+	if !strings.Contains(byoh, `resource "random_password" "byoh_build_box_private_key"`) || !strings.Contains(byoh, "private_key    = random_password.byoh_build_box_private_key.result") {
+		t.Errorf("the byoh private key should be generated:\n%s", byoh)
+	}
+	// A placeholder does not replace the value of a SecureString parameter on apply.
+	ssm := files[filepath.Join("terraform", "aws-services", "ssm-param-_dev01_orders_db_password.tf")]
+	if !strings.Contains(ssm, `value       = "replace-me"`) || !strings.Contains(ssm, `ignore_changes = ["value"]`) {
+		t.Errorf("the SecureString parameter should hold an ignored placeholder:\n%s", ssm)
+	}
+	// The default rule of rds passwords still applies.
+	if rds := files[filepath.Join("terraform", "aws-services", "rds-orders.tf")]; !strings.Contains(rds, "master_password                 = random_password.orders_password.result") {
+		t.Errorf("the rds password should be generated:\n%s", rds)
+	}
+
+	err = config.Secrets.WriteReport(targetDir, config.TenantName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := struct {
+		Secrets []common.SecretReplacement `json:"secrets"`
+	}{}
+	content, err := os.ReadFile(filepath.Join(targetDir, common.SECRETS_REPORT_JSON_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(content, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := []string{}
+	for _, replacement := range report.Secrets {
+		policies = append(policies, replacement.Resource+" "+replacement.Attribute+" "+replacement.Policy)
+	}
+	expected := []string{
+		"duplocloud_k8_secret.orders_db secret_data.password external",
+		"duplocloud_k8_secret.orders_db secret_data.username actual",
+		"duplocloud_aws_lb_listener_rule.web_listener_80_rule_3 action.authenticate_oidc.client_secret external",
+		"duplocloud_aws_ssm_parameter._dev01_orders_db_password value placeholder",
+		"duplocloud_byoh.build_box private_key random_password",
+		"duplocloud_rds_instance.orders master_password random_password",
+		"duplocloud_rds_instance.reports master_password random_password",
+	}
+	if strings.Join(policies, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got secrets\n%s\nexpected\n%s", strings.Join(policies, "\n"), strings.Join(expected, "\n"))
+	}
+	text, err := os.ReadFile(filepath.Join(targetDir, common.SECRETS_REPORT_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"3 value(s) must be supplied at apply time.",
+		"- app duplocloud_k8_secret.orders_db secret_data.password: store it in /duplocloud/dev01/k8_secret/orders_db/secret_data.password",
+		"- aws-services duplocloud_aws_ssm_parameter._dev01_orders_db_password value: replace the placeholder",
+	} {
+		if !strings.Contains(string(text), want) {
+			t.Errorf("the report is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+// TestWriteConfigFile checks that the config file written next to the generated code reads back
+// as the config file of the run without its token.
+func TestWriteConfigFile(t *testing.T) {
+	sourceDir := t.TempDir()
+	path := filepath.Join(sourceDir, "acme.yaml")
+	err := os.WriteFile(path, []byte(`version: 1
+duplo_host: https://acme.duplocloud.net
+duplo_token: secret-token
+customer: acme
+tenants: [dev01]
+parallelism: 2
+terraform:
+  modules: true
+  backend:
+    type: s3
+    bucket: acme-tfstate
+filters:
+  exclude: [duplocloud_k8_secret]
+secrets:
+  policies:
+    duplocloud_byoh: random_password
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileConfig, err := common.LoadFileConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &common.Config{
+		ConfigFile:   path,
+		TargetDir:    t.TempDir(),
+		CustomerName: "acme",
+		TenantName:   testTenantName,
+		TenantId:     testTenantId,
+	}
+	tenantPath := filepath.Join(config.TargetDir, config.CustomerName, config.TenantName)
+	err = os.MkdirAll(tenantPath, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writeConfigFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := common.LoadFileConfig(filepath.Join(tenantPath, "tf-generator.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.DuploToken) > 0 {
+		t.Errorf("the written config file should not hold the token")
+	}
+	if !reflect.DeepEqual(written, fileConfig.Sanitized()) {
+		t.Errorf("got %+v, expected %+v", written, fileConfig.Sanitized())
+	}
+	envrc, err := os.ReadFile(filepath.Join(tenantPath, ".envrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# Generated from acme.yaml\nexport tenant_id=\"" + testTenantId + "\"\n"; string(envrc) != expected {
+		t.Errorf("got .envrc\n%s\nexpected\n%s", envrc, expected)
+	}
+}
+
+// fixtureSecretFields are the fields of the fixtures holding secret values. Every string below them
+// is a secret.
+var fixtureSecretFields = map[string]bool{
+	"password":        true,
+	"masterpassword":  true,
+	"authtoken":       true,
+	"token":           true,
+	"sessiontoken":    true,
+	"accesskeyid":     true,
+	"secretaccesskey": true,
+	"privatekey":      true,
+	"secretdata":      true,
+	"clientsecret":    true,
+	"variables":       true,
+}
+
+// TestFixtureSecretsRedacted checks that recording the fixtures of testdata/duplo-api.json would
+// redact every secret value they hold.
+func TestFixtureSecretsRedacted(t *testing.T) {
+	bundle, err := duplosdk.LoadFixtureBundle(filepath.Join("testdata", "duplo-api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, response := range bundle.Responses {
+		var body interface{}
+		err := json.Unmarshal(duplosdk.RedactFixtureBody(response.Body, duplosdk.DefaultRedactedFixtureKeys), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixtureSecrets(body, "", false, func(field string, value string) {
+			found[field] = true
+			if len(value) > 0 && value != duplosdk.REDACTED_FIXTURE_VALUE {
+				t.Errorf("%s %s is recorded as %q", response.Path, field, value)
+			}
+		})
+	}
+	for _, field := range []string{"MasterPassword", "AuthToken", "Privatekey", "SecretData", "ClientSecret", "Variables", "Environment.Value", "OtherDockerConfig.Env.Value", "Value"} {
+		if !found[field] {
+			t.Errorf("the fixtures hold no %s to check", field)
+		}
+	}
+}
+
+// fixtureSecrets calls found with every string of a fixture body held by a secret field. The values
+// of environment variables are secret too, including the ones in the docker config of a service.
+// So is the value of a SecureString parameter.
+func fixtureSecrets(value interface{}, field string, secret bool, found func(field string, value string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			name := strings.TrimPrefix(field+"."+key, ".")
+			switch {
+			case secret:
+				fixtureSecrets(item, field, true, found)
+			case fixtureSecretFields[strings.ToLower(key)]:
+				fixtureSecrets(item, name, true, found)
+			case key == "Value" && v["Type"] == "SecureString":
+				fixtureSecrets(item, name, true, found)
+			case strings.EqualFold(key, "Env") || key == "Environment":
+				if list, ok := item.([]interface{}); ok {
+					for _, variable := range list {
+						variable, _ := variable.(map[string]interface{})
+						for variableKey, variableValue := range variable {
+							fixtureSecrets(variableValue, name+".Value", strings.EqualFold(variableKey, "Value"), found)
+						}
+					}
+					continue
+				}
+				fixtureSecrets(item, field, false, found)
+			case key == "OtherDockerConfig":
+				var config interface{}
+				if text, ok := item.(string); ok && json.Unmarshal([]byte(text), &config) == nil {
+					fixtureSecrets(config, name, false, found)
+				}
+			default:
+				fixtureSecrets(item, field, false, found)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			fixtureSecrets(item, field, secret, found)
+		}
+	case string:
+		if secret {
+			found(field, v)
+		}
+	}
+}
+
+// compareTrees reports missing, unexpected and different files between the golden and the
+// generated folders.
+func compareTrees(t *testing.T, goldenDir, generatedDir string) {
+	t.Helper()
+	golden, err := readFiles(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := readFiles(generatedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range golden {
+		actual, ok := generated[path]
+		if !ok {
+			t.Errorf("%s was not generated", path)
+			continue
+		}
+		if expected != actual {
+			t.Errorf("%s differs from the golden file, run go test ./tf-generator -run TestGenerateGolden -update to accept the change.\n--- golden\n%s\n--- generated\n%s", path, expected, actual)
+		}
+	}
+	for path := range generated {
+		if _, ok := golden[path]; !ok {
+			t.Errorf("%s is generated but has no golden file", path)
+		}
+	}
+}
+
+// brokenGenerator writes a file which the transformations of a project can not parse.
+type brokenGenerator struct {
+	context common.TFContext
+	// files are written next to the broken file.
+	files map[string]string
+}
+
+func (g *brokenGenerator) Generate(config *common.Config, client *duplosdk.Client) (*common.TFContext, error) {
+	files := map[string]string{"broken.tf": "resource \"duplocloud_s3_bucket\" \"logs\" {\n"}
+	for name, content := range g.files {
+		files[name] = content
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(config.AppDir, name), []byte(content), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &g.context, nil
+}
+
+// TestTransformErrors checks that a project whose transformation fails is still written as
+// generated when the run continues on errors.
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		context common.TFContext
+		files   map[string]string
+	}{
+		{
+			name:   "module mode",
+			values: map[string]string{"module_mode": "true"},
+		},
+		{
+			name:   "collection mode",
+			values: map[string]string{"collection_mode": "true"},
+		},
+		{
+			name:   "project contract",
+			values: map[string]string{"project_contract": "tfvars"},
+			files: map[string]string{"main.tf": `data "terraform_remote_state" "tenant" {
+  backend = "local"
+}
+
+locals {
+  tenant_id = data.terraform_remote_state.tenant.outputs["tenant_id"]
+}
+`},
+		},
+		{
+			name:    "references",
+			context: common.TFContext{ArnRefs: map[string]string{"arn:aws:sqs:us-west-2:123456789012:orders": "duplocloud_aws_sqs_queue.orders.arn"}},
+			files:   map[string]string{"a.tf": "locals {\n  queue_arn = \"arn:aws:sqs:us-west-2:123456789012:orders\"\n}\n"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.values == nil {
+				test.values = map[string]string{}
+			}
+			test.values["continue_on_error"] = "true"
+			test.values["generate_import_blocks"] = "true"
+			config := testConfig(t, t.TempDir(), test.values)
+			config.AppDir = filepath.Join(config.TargetDir, "app")
+			configVars := filepath.Join(config.TargetDir, "config")
+			for _, dir := range []string{config.AppDir, configVars} {
+				err := os.MkdirAll(dir, os.ModePerm)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			test.context.InputVars = append(test.context.InputVars, common.VarConfig{Name: "replicas", DefaultVal: "2", TypeVal: "number"})
+			test.context.ImportConfigs = []common.ImportConfig{{ResourceAddress: "duplocloud_s3_bucket.logs", ResourceId: testTenantId + "/logs"}}
+			generators := []*GeneratorInfo{{Name: "broken", Project: PROJECT_APP, Generator: &brokenGenerator{context: test.context, files: test.files}}}
+			service := &TfGeneratorService{Report: &ErrorReport{}}
+			err := service.starTFGenerationForProject(config, nil, generators, config.AppDir, configVars, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(service.Report.Errors) != 1 || service.Report.Errors[0].Generator != test.name {
+				t.Errorf("the %s error should be reported, got %v", test.name, service.Report.Errors)
+			}
+			files, err := readFiles(config.TargetDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range []string{"app/vars.tf", "app/imports.tf", "config/app.tfvars.json"} {
+				if _, ok := files[file]; !ok {
+					t.Errorf("%s should be written, got %v", file, sortedKeys(files))
+				}
+			}
+			for name, content := range test.files {
+				if files["app/"+name] != content {
+					t.Errorf("%s should be kept as generated, got\n%s", name, files["app/"+name])
+				}
+			}
+			if !strings.Contains(files["app/imports.tf"], "to = duplocloud_s3_bucket.logs") {
+				t.Errorf("the import should keep the generated address:\n%s", files["app/imports.tf"])
+			}
+		})
+	}
+}
+
+// TestGenerateSkipsMalformedResources checks that a service which can not be generated is reported
+// while the other services keep their files, vars and imports.
+func TestGenerateSkipsMalformedResources(t *testing.T) {
+	bundle, err := duplosdk.LoadFixtureBundle(filepath.Join("testdata", "duplo-api.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, response := range bundle.Responses {
+		if !strings.HasSuffix(response.Path, "/GetReplicationControllers") {
+			continue
+		}
+		services := []map[string]interface{}{}
+		err = json.Unmarshal(response.Body, &services)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, service := range services {
+			if service["Name"] == "orders-api" {
+				service["Template"].(map[string]interface{})["OtherDockerConfig"] = "{not json"
+			}
+		}
+		bundle.Responses[i].Body, err = json.Marshal(services)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, err := duplosdk.NewClient("https://fixtures.duplocloud.invalid", "fixture-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.HTTPClient.Transport = duplosdk.NewFixtureTransport(bundle)
+
+	config := testConfig(t, t.TempDir(), map[string]string{"generate_import_blocks": "true"})
+	config.TFCodePath = filepath.Join(config.TargetDir, "terraform")
+	config.AppDir = filepath.Join(config.TFCodePath, config.AppProject)
+	configVars := filepath.Join(config.TargetDir, "config")
+	for _, dir := range []string{config.AppDir, configVars} {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	generators := []*GeneratorInfo{{Name: "services", Project: PROJECT_APP, Generator: &app.Services{}}}
+	service := &TfGeneratorService{Report: &ErrorReport{}}
+	err = service.starTFGenerationForProject(config, client, generators, config.AppDir, configVars, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(service.Report.Errors) != 1 || service.Report.Errors[0].Resource != "duplocloud_duplo_service.orders_api" {
+		t.Errorf("the orders-api service should be reported, got %v", service.Report.Errors)
+	}
+	files, err := readFiles(config.TargetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["terraform/app/svc-orders-api.tf"]; ok {
+		t.Errorf("the malformed service should not be written")
+	}
+	if _, ok := files["terraform/app/svc-billing-worker.tf"]; !ok {
+		t.Errorf("the other services should be written, got %v", sortedKeys(files))
+	}
+	if !strings.Contains(files["terraform/app/vars.tf"], "svc_billing_worker_docker_image") || strings.Contains(files["terraform/app/vars.tf"], "svc_orders_api_") {
+		t.Errorf("only the vars of the written services should be declared:\n%s", files["terraform/app/vars.tf"])
+	}
+	if !strings.Contains(files["terraform/app/imports.tf"], "to = duplocloud_duplo_service.billing_worker") || strings.Contains(files["terraform/app/imports.tf"], "orders_api") {
+		t.Errorf("only the written services should be imported:\n%s", files["terraform/app/imports.tf"])
+	}
+}
+
+func readFiles(root string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	return files, err
+}
+
+func copyTree(src, dst string) error {
+	files, err := readFiles(src)
+	if err != nil {
+		return err
+	}
+	for path, content := range files {
+		target := filepath.Join(dst, filepath.FromSlash(path))
+		err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(target, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
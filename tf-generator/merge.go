@@ -0,0 +1,566 @@
+package tfgenerator
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// GENERATION_BASE_DIR keeps a copy of the last generation in a generated folder. It is the base
+// of the three-way merge run by the update mode.
+const GENERATION_BASE_DIR = ".tfgen/base"
+
+// MERGE_CONFLICTS_FILE lists the conflicts of the last update next to the base.
+const MERGE_CONFLICTS_FILE = ".tfgen/conflicts.txt"
+
+// MERGE_CONFLICT_COMMENT starts the comments written next to conflicting code.
+const MERGE_CONFLICT_COMMENT = "# tfgen conflict:"
+
+// MergeConflict is a change made both to the code and in DuploCloud since the last generation. The
+// local code is kept.
+type MergeConflict struct {
+	File    string
+	Address string
+	Path    string
+	Message string
+}
+
+func (c MergeConflict) Error() string {
+	location := c.File
+	if len(c.Address) > 0 {
+		location += " " + c.Address
+	}
+	if len(c.Path) > 0 {
+		location += " " + c.Path
+	}
+	return location + ": " + c.Message
+}
+
+// SaveGenerationBase copies a generated folder into its GENERATION_BASE_DIR.
+func SaveGenerationBase(dir string) error {
+	files, err := readGeneratedTree(dir)
+	if err != nil {
+		return err
+	}
+	return writeGenerationBase(dir, files)
+}
+
+func writeGenerationBase(dir string, files map[string][]byte) error {
+	baseDir := filepath.Join(dir, GENERATION_BASE_DIR)
+	err := os.RemoveAll(baseDir)
+	if err != nil {
+		return err
+	}
+	return writeTree(baseDir, files)
+}
+
+// MergeGeneration updates the code in codeDir with a generation in newDir, using the generation
+// saved in the base of codeDir. Terraform files are merged per block and attribute, other files
+// as a whole:
+//
+//   - What changed in DuploCloud but not in the code is updated.
+//   - What changed in the code but not in DuploCloud is kept, like added blocks, attributes,
+//     files and comments or resources moved to another file.
+//   - What changed in both is a conflict. The code is kept with a comment starting with
+//     MERGE_CONFLICT_COMMENT. A file is written next to it with a .tfgen-new suffix.
+//
+// Without a base, everything which differs between the code and the generation is a conflict.
+func MergeGeneration(newDir, codeDir string) ([]MergeConflict, error) {
+	base, err := readGeneratedTree(filepath.Join(codeDir, GENERATION_BASE_DIR))
+	if err != nil {
+		return nil, err
+	}
+	code, err := readGeneratedTree(codeDir)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := readGeneratedTree(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[string][]byte{}
+	conflicts := []MergeConflict{}
+	seen := map[string]bool{}
+	for _, files := range []map[string][]byte{base, code, generated} {
+		for path := range files {
+			if filepath.Ext(path) == ".tf" {
+				seen[filepath.Dir(path)+string(filepath.Separator)] = true
+			}
+			seen[path] = true
+		}
+	}
+	paths := []string{}
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		switch {
+		case strings.HasSuffix(path, string(filepath.Separator)):
+			projectConflicts, err := mergeProject(filepath.Dir(path), base, code, generated, merged)
+			if err != nil {
+				return nil, err
+			}
+			conflicts = append(conflicts, projectConflicts...)
+		case filepath.Ext(path) != ".tf":
+			content, conflict := mergeFile(path, base, code, generated)
+			if content != nil {
+				merged[path] = content
+			}
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+				if newContent, ok := generated[path]; ok {
+					merged[path+".tfgen-new"] = newContent
+				}
+			}
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		a, b := conflicts[i], conflicts[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Path < b.Path
+	})
+
+	// Files of the code missing from the merge were removed from DuploCloud.
+	for path := range code {
+		if _, ok := merged[path]; !ok {
+			err = os.Remove(filepath.Join(codeDir, path))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err = writeTree(codeDir, merged)
+	if err != nil {
+		return nil, err
+	}
+	err = writeGenerationBase(codeDir, generated)
+	if err != nil {
+		return nil, err
+	}
+	return conflicts, writeMergeConflicts(codeDir, conflicts)
+}
+
+// mergeFile merges a file other than terraform code as a whole. It returns nil content when the
+// file is removed.
+func mergeFile(path string, base, code, generated map[string][]byte) ([]byte, *MergeConflict) {
+	baseContent, inBase := base[path]
+	codeContent, inCode := code[path]
+	newContent, inNew := generated[path]
+	switch {
+	case !inCode && !inBase:
+		return newContent, nil
+	case inCode && (!inNew && !inBase || inNew && bytes.Equal(codeContent, newContent)):
+		return codeContent, nil
+	case inBase && inCode && bytes.Equal(codeContent, baseContent):
+		// Unchanged in the code. A file removed from the generation is removed too.
+		return newContent, nil
+	case inBase && (!inNew && !inCode || inNew && bytes.Equal(newContent, baseContent)):
+		return codeContent, nil
+	case !inCode:
+		// Removed from the code but changed in DuploCloud. The removal is kept.
+		return nil, &MergeConflict{File: path, Message: "removed locally and changed in DuploCloud, the generated file is written with a .tfgen-new suffix"}
+	case !inNew:
+		return codeContent, &MergeConflict{File: path, Message: "changed locally and no longer generated, the local file is kept"}
+	}
+	return codeContent, &MergeConflict{File: path, Message: "changed locally and in DuploCloud, the local file is kept and the generated one is written with a .tfgen-new suffix"}
+}
+
+// hclBlockRef is a top level block of a project along with the file declaring it.
+type hclBlockRef struct {
+	file  string
+	block *hclwrite.Block
+}
+
+// mergeProject merges the blocks declared in the terraform files of a project folder.
+func mergeProject(project string, base, code, generated, merged map[string][]byte) ([]MergeConflict, error) {
+	baseFiles, err := parseProject(project, base)
+	if err != nil {
+		return nil, err
+	}
+	codeFiles, err := parseProject(project, code)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := parseProject(project, generated)
+	if err != nil {
+		return nil, err
+	}
+	baseBlocks, _ := indexBlocks(baseFiles)
+	codeBlocks, _ := indexBlocks(codeFiles)
+	newBlocks, newOrder := indexBlocks(newFiles)
+
+	conflicts := []MergeConflict{}
+	for _, key := range mergeKeys(newOrder, codeBlocks) {
+		baseRef, inBase := baseBlocks[key]
+		codeRef, inCode := codeBlocks[key]
+		newRef, inNew := newBlocks[key]
+		switch {
+		case inCode && inNew:
+			var baseBody *hclwrite.Body
+			if inBase {
+				baseBody = baseRef.block.Body()
+			}
+			for _, conflict := range mergeBody(codeRef.block.Body(), baseBody, newRef.block.Body(), "") {
+				conflict.File, conflict.Address = filepath.Join(project, codeRef.file), key
+				conflicts = append(conflicts, conflict)
+			}
+		case inCode && inBase:
+			if blockKey(codeRef.block) == blockKey(baseRef.block) {
+				codeFiles[codeRef.file].Body().RemoveBlock(codeRef.block)
+				continue
+			}
+			conflict := MergeConflict{File: filepath.Join(project, codeRef.file), Address: key, Message: "changed locally and removed from DuploCloud, the local block is kept"}
+			annotateBlock(codeRef.block, conflict.Message)
+			conflicts = append(conflicts, conflict)
+		case inNew && inBase:
+			if blockKey(newRef.block) != blockKey(baseRef.block) {
+				conflicts = append(conflicts, MergeConflict{File: filepath.Join(project, newRef.file), Address: key, Message: "removed locally and changed in DuploCloud, the removal is kept"})
+			}
+		case inNew:
+			file, ok := codeFiles[newRef.file]
+			if !ok {
+				file = hclwrite.NewEmptyFile()
+				codeFiles[newRef.file] = file
+			} else {
+				file.Body().AppendNewline()
+			}
+			newFiles[newRef.file].Body().RemoveBlock(newRef.block)
+			file.Body().AppendBlock(newRef.block)
+		}
+	}
+
+	for name, file := range codeFiles {
+		path := filepath.Join(project, name)
+		content := collapseBlankLines(hclwrite.Format(file.Bytes()))
+		if len(bytes.TrimSpace(content)) == 0 {
+			continue
+		}
+		// A file left as it was keeps its formatting since the generators do not always write it like fmt.
+		if original, ok := code[path]; ok && bytes.Equal(content, collapseBlankLines(hclwrite.Format(original))) {
+			content = original
+		}
+		merged[path] = content
+	}
+	return conflicts, nil
+}
+
+// mergeBody merges the attributes and nested blocks of a block into its code. A nil base is an
+// empty block.
+func mergeBody(code, base, generated *hclwrite.Body, prefix string) []MergeConflict {
+	conflicts := []MergeConflict{}
+	baseAttributes := map[string]*hclwrite.Attribute{}
+	if base != nil {
+		baseAttributes = base.Attributes()
+	}
+	codeAttributes, newAttributes := code.Attributes(), generated.Attributes()
+	names := []string{}
+	for _, attributes := range []map[string]*hclwrite.Attribute{newAttributes, codeAttributes, baseAttributes} {
+		for name := range attributes {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		baseValue := attributeKey(baseAttributes[name])
+		codeValue := attributeKey(codeAttributes[name])
+		newValue := attributeKey(newAttributes[name])
+		switch {
+		case codeValue == newValue || newValue == baseValue:
+		case codeValue == baseValue:
+			if newAttributes[name] == nil {
+				code.RemoveAttribute(name)
+			} else {
+				code.SetAttributeRaw(name, newAttributes[name].Expr().BuildTokens(nil))
+			}
+		case codeAttributes[name] == nil:
+			conflicts = append(conflicts, MergeConflict{Path: prefix + name, Message: "removed locally and changed in DuploCloud, the removal is kept"})
+		default:
+			message := "changed locally and removed from DuploCloud, the local value is kept"
+			if newAttributes[name] != nil {
+				message = fmt.Sprintf("changed locally and in DuploCloud to %s, the local value is kept", tokensText(newAttributes[name].Expr().BuildTokens(nil), " "))
+			}
+			annotateAttribute(code, name, message)
+			conflicts = append(conflicts, MergeConflict{Path: prefix + name, Message: message})
+		}
+	}
+
+	baseBlocks := map[string]*hclwrite.Block{}
+	if base != nil {
+		baseBlocks, _ = indexNestedBlocks(base)
+	}
+	codeBlocks, _ := indexNestedBlocks(code)
+	newBlocks, newOrder := indexNestedBlocks(generated)
+	for _, key := range mergeKeys(newOrder, codeBlocks) {
+		baseBlock, inBase := baseBlocks[key]
+		codeBlock, inCode := codeBlocks[key]
+		newBlock, inNew := newBlocks[key]
+		switch {
+		case inCode && inNew:
+			var baseBody *hclwrite.Body
+			if inBase {
+				baseBody = baseBlock.Body()
+			}
+			conflicts = append(conflicts, mergeBody(codeBlock.Body(), baseBody, newBlock.Body(), prefix+key+".")...)
+		case inCode && inBase:
+			if blockKey(codeBlock) == blockKey(baseBlock) {
+				code.RemoveBlock(codeBlock)
+				continue
+			}
+			message := "changed locally and removed from DuploCloud, the local block is kept"
+			annotateBlock(codeBlock, message)
+			conflicts = append(conflicts, MergeConflict{Path: prefix + key, Message: message})
+		case inNew && inBase:
+			if blockKey(newBlock) != blockKey(baseBlock) {
+				conflicts = append(conflicts, MergeConflict{Path: prefix + key, Message: "removed locally and changed in DuploCloud, the removal is kept"})
+			}
+		case inNew:
+			generated.RemoveBlock(newBlock)
+			code.AppendBlock(newBlock)
+		}
+	}
+	return conflicts
+}
+
+// mergeKeys returns the generated keys in order followed by the sorted keys only in the code.
+func mergeKeys[T any](generated []string, code map[string]T) []string {
+	keys := append([]string{}, generated...)
+	inGenerated := map[string]bool{}
+	for _, key := range generated {
+		inGenerated[key] = true
+	}
+	codeOnly := []string{}
+	for key := range code {
+		if !inGenerated[key] {
+			codeOnly = append(codeOnly, key)
+		}
+	}
+	sort.Strings(codeOnly)
+	return append(keys, codeOnly...)
+}
+
+// parseProject parses the terraform files of a project folder keyed by file name.
+func parseProject(project string, files map[string][]byte) (map[string]*hclwrite.File, error) {
+	parsed := map[string]*hclwrite.File{}
+	for path, content := range files {
+		if filepath.Dir(path) != project || filepath.Ext(path) != ".tf" {
+			continue
+		}
+		file, diags := hclwrite.ParseConfig(content, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		parsed[filepath.Base(path)] = file
+	}
+	return parsed, nil
+}
+
+// indexBlocks keys the top level blocks of a project by address, like
+// resource.duplocloud_s3_bucket.logs. The order follows the file names.
+func indexBlocks(files map[string]*hclwrite.File) (map[string]hclBlockRef, []string) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	blocks := map[string]hclBlockRef{}
+	order := []string{}
+	for _, name := range names {
+		keys, keyOrder := indexNestedBlocks(files[name].Body())
+		for _, key := range keyOrder {
+			if _, ok := blocks[key]; ok {
+				// Terraform rejects duplicate addresses. The first one is merged.
+				continue
+			}
+			blocks[key] = hclBlockRef{file: name, block: keys[key]}
+			order = append(order, key)
+		}
+	}
+	return blocks, order
+}
+
+// indexNestedBlocks keys blocks by type and labels. Repeated blocks get their index as a suffix
+// and import blocks the resource they import into.
+func indexNestedBlocks(body *hclwrite.Body) (map[string]*hclwrite.Block, []string) {
+	blocks := map[string]*hclwrite.Block{}
+	order := []string{}
+	counts := map[string]int{}
+	for _, block := range body.Blocks() {
+		key := strings.Join(append([]string{block.Type()}, block.Labels()...), ".")
+		if to := block.Body().GetAttribute("to"); block.Type() == "import" && to != nil {
+			key += "." + attributeKey(to)
+		}
+		if counts[key] > 0 {
+			key = fmt.Sprintf("%s[%d]", key, counts[key])
+		}
+		counts[strings.SplitN(key, "[", 2)[0]]++
+		blocks[key] = block
+		order = append(order, key)
+	}
+	return blocks, order
+}
+
+// attributeKey compares the values of attributes regardless of spacing and comments. A missing
+// attribute has an empty key.
+func attributeKey(attribute *hclwrite.Attribute) string {
+	if attribute == nil {
+		return ""
+	}
+	return "=" + tokensText(attribute.Expr().BuildTokens(nil), "")
+}
+
+func blockKey(block *hclwrite.Block) string {
+	return tokensText(block.BuildTokens(nil), "")
+}
+
+// tokensText joins tokens without newlines and comments, sep is written where the tokens were
+// spaced.
+func tokensText(tokens hclwrite.Tokens, sep string) string {
+	text := strings.Builder{}
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment:
+			continue
+		}
+		if token.SpacesBefore > 0 && text.Len() > 0 {
+			text.WriteString(sep)
+		}
+		text.Write(token.Bytes)
+	}
+	return text.String()
+}
+
+// annotateAttribute ends the line of an attribute with a conflict comment.
+func annotateAttribute(body *hclwrite.Body, name, message string) {
+	comment := MERGE_CONFLICT_COMMENT + " " + message
+	attribute := body.GetAttribute(name)
+	if strings.Contains(string(attribute.BuildTokens(nil).Bytes()), comment) {
+		return
+	}
+	tokens := attribute.Expr().BuildTokens(nil)
+	tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenComment, Bytes: []byte(comment), SpacesBefore: 1})
+	body.SetAttributeRaw(name, tokens)
+}
+
+// annotateBlock adds a conflict comment at the end of a block.
+func annotateBlock(block *hclwrite.Block, message string) {
+	comment := MERGE_CONFLICT_COMMENT + " " + message
+	if strings.Contains(string(block.BuildTokens(nil).Bytes()), comment) {
+		return
+	}
+	block.Body().AppendUnstructuredTokens(hclwrite.Tokens{
+		{Type: hclsyntax.TokenComment, Bytes: []byte(comment + "\n")},
+	})
+}
+
+// collapseBlankLines drops the blank lines left at the start of a file and where blocks were
+// removed so that blocks are separated by a single blank line. Heredocs are kept as they are.
+func collapseBlankLines(src []byte) []byte {
+	tokens, diags := hclsyntax.LexConfig(src, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return src
+	}
+	out := bytes.Buffer{}
+	start, newlines := 0, 2
+	for _, token := range tokens {
+		switch {
+		case token.Type == hclsyntax.TokenNewline && newlines >= 2:
+			out.Write(src[start:token.Range.Start.Byte])
+			start = token.Range.End.Byte
+		case token.Type == hclsyntax.TokenNewline:
+			newlines++
+		case token.Type == hclsyntax.TokenComment && bytes.HasSuffix(token.Bytes, []byte("\n")):
+			newlines = 1
+		case token.Type != hclsyntax.TokenEOF:
+			newlines = 0
+		}
+	}
+	out.Write(src[start:])
+	return out.Bytes()
+}
+
+// writeMergeConflicts lists the conflicts of an update in MERGE_CONFLICTS_FILE. The file is
+// removed when there are none.
+func writeMergeConflicts(codeDir string, conflicts []MergeConflict) error {
+	path := filepath.Join(codeDir, MERGE_CONFLICTS_FILE)
+	if len(conflicts) == 0 {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	text := strings.Builder{}
+	fmt.Fprintf(&text, "Update finished with %d conflict(s). The local code is kept. Resolve them and remove the %q comments.\n\n", len(conflicts), MERGE_CONFLICT_COMMENT)
+	for _, conflict := range conflicts {
+		fmt.Fprintf(&text, "- %s\n", conflict.Error())
+	}
+	return os.WriteFile(path, []byte(text.String()), 0644)
+}
+
+// readGeneratedTree reads the files of a generated folder keyed by relative path, Terraform
+// working files, state and the .tfgen folder are left out.
+func readGeneratedTree(root string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return files, nil
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (name == ".terraform" || name == ".tfgen") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name == ".terraform.lock.hcl" || strings.Contains(name, ".tfstate") || strings.HasSuffix(name, ".tfgen-new") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = content
+		return nil
+	})
+	return files, err
+}
+
+func writeTree(root string, files map[string][]byte) error {
+	for path, content := range files {
+		target := filepath.Join(root, path)
+		err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(target, content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
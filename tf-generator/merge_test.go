@@ -0,0 +1,206 @@
+package tfgenerator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var mergeBase = map[string]string{
+	"terraform/app/main.tf": `resource "duplocloud_duplo_service" "orders_api" {
+  name     = "orders-api"
+  replicas = 2
+  image    = "orders:1"
+  tags {
+    key = "team"
+    val = "orders"
+  }
+}
+
+resource "duplocloud_s3_bucket" "logs" {
+  name = "logs"
+}
+
+resource "duplocloud_aws_sqs_queue" "old" {
+  name = "old"
+}
+
+resource "duplocloud_aws_sqs_queue" "audit" {
+  name = "audit"
+}
+`,
+	"config/dev01/app.json": `{"replicas": 2}`,
+}
+
+// mergeCode is mergeBase edited by hand: replicas is changed and the service ignores it. The
+// bucket is moved to its own file, the audit queue deleted and a file added.
+var mergeCode = map[string]string{
+	"terraform/app/main.tf": `# Services of the orders team.
+resource "duplocloud_duplo_service" "orders_api" {
+  name     = "orders-api"
+  replicas = 5 # Scaled for the sale.
+  image    = "orders:1"
+  tags {
+    key = "team"
+    val = "orders"
+  }
+  lifecycle {
+    ignore_changes = [replicas]
+  }
+}
+
+resource "duplocloud_aws_sqs_queue" "old" {
+  name = "old"
+}
+`,
+	"terraform/app/storage.tf": `resource "duplocloud_s3_bucket" "logs" {
+  # Keeps the access logs.
+  name = "logs"
+}
+`,
+	"terraform/app/alerts.tf": `resource "duplocloud_aws_sns_topic" "alerts" {
+  name = "alerts"
+}
+`,
+	"config/dev01/app.json": `{"replicas": 2}`,
+}
+
+// mergeGenerated is the next generation: replicas, the image, a tag and the bucket name changed in
+// DuploCloud. The old queue was deleted, the audit queue changed and an events queue created.
+var mergeGenerated = map[string]string{
+	"terraform/app/main.tf": `resource "duplocloud_duplo_service" "orders_api" {
+  name     = "orders-api"
+  replicas = 3
+  image    = "orders:2"
+  tags {
+    key = "team"
+    val = "payments"
+  }
+}
+
+resource "duplocloud_s3_bucket" "logs" {
+  name = "logs-v2"
+}
+
+resource "duplocloud_aws_sqs_queue" "audit" {
+  name = "audit-v2"
+}
+
+resource "duplocloud_aws_sqs_queue" "events" {
+  name = "events"
+}
+`,
+	"config/dev01/app.json": `{"replicas": 3}`,
+}
+
+func writeMergeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, path), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeGeneration(t *testing.T) {
+	codeDir, newDir := t.TempDir(), t.TempDir()
+	writeMergeTree(t, filepath.Join(codeDir, GENERATION_BASE_DIR), mergeBase)
+	writeMergeTree(t, codeDir, mergeCode)
+	writeMergeTree(t, newDir, mergeGenerated)
+
+	conflicts, err := MergeGeneration(newDir, codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{}
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.Error())
+	}
+	expected := []string{
+		filepath.Join("terraform", "app", "main.tf") + ` resource.duplocloud_aws_sqs_queue.audit: removed locally and changed in DuploCloud, the removal is kept`,
+		filepath.Join("terraform", "app", "main.tf") + ` resource.duplocloud_duplo_service.orders_api replicas: changed locally and in DuploCloud to 3, the local value is kept`,
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got conflicts\n%s\nexpected\n%s", strings.Join(messages, "\n"), strings.Join(expected, "\n"))
+	}
+
+	files, err := readFiles(codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := files[filepath.Join("terraform", "app", "main.tf")]
+	for _, want := range []string{
+		"# Services of the orders team.",
+		`replicas = 5 ` + MERGE_CONFLICT_COMMENT + ` changed locally and in DuploCloud to 3, the local value is kept # Scaled for the sale.`,
+		`image    = "orders:2"`,
+		`val = "payments"`,
+		"ignore_changes = [replicas]",
+		`resource "duplocloud_aws_sqs_queue" "events"`,
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.tf is missing %q:\n%s", want, main)
+		}
+	}
+	for _, unwanted := range []string{`"old"`, `"audit`, `"duplocloud_s3_bucket"`, "}\n\n\n"} {
+		if strings.Contains(main, unwanted) {
+			t.Errorf("main.tf should not have %s:\n%s", unwanted, main)
+		}
+	}
+	storage := files[filepath.Join("terraform", "app", "storage.tf")]
+	if !strings.Contains(storage, "# Keeps the access logs.") || !strings.Contains(storage, `name = "logs-v2"`) {
+		t.Errorf("the moved bucket should be updated in place:\n%s", storage)
+	}
+	if _, ok := files[filepath.Join("terraform", "app", "alerts.tf")]; !ok {
+		t.Error("the added file should be kept")
+	}
+	if files[filepath.Join("config", "dev01", "app.json")] != `{"replicas": 3}` {
+		t.Error("a file unchanged in the code should be updated")
+	}
+	if files[filepath.Join(GENERATION_BASE_DIR, "terraform", "app", "main.tf")] != mergeGenerated["terraform/app/main.tf"] {
+		t.Error("the generation should become the base of the next update")
+	}
+	if !strings.Contains(files[MERGE_CONFLICTS_FILE], "2 conflict(s)") {
+		t.Errorf("conflicts should be listed in %s", MERGE_CONFLICTS_FILE)
+	}
+
+	// Updating again from the same generation keeps the code as it is.
+	conflicts, err = MergeGeneration(newDir, codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := readFiles(codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 || again[filepath.Join("terraform", "app", "main.tf")] != main {
+		t.Errorf("a second update should not change the code, got %v:\n%s", conflicts, again[filepath.Join("terraform", "app", "main.tf")])
+	}
+	if _, ok := again[MERGE_CONFLICTS_FILE]; ok {
+		t.Errorf("%s should be removed without conflicts", MERGE_CONFLICTS_FILE)
+	}
+}
+
+func TestMergeGenerationFileConflict(t *testing.T) {
+	codeDir, newDir := t.TempDir(), t.TempDir()
+	writeMergeTree(t, filepath.Join(codeDir, GENERATION_BASE_DIR), map[string]string{"scripts/plan.sh": "plan v1\n"})
+	writeMergeTree(t, codeDir, map[string]string{"scripts/plan.sh": "plan v1 with a local fix\n"})
+	writeMergeTree(t, newDir, map[string]string{"scripts/plan.sh": "plan v2\n"})
+
+	conflicts, err := MergeGeneration(newDir, codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := readFiles(codeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || files[filepath.Join("scripts", "plan.sh")] != "plan v1 with a local fix\n" || files[filepath.Join("scripts", "plan.sh.tfgen-new")] != "plan v2\n" {
+		t.Errorf("got %v with files %v", conflicts, files)
+	}
+}
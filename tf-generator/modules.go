@@ -0,0 +1,557 @@
+package tfgenerator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// MODULES_DIR holds the local modules of a project generated in module mode.
+const MODULES_DIR = "modules"
+
+// MOVED_FILE moves the resources of a project generated before module mode into their modules.
+const MOVED_FILE = "moved.tf"
+
+// moduleRootFiles are written for the whole project. They stay in the root module.
+var moduleRootFiles = []string{"main.tf", "vars.tf", "outputs.tf", "providers.tf", "backend.tf", "imports.tf", MOVED_FILE}
+
+var moduleNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// moduleFamily is a file of resources written by a generator, like a service with its load
+// balancer configs and params. It becomes a local module of the project.
+type moduleFamily struct {
+	name    string
+	file    string
+	src     []byte
+	body    *hclsyntax.Body
+	inputs  map[string]*moduleInput
+	outputs map[string]string
+	// dependsOn are the modules and root resources the family depends on outside of its module.
+	dependsOn map[string]bool
+	// keptDependsOn are the depends_on entries of a block left once the external ones are moved to
+	// the module, keyed by the block type and labels.
+	keptDependsOn map[string][]string
+}
+
+// moduleInput is a variable of a module set from the root module.
+type moduleInput struct {
+	value       string
+	typeName    string
+	description string
+	sensitive   bool
+}
+
+type moduleEdit struct {
+	start, end  int
+	replacement string
+}
+
+type moduleRootVar struct {
+	typeName  string
+	sensitive bool
+}
+
+// ModularizeProject moves every file of resources of a generated project into a local module under
+// MODULES_DIR, instantiated from a file of the same name in the root module. References to locals,
+// variables, data sources and resources outside of a module become typed inputs of the module, and
+// references to its resources from outside become outputs. It returns the new address of each
+// moved resource so that moved.tf tells terraform about them.
+func ModularizeProject(projectDir string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(projectDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	sources := map[string][]byte{}
+	bodies := map[string]*hclsyntax.Body{}
+	families := map[string]*moduleFamily{}
+	familyNames := []string{}
+	// owners maps the address of every block of a family onto its family.
+	owners := map[string]*moduleFamily{}
+	rootResources := map[string]bool{}
+	rootVars := map[string]moduleRootVar{}
+	rootLocals := map[string]string{}
+	requiredProviders := map[string]string{}
+	for _, path := range paths {
+		file := filepath.Base(path)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		body := hclFile.Body.(*hclsyntax.Body)
+		sources[file], bodies[file] = src, body
+
+		var family *moduleFamily
+		if isModuleFamily(file, body) {
+			family = &moduleFamily{
+				name:          moduleName(file),
+				file:          file,
+				src:           src,
+				body:          body,
+				inputs:        map[string]*moduleInput{},
+				outputs:       map[string]string{},
+				dependsOn:     map[string]bool{},
+				keptDependsOn: map[string][]string{},
+			}
+			families[file] = family
+			familyNames = append(familyNames, file)
+		}
+		for _, block := range body.Blocks {
+			switch {
+			case family != nil:
+				owners[blockAddress(block)] = family
+			case block.Type == "resource" && len(block.Labels) == 2:
+				rootResources[blockAddress(block)] = true
+			case block.Type == "variable" && len(block.Labels) == 1:
+				rootVar := moduleRootVar{typeName: "any"}
+				if attribute, ok := block.Body.Attributes["type"]; ok {
+					rootVar.typeName = exprSource(attribute.Expr, src)
+				}
+				if attribute, ok := block.Body.Attributes["sensitive"]; ok {
+					value, _ := attribute.Expr.Value(nil)
+					rootVar.sensitive = value.Type() == cty.Bool && value.True()
+				}
+				rootVars[block.Labels[0]] = rootVar
+			case block.Type == "locals":
+				for name, attribute := range block.Body.Attributes {
+					rootLocals[name] = exprTypeName(attribute.Expr)
+				}
+			case block.Type == "terraform":
+				for _, nested := range block.Body.Blocks {
+					if nested.Type == "required_providers" {
+						for name, attribute := range nested.Body.Attributes {
+							requiredProviders[name] = exprSource(attribute.Expr, src)
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(families) == 0 {
+		return map[string]string{}, nil
+	}
+
+	// The references of each file are rewritten. Families read the values of their inputs and the
+	// root module reads the outputs of the modules.
+	rootEdits := map[string][]moduleEdit{}
+	for _, path := range paths {
+		file := filepath.Base(path)
+		family := families[file]
+		src := sources[file]
+		for _, block := range bodies[file].Blocks {
+			if block.Type == "import" || block.Type == "moved" {
+				// Their addresses are moved with the resources. See the moved map.
+				continue
+			}
+			walkAttributes(block.Body, func(name string, attribute *hclsyntax.Attribute, nested bool) {
+				kept := []string{}
+				for _, traversal := range attribute.Expr.Variables() {
+					address, steps := referenceAddress(traversal, owners, rootResources)
+					if len(address) == 0 {
+						if name == "depends_on" && !nested {
+							kept = append(kept, string(traversal.SourceRange().SliceBytes(src)))
+						}
+						continue
+					}
+					owner := owners[address]
+					if family != nil && owner == family {
+						if name == "depends_on" && !nested {
+							kept = append(kept, string(traversal.SourceRange().SliceBytes(src)))
+						}
+						continue
+					}
+					if name == "depends_on" && !nested {
+						// A module can not depend on its inputs, so the module depends on the resource.
+						dependency := address
+						if owner != nil {
+							dependency = "module." + owner.name
+						}
+						if family != nil {
+							family.dependsOn[dependency] = true
+						} else {
+							rootEdits[file] = append(rootEdits[file], traversalEdit(traversal, steps, dependency))
+						}
+						continue
+					}
+					replacement := ""
+					if owner != nil {
+						output := outputName(address)
+						owner.outputs[output] = address
+						replacement = "module." + owner.name + "." + output
+					}
+					if family == nil {
+						if len(replacement) > 0 {
+							rootEdits[file] = append(rootEdits[file], traversalEdit(traversal, steps, replacement))
+						}
+						continue
+					}
+					input := family.addInput(address, replacement, rootVars, rootLocals)
+					rootEdits[file] = append(rootEdits[file], traversalEdit(traversal, steps, "var."+input))
+				}
+				if family != nil && name == "depends_on" && !nested {
+					family.keptDependsOn[blockAddress(block)] = kept
+				}
+			})
+		}
+	}
+
+	moved := map[string]string{}
+	for _, file := range familyNames {
+		family := families[file]
+		moduleDir := filepath.Join(projectDir, MODULES_DIR, family.name)
+		err = os.MkdirAll(moduleDir, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+		err = family.writeModule(moduleDir, applyEdits(family.src, rootEdits[file]), requiredProviders)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(filepath.Join(projectDir, file), family.moduleBlock(), 0644)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range family.body.Blocks {
+			if block.Type == "resource" {
+				moved[blockAddress(block)] = "module." + family.name + "." + blockAddress(block)
+			}
+		}
+	}
+	for _, path := range paths {
+		file := filepath.Base(path)
+		if _, ok := families[file]; ok || len(rootEdits[file]) == 0 {
+			continue
+		}
+		err = os.WriteFile(path, hclwrite.Format(applyEdits(sources[file], rootEdits[file])), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return moved, writeMovedBlocks(projectDir, moved)
+}
+
+// isModuleFamily reports whether a file only holds resources and data sources. Files of the whole
+// project like main.tf stay in the root module.
+func isModuleFamily(file string, body *hclsyntax.Body) bool {
+	for _, rootFile := range moduleRootFiles {
+		if file == rootFile {
+			return false
+		}
+	}
+	resources := 0
+	for _, block := range body.Blocks {
+		switch {
+		case block.Type == "resource" && len(block.Labels) == 2:
+			resources++
+		case block.Type == "data" && len(block.Labels) == 2:
+		default:
+			return false
+		}
+	}
+	return resources > 0
+}
+
+// moduleName is the name of the module of a file, like svc-orders-api for svc-orders-api.tf.
+func moduleName(file string) string {
+	name := moduleNameReplacer.ReplaceAllString(strings.TrimSuffix(file, ".tf"), "_")
+	if len(name) == 0 || !(name[0] == '_' || (name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z')) {
+		name = "m_" + name
+	}
+	return name
+}
+
+func blockAddress(block *hclsyntax.Block) string {
+	address := strings.Join(block.Labels, ".")
+	if block.Type == "data" {
+		address = "data." + address
+	}
+	return address
+}
+
+// referenceAddress returns what a traversal refers to, like local.tenant_id, var.region,
+// data.aws_region.current or a resource, and the number of its steps naming it. Other traversals
+// like each.value return no address.
+func referenceAddress(traversal hcl.Traversal, owners map[string]*moduleFamily, rootResources map[string]bool) (string, int) {
+	names := []string{}
+	for _, step := range traversal {
+		if len(names) == 3 {
+			break
+		}
+		if root, ok := step.(hcl.TraverseRoot); ok {
+			names = append(names, root.Name)
+		} else if attr, ok := step.(hcl.TraverseAttr); ok {
+			names = append(names, attr.Name)
+		} else {
+			break
+		}
+	}
+	switch {
+	case len(names) >= 2 && (names[0] == "local" || names[0] == "var"):
+		return names[0] + "." + names[1], 2
+	case len(names) >= 3 && names[0] == "data":
+		return strings.Join(names[:3], "."), 3
+	case len(names) >= 2:
+		address := names[0] + "." + names[1]
+		if _, ok := owners[address]; ok || rootResources[address] {
+			return address, 2
+		}
+	}
+	return "", 0
+}
+
+// walkAttributes calls fn with the attributes of a body and of its nested blocks.
+func walkAttributes(body *hclsyntax.Body, fn func(name string, attribute *hclsyntax.Attribute, nested bool)) {
+	var walk func(body *hclsyntax.Body, nested bool)
+	walk = func(body *hclsyntax.Body, nested bool) {
+		names := []string{}
+		for name := range body.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fn(name, body.Attributes[name], nested)
+		}
+		for _, block := range body.Blocks {
+			walk(block.Body, true)
+		}
+	}
+	walk(body, false)
+}
+
+func traversalEdit(traversal hcl.Traversal, steps int, replacement string) moduleEdit {
+	return moduleEdit{
+		start:       traversal[0].SourceRange().Start.Byte,
+		end:         traversal[steps-1].SourceRange().End.Byte,
+		replacement: replacement,
+	}
+}
+
+func applyEdits(src []byte, edits []moduleEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte{}, src...)
+	for _, edit := range edits {
+		out = append(out[:edit.start], append([]byte(edit.replacement), out[edit.end:]...)...)
+	}
+	return out
+}
+
+func exprSource(expr hclsyntax.Expression, src []byte) string {
+	return string(expr.Range().SliceBytes(src))
+}
+
+// exprTypeName guesses the type of a local from its expression. Locals which are not literals are
+// of any type.
+func exprTypeName(expr hclsyntax.Expression) string {
+	switch expr := expr.(type) {
+	case *hclsyntax.TemplateExpr:
+		return "string"
+	case *hclsyntax.LiteralValueExpr:
+		switch expr.Val.Type() {
+		case cty.String:
+			return "string"
+		case cty.Number:
+			return "number"
+		case cty.Bool:
+			return "bool"
+		}
+	}
+	return "any"
+}
+
+// outputName is the output of a module returning one of its resources or data sources.
+func outputName(address string) string {
+	return strings.ReplaceAll(address, ".", "_")
+}
+
+// addInput declares the input reading a value from outside of the module and returns its name,
+// value is set when the value is the output of another module.
+func (f *moduleFamily) addInput(address, value string, rootVars map[string]moduleRootVar, rootLocals map[string]string) string {
+	kind, name, _ := strings.Cut(address, ".")
+	input := &moduleInput{value: address, typeName: "any"}
+	switch kind {
+	case "local":
+		input.typeName = rootLocals[name]
+		if len(input.typeName) == 0 {
+			input.typeName = "any"
+		}
+		input.description = "Value of local." + name + " of the root module."
+	case "var":
+		if rootVar, ok := rootVars[name]; ok {
+			input.typeName, input.sensitive = rootVar.typeName, rootVar.sensitive
+		}
+		input.description = "Value of var." + name + " of the root module."
+	default:
+		name = outputName(address)
+		if kind == "data" {
+			input.description = "The " + strings.TrimPrefix(address, "data.") + " data source."
+		} else {
+			input.description = "The " + address + " resource."
+		}
+		if len(value) > 0 {
+			input.value = value
+		}
+	}
+	// A local and a variable of the same name are told apart by their kind.
+	if existing, ok := f.inputs[name]; ok && existing.value != input.value {
+		name = kind + "_" + name
+	}
+	f.inputs[name] = input
+	return name
+}
+
+// writeModule writes main.tf, vars.tf, outputs.tf and versions.tf of the module of a family.
+func (f *moduleFamily) writeModule(moduleDir string, src []byte, requiredProviders map[string]string) error {
+	hclFile, diags := hclwrite.ParseConfig(src, f.file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("error while moving %s into a module: %s", f.file, diags)
+	}
+	providers := map[string]bool{}
+	for _, block := range hclFile.Body().Blocks() {
+		providers[strings.Split(block.Labels()[0], "_")[0]] = true
+		key := strings.Join(block.Labels(), ".")
+		if block.Type() == "data" {
+			key = "data." + key
+		}
+		kept, ok := f.keptDependsOn[key]
+		if !ok {
+			continue
+		}
+		if len(kept) == 0 {
+			block.Body().RemoveAttribute("depends_on")
+			continue
+		}
+		tokens := []hclwrite.Tokens{}
+		for _, dependency := range kept {
+			tokens = append(tokens, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(dependency)}})
+		}
+		block.Body().SetAttributeRaw("depends_on", hclwrite.TokensForTuple(tokens))
+	}
+	err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), hclwrite.Format(hclFile.Bytes()), 0644)
+	if err != nil {
+		return err
+	}
+
+	varsFile := hclwrite.NewEmptyFile()
+	for _, name := range sortedKeys(f.inputs) {
+		input := f.inputs[name]
+		varBody := varsFile.Body().AppendNewBlock("variable", []string{name}).Body()
+		varBody.SetAttributeValue("description", cty.StringVal(input.description))
+		varBody.SetAttributeRaw("type", hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(input.typeName)}})
+		if input.sensitive {
+			varBody.SetAttributeValue("sensitive", cty.True)
+		}
+	}
+	err = writeModuleFile(filepath.Join(moduleDir, "vars.tf"), varsFile)
+	if err != nil {
+		return err
+	}
+
+	outputsFile := hclwrite.NewEmptyFile()
+	for _, name := range sortedKeys(f.outputs) {
+		address := f.outputs[name]
+		outputBody := outputsFile.Body().AppendNewBlock("output", []string{name}).Body()
+		outputBody.SetAttributeRaw("value", hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(address)}})
+		if strings.HasPrefix(address, "data.") {
+			outputBody.SetAttributeValue("description", cty.StringVal("The "+strings.TrimPrefix(address, "data.")+" data source."))
+		} else {
+			outputBody.SetAttributeValue("description", cty.StringVal("The "+address+" resource."))
+		}
+	}
+	err = writeModuleFile(filepath.Join(moduleDir, "outputs.tf"), outputsFile)
+	if err != nil {
+		return err
+	}
+
+	// Providers out of the hashicorp namespace must be required by the module as well.
+	versionsFile := hclwrite.NewEmptyFile()
+	requiredBody := versionsFile.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("required_providers", nil).Body()
+	required := 0
+	for _, name := range sortedKeys(providers) {
+		if source, ok := requiredProviders[name]; ok {
+			requiredBody.SetAttributeRaw(name, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(source)}})
+			required++
+		}
+	}
+	if required == 0 {
+		return os.RemoveAll(filepath.Join(moduleDir, "versions.tf"))
+	}
+	return writeModuleFile(filepath.Join(moduleDir, "versions.tf"), versionsFile)
+}
+
+// writeModuleFile writes a file of a module, or removes it when it has no block.
+func writeModuleFile(path string, file *hclwrite.File) error {
+	if len(file.Body().Blocks()) == 0 {
+		return os.RemoveAll(path)
+	}
+	return os.WriteFile(path, hclwrite.Format(file.Bytes()), 0644)
+}
+
+// moduleBlock instantiates the module of a family in the root module.
+func (f *moduleFamily) moduleBlock() []byte {
+	hclFile := hclwrite.NewEmptyFile()
+	moduleBody := hclFile.Body().AppendNewBlock("module", []string{f.name}).Body()
+	moduleBody.SetAttributeValue("source", cty.StringVal("./"+MODULES_DIR+"/"+f.name))
+	for _, name := range sortedKeys(f.inputs) {
+		moduleBody.SetAttributeRaw(name, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(f.inputs[name].value)}})
+	}
+	if len(f.dependsOn) > 0 {
+		tokens := []hclwrite.Tokens{}
+		for _, dependency := range sortedKeys(f.dependsOn) {
+			tokens = append(tokens, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(dependency)}})
+		}
+		moduleBody.SetAttributeRaw("depends_on", hclwrite.TokensForTuple(tokens))
+	}
+	return hclwrite.Format(hclFile.Bytes())
+}
+
+// writeMovedBlocks writes moved.tf so that the state of a project generated before module mode
+// follows its resources into their modules. The moved blocks already in the file are kept.
+func writeMovedBlocks(projectDir string, moved map[string]string) error {
+	path := filepath.Join(projectDir, MOVED_FILE)
+	all := map[string]string{}
+	if src, err := os.ReadFile(path); err == nil {
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return diags
+		}
+		for _, block := range hclFile.Body.(*hclsyntax.Body).Blocks {
+			from, hasFrom := block.Body.Attributes["from"]
+			to, hasTo := block.Body.Attributes["to"]
+			if block.Type == "moved" && hasFrom && hasTo {
+				all[exprSource(from.Expr, src)] = exprSource(to.Expr, src)
+			}
+		}
+	}
+	for from, to := range moved {
+		all[from] = to
+	}
+	hclFile := hclwrite.NewEmptyFile()
+	for _, from := range sortedKeys(all) {
+		movedBody := hclFile.Body().AppendNewBlock("moved", nil).Body()
+		movedBody.SetAttributeRaw("from", hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(from)}})
+		movedBody.SetAttributeRaw("to", hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(all[from])}})
+	}
+	return writeModuleFile(path, hclFile)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
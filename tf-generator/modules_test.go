@@ -0,0 +1,156 @@
+package tfgenerator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// TestGenerateModules checks that module mode moves each file of resources into a module, and that
+// every module gets the inputs it reads and has the outputs read from it.
+func TestGenerateModules(t *testing.T) {
+	targetDir := t.TempDir()
+	config := testConfig(t, targetDir, map[string]string{"module_mode": "true"})
+	client, _ := testClient(t)
+	generateFixtureTenant(t, config, client)
+
+	appDir := filepath.Join(targetDir, "terraform", "app")
+	files, err := readFiles(appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `module "svc-orders-api" {
+  source                                 = "./modules/svc-orders-api"
+  cert_arn                               = local.cert_arn
+  duplocloud_k8_config_map_orders_config = module.k8s-cm-orders-config.duplocloud_k8_config_map_orders_config
+  duplocloud_k8_secret_orders_db         = module.k8s-secret-orders-db.duplocloud_k8_secret_orders_db
+  svc_orders_api_docker_image            = var.svc_orders_api_docker_image
+  tenant_id                              = local.tenant_id
+  tenant_name                            = local.tenant_name
+}
+`
+	if files["svc-orders-api.tf"] != expected {
+		t.Errorf("got svc-orders-api.tf\n%s\nexpected\n%s", files["svc-orders-api.tf"], expected)
+	}
+	service := files[filepath.Join(MODULES_DIR, "svc-orders-api", "main.tf")]
+	for _, reference := range []string{"tenant_id                            = var.tenant_id", "${var.duplocloud_k8_secret_orders_db.secret_name}"} {
+		if !strings.Contains(service, reference) {
+			t.Errorf("the module should read %s:\n%s", reference, service)
+		}
+	}
+	if strings.Contains(service, "local.") {
+		t.Errorf("a module can not read the locals of the root module:\n%s", service)
+	}
+	moved := "moved {\n  from = duplocloud_duplo_service.orders_api\n  to   = module.svc-orders-api.duplocloud_duplo_service.orders_api\n}\n"
+	if !strings.Contains(files[MOVED_FILE], moved) {
+		t.Errorf("got moved.tf\n%s", files[MOVED_FILE])
+	}
+
+	addresses, err := common.ListResourceAddresses(appDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !common.Contains(addresses, "module.svc-orders-api.duplocloud_duplo_service.orders_api") {
+		t.Errorf("the resources of the modules should be listed, got %v", addresses)
+	}
+
+	for _, project := range []string{config.TenantProject, config.AwsServicesProject, config.AppProject} {
+		checkModules(t, filepath.Join(targetDir, "terraform", project))
+	}
+}
+
+// checkModules checks that the module blocks of a project set every variable of their module, and
+// that the module outputs read by the project exist.
+func checkModules(t *testing.T, projectDir string) {
+	t.Helper()
+	root := parseTfDir(t, projectDir)
+	modules := 0
+	for _, block := range root {
+		if block.Type != "module" {
+			continue
+		}
+		modules++
+		name := block.Labels[0]
+		moduleDir := filepath.Join(projectDir, MODULES_DIR, name)
+		variables, outputs := map[string]bool{}, map[string]bool{}
+		moduleBlocks := parseTfDir(t, moduleDir)
+		for _, moduleBlock := range moduleBlocks {
+			switch moduleBlock.Type {
+			case "variable":
+				variables[moduleBlock.Labels[0]] = true
+			case "output":
+				outputs[moduleBlock.Labels[0]] = true
+			}
+		}
+		for argument := range block.Body.Attributes {
+			if argument != "source" && argument != "depends_on" && !variables[argument] {
+				t.Errorf("module %s of %s has no variable %s", name, projectDir, argument)
+			}
+		}
+		for variable := range variables {
+			if _, ok := block.Body.Attributes[variable]; !ok {
+				t.Errorf("module %s of %s is missing variable %s", name, projectDir, variable)
+			}
+		}
+		for _, moduleBlock := range moduleBlocks {
+			walkAttributes(moduleBlock.Body, func(_ string, attribute *hclsyntax.Attribute, _ bool) {
+				for _, traversal := range attribute.Expr.Variables() {
+					switch traversal.RootName() {
+					case "local", "module":
+						t.Errorf("module %s of %s reads %s of the root module", name, projectDir, traversal.RootName())
+					case "var":
+						variable := traversal[1].(hcl.TraverseAttr).Name
+						if !variables[variable] {
+							t.Errorf("module %s of %s reads undeclared var.%s", name, projectDir, variable)
+						}
+					}
+				}
+			})
+		}
+		for _, rootBlock := range root {
+			if rootBlock.Type == "moved" || rootBlock.Type == "import" {
+				continue
+			}
+			walkAttributes(rootBlock.Body, func(_ string, attribute *hclsyntax.Attribute, _ bool) {
+				for _, traversal := range attribute.Expr.Variables() {
+					if traversal.RootName() != "module" || len(traversal) < 3 || traversal[1].(hcl.TraverseAttr).Name != name {
+						continue
+					}
+					output := traversal[2].(hcl.TraverseAttr).Name
+					if !outputs[output] {
+						t.Errorf("module %s of %s has no output %s", name, projectDir, output)
+					}
+				}
+			})
+		}
+	}
+	if modules == 0 {
+		t.Errorf("%s should have modules", projectDir)
+	}
+}
+
+func parseTfDir(t *testing.T, dir string) []*hclsyntax.Block {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []*hclsyntax.Block{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		blocks = append(blocks, hclFile.Body.(*hclsyntax.Body).Blocks...)
+	}
+	return blocks
+}
@@ -0,0 +1,158 @@
+package tfgenerator
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ReferenceLiterals replaces the ARNs of the resources and the ARN and ID of the KMS keys of the
+// context written in the project, or held by its variables, with references to them. The variables
+// are removed.
+func ReferenceLiterals(projectDir string, tfContext *common.TFContext) error {
+	// refs maps each ARN or ID onto its reference.
+	refs := map[string]string{}
+	for _, key := range tfContext.KmsKeys {
+		if len(key.KeyArn) > 0 {
+			refs[key.KeyArn] = key.ArnRef
+		}
+		if len(key.KeyId) > 0 {
+			refs[key.KeyId] = key.IdRef
+		}
+	}
+	for arn, ref := range tfContext.ArnRefs {
+		refs[arn] = ref
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	values := sortedKeys(refs)
+	// The ARN of a key contains its ID, so longer values are matched first.
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for i, value := range values {
+		values[i] = regexp.QuoteMeta(value)
+	}
+	pattern := regexp.MustCompile(strings.Join(values, "|"))
+
+	varRefs := map[string]string{}
+	for _, inputVar := range tfContext.InputVars {
+		if ref, ok := refs[inputVar.DefaultVal]; ok && !inputVar.Sensitive && inputVar.ConfigVal == nil {
+			varRefs[inputVar.Name] = ref
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(projectDir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	referenced := map[string]bool{}
+	// The files are only written once all of them are parsed.
+	edited := map[string][]byte{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hclFile, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			return diags
+		}
+		edits := []moduleEdit{}
+		for _, block := range hclFile.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == "variable" || block.Type == "output" {
+				continue
+			}
+			// A resource does not reference itself.
+			address := blockAddress(block) + "."
+			walkAttributes(block.Body, func(_ string, attribute *hclsyntax.Attribute, _ bool) {
+				hclsyntax.VisitAll(attribute.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+					switch expr := node.(type) {
+					case *hclsyntax.ScopeTraversalExpr:
+						if len(expr.Traversal) == 2 && expr.Traversal.RootName() == "var" {
+							name := expr.Traversal[1].(hcl.TraverseAttr).Name
+							if ref, ok := varRefs[name]; ok && !strings.HasPrefix(ref, address) {
+								edits = append(edits, traversalEdit(expr.Traversal, 2, ref))
+								referenced[name] = true
+							}
+						}
+					case *hclsyntax.TemplateExpr:
+						edits = append(edits, literalEdits(expr, src, refs, pattern, address)...)
+					}
+					return nil
+				})
+			})
+		}
+		if len(edits) > 0 {
+			edited[path] = applyEdits(src, edits)
+		}
+	}
+	for _, path := range sortedKeys(edited) {
+		err = os.WriteFile(path, edited[path], 0644)
+		if err != nil {
+			return err
+		}
+	}
+	inputVars := []common.VarConfig{}
+	for _, inputVar := range tfContext.InputVars {
+		if !referenced[inputVar.Name] {
+			inputVars = append(inputVars, inputVar)
+		}
+	}
+	tfContext.InputVars = inputVars
+	return nil
+}
+
+// literalEdits replaces a string which is an ARN or ID of the refs with its reference, and
+// interpolates the reference where the string contains it.
+func literalEdits(expr *hclsyntax.TemplateExpr, src []byte, refs map[string]string, pattern *regexp.Regexp, address string) []moduleEdit {
+	if len(expr.Parts) == 1 {
+		if literal, ok := expr.Parts[0].(*hclsyntax.LiteralValueExpr); ok && literal.Val.Type() == cty.String {
+			if ref, ok := refs[literal.Val.AsString()]; ok {
+				if strings.HasPrefix(ref, address) {
+					return nil
+				}
+				return []moduleEdit{{
+					start:       expr.SrcRange.Start.Byte,
+					end:         expr.SrcRange.End.Byte,
+					replacement: ref,
+				}}
+			}
+		}
+	}
+	edits := []moduleEdit{}
+	for _, part := range expr.Parts {
+		literal, ok := part.(*hclsyntax.LiteralValueExpr)
+		if !ok {
+			continue
+		}
+		text := literal.SrcRange.SliceBytes(src)
+		start := literal.SrcRange.Start.Byte
+		for _, match := range pattern.FindAllIndex(text, -1) {
+			// The ARN of a function is not replaced in the ARN of a function whose name starts alike.
+			if (match[0] > 0 && isArnChar(text[match[0]-1])) || (match[1] < len(text) && isArnChar(text[match[1]])) {
+				continue
+			}
+			ref := refs[string(text[match[0]:match[1]])]
+			if strings.HasPrefix(ref, address) {
+				continue
+			}
+			edits = append(edits, moduleEdit{
+				start:       start + match[0],
+				end:         start + match[1],
+				replacement: "${" + ref + "}",
+			})
+		}
+	}
+	return edits
+}
+
+func isArnChar(c byte) bool {
+	return isAddressChar(c) || c == '.'
+}
@@ -0,0 +1,107 @@
+package tfgenerator
+
+import (
+	"tenant-terraform-generator/tf-generator/common"
+	"testing"
+)
+
+// TestReferenceLiterals checks that literals and variables holding an ARN of a resource or the ARN or
+// ID of a key are replaced by its references, and that the variables are removed.
+func TestReferenceLiterals(t *testing.T) {
+	const (
+		tenantArn = "arn:aws:kms:us-west-2:123456789012:key/11111111-2222-3333-4444-555555555555"
+		tenantId  = "11111111-2222-3333-4444-555555555555"
+		planArn   = "arn:aws:kms:us-west-2:123456789012:key/0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0"
+		// functionArn is the start of the ARN of another function.
+		functionArn = "arn:aws:lambda:us-west-2:123456789012:function:duploservices-dev01-resize"
+	)
+	projectDir := t.TempDir()
+	writeMergeTree(t, projectDir, map[string]string{
+		"redis-sessions.tf": `resource "duplocloud_ecache_instance" "sessions" {
+  kms_key_id = "` + tenantId + `"
+}
+`,
+		"rds-orders.tf": `resource "duplocloud_rds_instance" "orders" {
+  kms_key_id = var.rds_orders_kms_key_id
+  performance_insights {
+    kms_key_id = var.rds_orders_performance_insights_kms_key_id
+  }
+}
+`,
+		"s3-assets.tf": `resource "duplocloud_s3_bucket" "assets" {
+  policy = "{\"Resource\":\"` + tenantArn + `\",\"Key\":\"` + tenantId + `\"}"
+}
+`,
+		"lf-resize.tf": `resource "duplocloud_aws_lambda_function" "resize" {
+  description = "Called by ` + functionArn + `"
+}
+resource "duplocloud_aws_lambda_permission" "resize" {
+  source_arn = "` + functionArn + `"
+  qualifier  = "` + functionArn + `:live"
+  other      = "` + functionArn + `-v2"
+}
+`,
+	})
+	tfContext := &common.TFContext{
+		InputVars: []common.VarConfig{
+			{Name: "rds_orders_kms_key_id", DefaultVal: planArn, TypeVal: "string"},
+			{Name: "rds_orders_performance_insights_kms_key_id", DefaultVal: "", TypeVal: "string"},
+		},
+		ArnRefs: map[string]string{
+			functionArn: "duplocloud_aws_lambda_function.resize.arn",
+		},
+		KmsKeys: []common.KmsKeyConfig{
+			{
+				KeyArn: tenantArn,
+				KeyId:  tenantId,
+				ArnRef: "data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn",
+				IdRef:  "data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id",
+			},
+			{
+				KeyArn: planArn,
+				ArnRef: "data.duplocloud_plan_kms.nonprod_kms.arn",
+			},
+		},
+	}
+	err := ReferenceLiterals(projectDir, tfContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := readFiles(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]string{
+		"redis-sessions.tf": `resource "duplocloud_ecache_instance" "sessions" {
+  kms_key_id = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id
+}
+`,
+		"rds-orders.tf": `resource "duplocloud_rds_instance" "orders" {
+  kms_key_id = data.duplocloud_plan_kms.nonprod_kms.arn
+  performance_insights {
+    kms_key_id = var.rds_orders_performance_insights_kms_key_id
+  }
+}
+`,
+		"s3-assets.tf": `resource "duplocloud_s3_bucket" "assets" {
+  policy = "{\"Resource\":\"${data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn}\",\"Key\":\"${data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id}\"}"
+}
+`,
+		"lf-resize.tf": `resource "duplocloud_aws_lambda_function" "resize" {
+  description = "Called by ` + functionArn + `"
+}
+resource "duplocloud_aws_lambda_permission" "resize" {
+  source_arn = duplocloud_aws_lambda_function.resize.arn
+  qualifier  = "${duplocloud_aws_lambda_function.resize.arn}:live"
+  other      = "` + functionArn + `-v2"
+}
+`,
+	} {
+		if files[path] != expected {
+			t.Errorf("got %s\n%s\nexpected\n%s", path, files[path], expected)
+		}
+	}
+	if len(tfContext.InputVars) != 1 || tfContext.InputVars[0].Name != "rds_orders_performance_insights_kms_key_id" {
+		t.Errorf("only the variable holding a key should be removed, got %v", tfContext.InputVars)
+	}
+}
@@ -0,0 +1,307 @@
+package tfgenerator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"tenant-terraform-generator/tf-generator/common"
+	"unicode/utf8"
+)
+
+const (
+	SECRET_SCAN_REPORT_FILE      = "secret-scan.txt"
+	SECRET_SCAN_REPORT_JSON_FILE = "secret-scan.json"
+	// SECRET_SCAN_REDACTED replaces the values found by a redacting scan.
+	SECRET_SCAN_REDACTED = "REDACTED"
+)
+
+// Strings of the base64 alphabet at least this long are checked for entropy. Those with a higher
+// entropy in bits per character are reported.
+const (
+	secretScanMinLength = 20
+	secretScanEntropy   = 4.0
+	// Base64 blobs at least this long are decoded and their text scanned.
+	secretScanMinBase64Length = 32
+)
+
+// secretDetector finds a known key format.
+type secretDetector struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretDetectors = []secretDetector{
+	{"private-key", regexp.MustCompile(`-----BEGIN[A-Z ]* PRIVATE KEY-----(?s:.*?)-----END[A-Z ]* PRIVATE KEY-----`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+}
+
+// secretScanIgnored are values which look random but are public, like certificates.
+var secretScanIgnored = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN CERTIFICATE-----(?s:.*?)-----END CERTIFICATE-----`),
+	regexp.MustCompile(`-----BEGIN PUBLIC KEY-----(?s:.*?)-----END PUBLIC KEY-----`),
+	regexp.MustCompile(`\b(?:ssh-rsa|ssh-ed25519|ecdsa-sha2-nistp[0-9]+) AAAA[A-Za-z0-9+/=]+`),
+}
+
+var (
+	secretScanCandidate = regexp.MustCompile(`[A-Za-z0-9+/_-]{20,}={0,2}`)
+	secretScanBase64    = regexp.MustCompile(`[A-Za-z0-9+/]{32,}={0,2}`)
+	secretScanUUID      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// SecretFinding is a value looking like a secret in a generated file. Preview only shows the start
+// of the value.
+type SecretFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Detector string `json:"detector"`
+	Preview  string `json:"preview"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+func (f SecretFinding) String() string {
+	message := fmt.Sprintf("%s:%d: %s %s", f.File, f.Line, f.Detector, f.Preview)
+	if f.Redacted {
+		message += " (redacted)"
+	}
+	return message
+}
+
+// SecretScanner looks for secrets in the generated files. The values matching one of Allow are
+// ignored.
+type SecretScanner struct {
+	Allow  []*regexp.Regexp
+	Redact bool
+}
+
+// NewSecretScanner compiles the allowed values of a scan.
+func NewSecretScanner(allow []string, redact bool) (*SecretScanner, error) {
+	scanner := &SecretScanner{Redact: redact}
+	for _, pattern := range allow {
+		allowRegex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret scan allow pattern %q: %s", pattern, err)
+		}
+		scanner.Allow = append(scanner.Allow, allowRegex)
+	}
+	return scanner, nil
+}
+
+// ScanDir scans every file of a generated folder. Redacted files are written back. The reports of
+// the generator and the update base are skipped.
+func (s *SecretScanner) ScanDir(dir string) ([]SecretFinding, error) {
+	files, err := readGeneratedTree(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for path := range files {
+		switch filepath.Base(path) {
+		case SECRET_SCAN_REPORT_FILE, SECRET_SCAN_REPORT_JSON_FILE, common.SECRETS_REPORT_FILE, common.SECRETS_REPORT_JSON_FILE:
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	findings := []SecretFinding{}
+	for _, path := range paths {
+		if !utf8.Valid(files[path]) {
+			continue
+		}
+		content, fileFindings := s.scan(string(files[path]), "")
+		for i := range fileFindings {
+			fileFindings[i].File = path
+		}
+		findings = append(findings, fileFindings...)
+		if s.Redact && len(fileFindings) > 0 {
+			err = os.WriteFile(filepath.Join(dir, path), []byte(content), 0644)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return findings, nil
+}
+
+type secretMatch struct {
+	start, end int
+	detector   string
+	// replacement of a match which is not a finding, like a base64 blob holding secrets or an
+	// ignored value.
+	replacement string
+}
+
+// scan finds the secrets of a text and returns it redacted when the scanner redacts, Base64 blobs
+// of text like user data are decoded and scanned as well.
+func (s *SecretScanner) scan(text, suffix string) (string, []SecretFinding) {
+	matches := []secretMatch{}
+	overlaps := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.end && m.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ignored := range secretScanIgnored {
+		for _, loc := range ignored.FindAllStringIndex(text, -1) {
+			matches = append(matches, secretMatch{start: loc[0], end: loc[1], replacement: text[loc[0]:loc[1]]})
+		}
+	}
+	for _, detector := range secretDetectors {
+		for _, loc := range detector.pattern.FindAllStringIndex(text, -1) {
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			if s.allowed(text[loc[0]:loc[1]]) {
+				// Kept as a whole so that its parts are not reported either.
+				matches = append(matches, secretMatch{start: loc[0], end: loc[1], replacement: text[loc[0]:loc[1]]})
+			} else {
+				matches = append(matches, secretMatch{start: loc[0], end: loc[1], detector: detector.name + suffix})
+			}
+		}
+	}
+	decoded := map[int][]SecretFinding{}
+	if len(suffix) == 0 {
+		for _, loc := range secretScanBase64.FindAllStringIndex(text, -1) {
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			blob, err := base64.StdEncoding.DecodeString(text[loc[0]:loc[1]])
+			if err != nil || !isText(blob) {
+				continue
+			}
+			redacted, blobFindings := s.scan(string(blob), " (base64)")
+			// A blob of text is not a secret by itself. Only what it holds is reported.
+			matches = append(matches, secretMatch{start: loc[0], end: loc[1], replacement: base64.StdEncoding.EncodeToString([]byte(redacted))})
+			if len(blobFindings) > 0 {
+				decoded[loc[0]] = blobFindings
+			}
+		}
+	}
+	for _, loc := range secretScanCandidate.FindAllStringIndex(text, -1) {
+		value := text[loc[0]:loc[1]]
+		if !overlaps(loc[0], loc[1]) && highEntropy(value) && !s.allowed(value) {
+			matches = append(matches, secretMatch{start: loc[0], end: loc[1], detector: "high-entropy" + suffix})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	findings := []SecretFinding{}
+	redacted := strings.Builder{}
+	last := 0
+	for _, m := range matches {
+		line := strings.Count(text[:m.start], "\n") + 1
+		redacted.WriteString(text[last:m.start])
+		last = m.end
+		if len(m.detector) == 0 {
+			for _, finding := range decoded[m.start] {
+				finding.Line, finding.Redacted = line, s.Redact
+				findings = append(findings, finding)
+			}
+			if s.Redact {
+				redacted.WriteString(m.replacement)
+			} else {
+				redacted.WriteString(text[m.start:m.end])
+			}
+			continue
+		}
+		findings = append(findings, SecretFinding{Line: line, Detector: m.detector, Preview: previewSecret(text[m.start:m.end]), Redacted: s.Redact})
+		if s.Redact {
+			redacted.WriteString(SECRET_SCAN_REDACTED)
+		} else {
+			redacted.WriteString(text[m.start:m.end])
+		}
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String(), findings
+}
+
+func (s *SecretScanner) allowed(value string) bool {
+	if value == SECRET_SCAN_REDACTED {
+		return true
+	}
+	for _, allow := range s.Allow {
+		if allow.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// highEntropy reports whether a string looks random. It must mix upper case, lower case and digits
+// so that names and hex ids like ami-0abcdef1234567890 are not reported.
+func highEntropy(value string) bool {
+	if len(value) < secretScanMinLength || secretScanUUID.MatchString(value) {
+		return false
+	}
+	upper, lower, digit := false, false, false
+	counts := map[rune]int{}
+	for _, r := range value {
+		upper = upper || (r >= 'A' && r <= 'Z')
+		lower = lower || (r >= 'a' && r <= 'z')
+		digit = digit || (r >= '0' && r <= '9')
+		counts[r]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(value))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy > secretScanEntropy
+}
+
+// isText reports whether decoded base64 is printable text, like a user data script.
+func isText(content []byte) bool {
+	if !utf8.Valid(content) {
+		return false
+	}
+	for _, r := range string(content) {
+		if r < ' ' && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// previewSecret shows the first characters of a value, enough to find it but not to use it.
+func previewSecret(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + "..." + fmt.Sprintf("(%d chars)", len(value))
+}
+
+// WriteSecretScanReport stores the findings of a folder as secret-scan.txt and secret-scan.json in
+// it.
+func WriteSecretScanReport(dir string, findings []SecretFinding) error {
+	var text strings.Builder
+	if len(findings) == 0 {
+		text.WriteString("The secret scan found no secret.\n")
+	} else {
+		fmt.Fprintf(&text, "The secret scan found %d value(s) looking like secrets.\n\n", len(findings))
+		for _, finding := range findings {
+			fmt.Fprintf(&text, "- %s\n", finding)
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, SECRET_SCAN_REPORT_FILE), []byte(text.String()), 0644)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{"count": len(findings), "findings": findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, SECRET_SCAN_REPORT_JSON_FILE), content, 0644)
+}
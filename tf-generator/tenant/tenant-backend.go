@@ -9,7 +9,6 @@ import (
 	"tenant-terraform-generator/tf-generator/common"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
-	"github.com/zclconf/go-cty/cty"
 )
 
 type TenantBackend struct {
@@ -33,21 +32,7 @@ func (tb *TenantBackend) Generate(config *common.Config, client *duplosdk.Client
 	// Add duplo terraform block
 	tfBlock := rootBody.AppendNewBlock("terraform",
 		nil)
-	tfBlockBody := tfBlock.Body()
-	s3Backend := tfBlockBody.AppendNewBlock("backend",
-		[]string{"s3"})
-
-	s3BackendBody := s3Backend.Body()
-
-	s3BackendBody.SetAttributeValue("region",
-		cty.StringVal(config.DuploDefaultPlanRegion)) // TODO - Take region from ENV VAR
-	s3BackendBody.SetAttributeValue("key",
-		cty.StringVal("tenant"))
-
-	s3BackendBody.SetAttributeValue("workspace_key_prefix",
-		cty.StringVal("admin:"))
-	s3BackendBody.SetAttributeValue("encrypt",
-		cty.True)
+	config.StateBackend().WriteBackend(tfBlock.Body(), common.TenantProjectState(config), config)
 
 	fmt.Printf("%s", hclFile.Bytes())
 	_, err = tfFile.Write(hclFile.Bytes())
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
@@ -45,13 +46,34 @@ func (tsgrule *TenantSGRule) Generate(config *common.Config, client *duplosdk.Cl
 		rootBodyCreated := false
 		importConfigs := []common.ImportConfig{}
 		counter := 0
-		for _, sgRule := range *list {
-			if !rootBodyCreated {
-				rootBody = hclFile.Body()
+		// The rules are numbered, so they are sorted to keep their names when the API order changes.
+		sgRules := append([]duplosdk.DuploTenantExtConnSecurityGroupRule{}, *list...)
+		sort.SliceStable(sgRules, func(i, j int) bool {
+			a, b := sgRules[i], sgRules[j]
+			if a.Type != b.Type {
+				return a.Type < b.Type
 			}
-			rootBodyCreated = true
-			for _, source := range *sgRule.Sources {
+			if a.FromPort != b.FromPort {
+				return a.FromPort < b.FromPort
+			}
+			if a.ToPort != b.ToPort {
+				return a.ToPort < b.ToPort
+			}
+			return a.Protocol < b.Protocol
+		})
+		for _, sgRule := range sgRules {
+			sources := append([]duplosdk.DuploTenantExtConnSecurityGroupSource{}, *sgRule.Sources...)
+			sort.SliceStable(sources, func(i, j int) bool { return sources[i].Value < sources[j].Value })
+			for _, source := range sources {
+				// Excluded rules keep their number so that the other rules keep their address.
 				counter++
+				if !config.ResourceAllowed("duplocloud_tenant_network_security_rule", source.Value, nil) {
+					continue
+				}
+				if !rootBodyCreated {
+					rootBody = hclFile.Body()
+				}
+				rootBodyCreated = true
 				tenantSgRule := rootBody.AppendNewBlock("resource",
 					[]string{"duplocloud_tenant_network_security_rule",
 						"tenant-sg-rule" + strconv.Itoa(counter)})
@@ -84,7 +106,7 @@ func (tsgrule *TenantSGRule) Generate(config *common.Config, client *duplosdk.Cl
 					cty.StringVal(source.Description))
 				rootBody.AppendNewline()
 
-				if config.GenerateTfState {
+				if config.ImportsEnabled() {
 					importConfigs = append(importConfigs, common.ImportConfig{
 						ResourceAddress: "duplocloud_tenant_network_security_rule.tenant-sg-rule" + strconv.Itoa(counter),
 						ResourceId:      config.TenantId + "/" + strconv.Itoa(sgRule.Type) + "/" + sourceType + "/" + sgRule.Protocol + "/" + strconv.Itoa(sgRule.FromPort) + "/" + strconv.Itoa(sgRule.ToPort),
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"tenant-terraform-generator/duplosdk"
 	"tenant-terraform-generator/tf-generator/common"
 
@@ -31,6 +32,11 @@ func (t *Tenant) Generate(config *common.Config, client *duplosdk.Client) (*comm
 		fmt.Println(clientErr)
 		return nil, clientErr
 	}
+	tenantConfig, clientErr := client.TenantGetConfig(config.TenantId)
+	if clientErr != nil {
+		fmt.Println(clientErr)
+		return nil, clientErr
+	}
 	tfContext := common.TFContext{}
 	//1. ==========================================================================================
 	// Generate variables
@@ -87,14 +93,7 @@ func (t *Tenant) Generate(config *common.Config, client *duplosdk.Client) (*comm
 			Name: "cert_arn",
 		},
 	})
-	localsBlockBody.SetAttributeTraversal("tenant_name", hcl.Traversal{
-		hcl.TraverseRoot{
-			Name: "terraform",
-		},
-		hcl.TraverseAttr{
-			Name: "workspace",
-		},
-	})
+	common.SetTenantName(localsBlockBody, "tenant_name", config)
 	rootBody.AppendNewline()
 
 	// duplocloud_infrastructure block
@@ -112,7 +111,8 @@ func (t *Tenant) Generate(config *common.Config, client *duplosdk.Client) (*comm
 	})
 	rootBody.AppendNewline()
 
-	// Add duplocloud_tenant resource
+	// Add duplocloud_tenant resource. It is generated even when it is filtered out because every
+	// project reads it.
 	tenant := rootBody.AppendNewBlock("resource",
 		[]string{"duplocloud_tenant",
 			"tenant"})
@@ -136,30 +136,62 @@ func (t *Tenant) Generate(config *common.Config, client *duplosdk.Client) (*comm
 	tenantBody.SetAttributeValue("allow_deletion",
 		cty.BoolVal(true))
 	rootBody.AppendNewline()
-	// Add duplocloud_tenant_config resource
-
-	tenantConfig := rootBody.AppendNewBlock("resource",
-		[]string{"duplocloud_tenant_config",
-			"tenant-config"})
-	tenantConfigBody := tenantConfig.Body()
-	// tenantConfigBody.SetAttributeValue("tenant_id",
-	// 	cty.StringVal(config.TenantId))
-	tenantConfigBody.SetAttributeTraversal("tenant_id", hcl.Traversal{
-		hcl.TraverseRoot{
-			Name: "duplocloud_tenant.tenant",
-		},
-		hcl.TraverseAttr{
-			Name: "tenant_id",
-		},
-	})
-	settingBlock := tenantConfigBody.AppendNewBlock("setting",
-		nil)
-	settingBlockBody := settingBlock.Body()
-	settingBlockBody.SetAttributeValue("key",
-		cty.StringVal("delete_protection"))
-	settingBlockBody.SetAttributeValue("value",
-		cty.StringVal("true"))
-	rootBody.AppendNewline()
+
+	// Add duplocloud_tenant_config resource with the settings of the tenant metadata
+	settings := tenantSettings(tenantConfig, config.TenantSettings)
+	if !config.ResourceAllowed("duplocloud_tenant_config", duplo.AccountName, nil) {
+		settings = nil
+	}
+	if len(settings) > 0 {
+		tenantConfigBlock := rootBody.AppendNewBlock("resource",
+			[]string{"duplocloud_tenant_config",
+				"tenant-config"})
+		tenantConfigBody := tenantConfigBlock.Body()
+		tenantConfigBody.SetAttributeTraversal("tenant_id", hcl.Traversal{
+			hcl.TraverseRoot{
+				Name: "duplocloud_tenant.tenant",
+			},
+			hcl.TraverseAttr{
+				Name: "tenant_id",
+			},
+		})
+		for _, setting := range settings {
+			settingBlockBody := tenantConfigBody.AppendNewBlock("setting",
+				nil).Body()
+			settingBlockBody.SetAttributeValue("key",
+				cty.StringVal(setting.Key))
+			settingBlockBody.SetAttributeValue("value",
+				cty.StringVal(setting.Value))
+		}
+		rootBody.AppendNewline()
+	}
+
+	// Add a duplocloud_tenant_tag resource for each tag of the tenant
+	tags := []duplosdk.DuploKeyStringValue{}
+	for _, tag := range tenantTags(duplo) {
+		if config.ResourceAllowed("duplocloud_tenant_tag", tag.Key, nil) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range tags {
+		tagBlock := rootBody.AppendNewBlock("resource",
+			[]string{"duplocloud_tenant_tag",
+				common.GetResourceName(tag.Key)})
+		tagBody := tagBlock.Body()
+		tagBody.SetAttributeTraversal("tenant_id", hcl.Traversal{
+			hcl.TraverseRoot{
+				Name: "duplocloud_tenant.tenant",
+			},
+			hcl.TraverseAttr{
+				Name: "tenant_id",
+			},
+		})
+		tagBody.SetAttributeValue("key",
+			cty.StringVal(tag.Key))
+		tagBody.SetAttributeValue("value",
+			cty.StringVal(tag.Value))
+		rootBody.AppendNewline()
+	}
 
 	fmt.Printf("%s", hclFile.Bytes())
 	_, err = tfFile.Write(hclFile.Bytes())
@@ -173,22 +205,42 @@ func (t *Tenant) Generate(config *common.Config, client *duplosdk.Client) (*comm
 	// Generate outputs
 	log.Printf("[TRACE] Genrating output vars for Tenant Name : %s", duplo.AccountName)
 	outVars := generateTenantOutputVars(workingDir)
+	if duplo.TenantPolicy != nil {
+		// The provider can not change the policy of a tenant. A tenant created from the code gets the
+		// default policy, so the output tells whether it still has to be set in DuploCloud.
+		outVars = append(outVars, common.OutputVarConfig{
+			Name:          "tenant_policy_matches",
+			ActualVal:     "try(duplocloud_tenant.tenant.policy[0].allow_volume_mapping, false) == var.tenant_policy.allow_volume_mapping && try(duplocloud_tenant.tenant.policy[0].block_external_ep, false) == var.tenant_policy.block_external_ep",
+			DescVal:       "Whether the policy of the tenant is var.tenant_policy. The policy can only be changed in DuploCloud.",
+			RootTraversal: true,
+		})
+	}
 	tfContext.OutputVars = outVars
 	log.Printf("[TRACE] Output vars generated for Tenant Name : %s", duplo.AccountName)
 
 	// 4. ==========================================================================================
 	// Import all created resources.
-	if config.GenerateTfState {
+	if config.ImportsEnabled() {
 		importConfigs := []common.ImportConfig{}
 		importConfigs = append(importConfigs, common.ImportConfig{
 			ResourceAddress: "duplocloud_tenant.tenant",
 			ResourceId:      "v2/admin/TenantV2/" + config.TenantId,
 			WorkingDir:      workingDir,
-		}, common.ImportConfig{
-			ResourceAddress: "duplocloud_tenant_config.tenant-config",
-			ResourceId:      config.TenantId,
-			WorkingDir:      workingDir,
 		})
+		if len(settings) > 0 {
+			importConfigs = append(importConfigs, common.ImportConfig{
+				ResourceAddress: "duplocloud_tenant_config.tenant-config",
+				ResourceId:      config.TenantId,
+				WorkingDir:      workingDir,
+			})
+		}
+		for _, tag := range tags {
+			importConfigs = append(importConfigs, common.ImportConfig{
+				ResourceAddress: "duplocloud_tenant_tag." + common.GetResourceName(tag.Key),
+				ResourceId:      config.TenantId + "/" + tag.Key,
+				WorkingDir:      workingDir,
+			})
+		}
 		tfContext.ImportConfigs = importConfigs
 		// importer := &common.Importer{}
 		// importer.Import(config, &common.ImportConfig{
@@ -229,7 +281,20 @@ func generateTenantVars(duplo *duplosdk.DuploTenant, infraConfig *duplosdk.Duplo
 	}
 	varConfigs["cert_arn"] = certVar
 
-	vars := make([]common.VarConfig, len(varConfigs))
+	if duplo.TenantPolicy != nil {
+		policyVar := common.VarConfig{
+			Name:    "tenant_policy",
+			TypeVal: "object({ allow_volume_mapping = bool, block_external_ep = bool })",
+			DescVal: "The policy of the tenant. It can only be set in DuploCloud.",
+			ConfigVal: map[string]interface{}{
+				"allow_volume_mapping": duplo.TenantPolicy.AllowVolumeMapping,
+				"block_external_ep":    duplo.TenantPolicy.BlockExternalEp,
+			},
+		}
+		varConfigs["tenant_policy"] = policyVar
+	}
+
+	vars := make([]common.VarConfig, 0, len(varConfigs))
 	for _, v := range varConfigs {
 		vars = append(vars, v)
 	}
@@ -293,7 +358,7 @@ func generateTenantOutputVars(workingDir string) []common.OutputVarConfig {
 	}
 	outVarConfigs["vpc_id"] = vpcIdVar
 
-	outVars := make([]common.OutputVarConfig, len(outVarConfigs))
+	outVars := make([]common.OutputVarConfig, 0, len(outVarConfigs))
 	for _, v := range outVarConfigs {
 		outVars = append(outVars, v)
 	}
@@ -305,3 +370,36 @@ func generateTenantOutputVars(workingDir string) []common.OutputVarConfig {
 	// outVarsGenerator.Generate()
 	return outVars
 }
+
+// tenantSettings returns the settings of the tenant metadata in the allowed keys sorted by key. The
+// other keys are managed by DuploCloud.
+func tenantSettings(tenantConfig *duplosdk.DuploTenantConfig, allowed []string) []duplosdk.DuploKeyStringValue {
+	settings := []duplosdk.DuploKeyStringValue{}
+	if tenantConfig == nil || tenantConfig.Metadata == nil {
+		return settings
+	}
+	for _, setting := range *tenantConfig.Metadata {
+		if common.Contains(allowed, setting.Key) {
+			settings = append(settings, setting)
+		} else {
+			log.Printf("[TRACE] Tenant setting %s is managed by DuploCloud.", setting.Key)
+		}
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// tenantTags returns the tags of the tenant sorted by key, without the tags managed by DuploCloud.
+func tenantTags(duplo *duplosdk.DuploTenant) []duplosdk.DuploKeyStringValue {
+	tags := []duplosdk.DuploKeyStringValue{}
+	if duplo.Tags == nil {
+		return tags
+	}
+	for _, tag := range *duplo.Tags {
+		if !common.Contains(common.GetDuploManagedAwsTags(), tag.Key) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	return tags
+}
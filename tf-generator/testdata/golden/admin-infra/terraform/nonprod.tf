@@ -0,0 +1,39 @@
+resource "duplocloud_infrastructure" "infra" {
+  infra_name                = "nonprod"
+  account_id                = var.infra_nonprod_account_id
+  cloud                     = 0
+  region                    = var.infra_nonprod_region
+  azcount                   = 2
+  enable_k8_cluster         = true
+  is_serverless_kubernetes  = false
+  enable_ecs_cluster        = false
+  enable_container_insights = false
+  address_prefix            = var.infra_nonprod_address_prefix
+  subnet_cidr               = 22
+  custom_data {
+    key   = "EnableAwsAlbIngress"
+    value = "true"
+  }
+}
+resource "duplocloud_infrastructure_subnet" "infra-A-private" {
+  name             = "nonprod-A-private"
+  infra_name       = duplocloud_infrastructure.infra.infra_name
+  cidr_block       = "10.220.4.0/22"
+  type             = "private"
+  zone             = "A"
+  isolated_network = false
+  tags = {
+    Name = "nonprod-A-private"
+  }
+}
+resource "duplocloud_infrastructure_subnet" "infra-A-public" {
+  name             = "nonprod-A-public"
+  infra_name       = duplocloud_infrastructure.infra.infra_name
+  cidr_block       = "10.220.0.0/22"
+  type             = "public"
+  zone             = "A"
+  isolated_network = false
+  tags = {
+    Name = "nonprod-A-public"
+  }
+}
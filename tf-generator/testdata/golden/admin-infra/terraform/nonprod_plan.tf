@@ -0,0 +1,56 @@
+resource "duplocloud_plan_configs" "plan_config" {
+  plan_id                    = "nonprod"
+  delete_unspecified_configs = false
+  config {
+    key   = "EnableAwsAlbIngress"
+    value = "true"
+    type  = "Flags"
+  }
+  config {
+    key   = "OtelCollectorEndpoint"
+    value = "otel.nonprod.internal:4317"
+    type  = "AppConfig"
+  }
+}
+resource "duplocloud_plan_waf" "plan_wafnonprod-waf" {
+  plan_id       = "nonprod"
+  waf_arn       = "arn:aws:wafv2:us-west-2:123456789012:regional/webacl/nonprod-waf/7a6b5c4d"
+  waf_name      = "nonprod-waf"
+  dashboard_url = ""
+}
+resource "duplocloud_plan_certificate" "plan_cert" {
+  plan_id                         = "nonprod"
+  delete_unspecified_certificates = false
+  certificate {
+    name = "example-com"
+    id   = "arn:aws:acm:us-west-2:123456789012:certificate/0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+  }
+}
+resource "duplocloud_plan_settings" "plan_setting" {
+  plan_id             = "nonprod"
+  unrestricted_ext_lb = false
+  dns_setting {
+    domain_id           = "Z04123456ABCDEFGHIJ"
+    internal_dns_suffix = ".nonprod.internal"
+    external_dns_suffix = ".example.com"
+    ignore_global_dns   = false
+  }
+  metadata {
+    key   = "cost-center"
+    value = "1001"
+  }
+}
+resource "duplocloud_plan_image" "image-ubuntu-22-eks" {
+  plan_id                   = "nonprod"
+  delete_unspecified_images = false
+  image {
+    name     = "ubuntu-22-eks"
+    image_id = "ami-0a1b2c3d4e5f60789"
+    os       = "Linux"
+    username = "ubuntu"
+  }
+  tags {
+    key   = "os"
+    value = "ubuntu"
+  }
+}
@@ -0,0 +1,12 @@
+variable "infra_nonprod_account_id" {
+  default = "123456789012"
+  type    = string
+}
+variable "infra_nonprod_address_prefix" {
+  default = "10.220.0.0/16"
+  type    = string
+}
+variable "infra_nonprod_region" {
+  default = "us-west-2"
+  type    = string
+}
@@ -0,0 +1,39 @@
+locals {
+  region      = var.region
+  plan_id     = var.infra_name
+  cert_arn    = var.cert_arn
+  tenant_name = terraform.workspace
+}
+
+data "duplocloud_infrastructure" "infra" {
+  infra_name = var.infra_name
+}
+
+resource "duplocloud_tenant" "tenant" {
+  account_name   = local.tenant_name
+  plan_id        = local.plan_id
+  allow_deletion = true
+}
+
+resource "duplocloud_tenant_config" "tenant-config" {
+  tenant_id = duplocloud_tenant.tenant.tenant_id
+  setting {
+    key   = "block_master_vpn"
+    value = "false"
+  }
+  setting {
+    key   = "delete_protection"
+    value = "true"
+  }
+  setting {
+    key   = "enable_alerting"
+    value = "true"
+  }
+}
+
+resource "duplocloud_tenant_tag" "team" {
+  tenant_id = duplocloud_tenant.tenant.tenant_id
+  key       = "team"
+  value     = "payments"
+}
+
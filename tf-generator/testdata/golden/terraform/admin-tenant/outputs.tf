@@ -0,0 +1,28 @@
+output "cert_arn" {
+  value       = var.cert_arn
+  description = "The duplo plan certificate arn."
+}
+output "infra_name" {
+  value       = data.duplocloud_infrastructure.infra.infra_name
+  description = "The duplo infra name."
+}
+output "region" {
+  value       = var.region
+  description = "The duplo plan region."
+}
+output "tenant_id" {
+  value       = duplocloud_tenant.tenant.tenant_id
+  description = "The tenant ID"
+}
+output "tenant_name" {
+  value       = duplocloud_tenant.tenant.account_name
+  description = "The tenant name"
+}
+output "tenant_policy_matches" {
+  value       = try(duplocloud_tenant.tenant.policy[0].allow_volume_mapping, false) == var.tenant_policy.allow_volume_mapping && try(duplocloud_tenant.tenant.policy[0].block_external_ep, false) == var.tenant_policy.block_external_ep
+  description = "Whether the policy of the tenant is var.tenant_policy. The policy can only be changed in DuploCloud."
+}
+output "vpc_id" {
+  value       = data.duplocloud_infrastructure.infra.vpc_id
+  description = "The VPC or VNet ID."
+}
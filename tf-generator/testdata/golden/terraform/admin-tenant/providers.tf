@@ -0,0 +1,16 @@
+terraform {
+  required_version = ">= v1.4.7"
+  required_providers {
+    duplocloud = {
+      source  = "duplocloud/duplocloud"
+      version = "~> 0.10.0"
+    }
+  }
+}
+provider "duplocloud" {
+
+}
+provider "aws" {
+  region = var.region
+
+}
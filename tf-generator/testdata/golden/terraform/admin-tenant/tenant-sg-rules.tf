@@ -0,0 +1,18 @@
+resource "duplocloud_tenant_network_security_rule" "tenant-sg-rule1" {
+  tenant_id      = duplocloud_tenant.tenant.tenant_id
+  protocol       = "tcp"
+  source_address = "10.10.0.0/16"
+  from_port      = 443
+  to_port        = 443
+  description    = "office vpn"
+}
+
+resource "duplocloud_tenant_network_security_rule" "tenant-sg-rule2" {
+  tenant_id     = duplocloud_tenant.tenant.tenant_id
+  protocol      = "tcp"
+  source_tenant = "shared01"
+  from_port     = 5432
+  to_port       = 5432
+  description   = "shared tenant"
+}
+
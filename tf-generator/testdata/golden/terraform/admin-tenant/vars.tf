@@ -0,0 +1,15 @@
+variable "cert_arn" {
+  type = string
+}
+variable "infra_name" {
+  default = "nonprod"
+  type    = string
+}
+variable "region" {
+  default = "us-west-2"
+  type    = string
+}
+variable "tenant_policy" {
+  description = "The policy of the tenant. It can only be set in DuploCloud."
+  type        = object({ allow_volume_mapping = bool, block_external_ep = bool })
+}
@@ -0,0 +1,65 @@
+resource "duplocloud_ecs_task_definition" "reports_api" {
+  tenant_id                = local.tenant_id
+  family                   = "duploservices-${local.tenant_name}-reports-api"
+  cpu                      = "512"
+  memory                   = "1024"
+  network_mode             = "awsvpc"
+  prevent_tf_destroy       = false
+  requires_compatibilities = ["FARGATE"]
+  volumes                  = jsonencode([
+    {
+        "Name": "scratch"
+    }
+]
+)
+  container_definitions    = jsonencode([
+    {
+        "Environment": [
+            {
+                "Name": "REPORTS_BUCKET",
+                "Value": "duploservices-${local.tenant_name}-assets-123456789012"
+            }
+        ],
+        "Essential": true,
+        "Image": "123456789012.dkr.ecr.us-west-2.amazonaws.com/reports-api:3.1.0",
+        "Name": "reports-api",
+        "PortMappings": [
+            {
+                "ContainerPort": 8080,
+                "Protocol": "tcp"
+            }
+        ]
+    }
+]
+)
+}
+
+resource "duplocloud_ecs_service" "reports_api" {
+  tenant_id                         = local.tenant_id
+  name                              = "reports-api"
+  task_definition                   = duplocloud_ecs_task_definition.reports_api.arn
+  replicas                          = 2
+  health_check_grace_period_seconds = 30
+  old_task_definition_buffer_size   = 10
+  is_target_group_only              = false
+  dns_prfx                          = "reports-${local.tenant_name}"
+  capacity_provider_strategy {
+    base              = 1
+    weight            = 1
+    capacity_provider = "FARGATE"
+  }
+  load_balancer {
+    target_group_count       = 1
+    lb_type                  = 1
+    is_internal              = false
+    port                     = 8080
+    external_port            = 443
+    protocol                 = "HTTPS"
+    backend_protocol_version = "HTTP1"
+    health_check_url         = "/status"
+    enable_access_logs       = true
+    drop_invalid_headers     = true
+    idle_timeout             = 120
+  }
+
+}
@@ -0,0 +1,9 @@
+resource "duplocloud_k8_config_map" "orders_config" {
+  tenant_id = local.tenant_id
+  name      = "orders-config"
+  data      = jsonencode({
+    "log_level": "info",
+    "orders.yaml": "queue: duploservices-dev01-orders-events\nretries: 3\n"
+}
+)
+}
@@ -0,0 +1,45 @@
+resource "duplocloud_k8s_cron_job" "orders_cleanup" {
+  tenant_id = local.tenant_id
+  metadata {
+    name = "orders-cleanup"
+    labels = {
+      app = "orders-cleanup"
+    }
+  }
+  spec {
+    concurrency_policy            = "Forbid"
+    schedule                      = "0 3 * * *"
+    successful_jobs_history_limit = 3
+    failed_jobs_history_limit     = 1
+    job_template {
+      metadata {
+      }
+      spec {
+        backoff_limit = 1
+        template {
+          metadata {
+            labels = {
+              app = "orders-cleanup"
+            }
+          }
+          spec {
+            host_ipc       = false
+            host_network   = false
+            host_pid       = false
+            restart_policy = "OnFailure"
+            container {
+              image      = "duplocloud/orders-cleanup:1.0.0"
+              name       = "cleanup"
+              args       = ["--older-than", "30d"]
+              stdin      = false
+              stdin_once = false
+              tty        = false
+            }
+          }
+        }
+      }
+    }
+  }
+  is_any_host_allowed = false
+  allocation_tags     = ""
+}
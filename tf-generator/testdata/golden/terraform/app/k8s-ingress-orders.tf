@@ -0,0 +1,25 @@
+resource "duplocloud_k8_ingress" "orders" {
+  tenant_id          = local.tenant_id
+  name               = "orders"
+  ingress_class_name = "alb"
+  annotations = {
+    "alb.ingress.kubernetes.io/group.name" = "orders"
+  }
+  labels = {
+    app = "orders"
+  }
+  lbconfig {
+    dns_prefix      = "orders-${local.tenant_name}"
+    is_internal     = false
+    certificate_arn = local.cert_arn
+    https_port      = 443
+    http_port       = 80
+  }
+  rule {
+    path         = "/"
+    path_type    = "Prefix"
+    port         = 8080
+    host         = "orders.example.com"
+    service_name = duplocloud_duplo_service.orders_api.name
+  }
+}
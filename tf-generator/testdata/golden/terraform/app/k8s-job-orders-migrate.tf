@@ -0,0 +1,43 @@
+resource "duplocloud_k8s_job" "orders_migrate" {
+  tenant_id = local.tenant_id
+  metadata {
+    name = "orders-migrate"
+    labels = {
+      app = "orders-migrate"
+    }
+  }
+  spec {
+    backoff_limit              = 2
+    ttl_seconds_after_finished = 3600
+    template {
+      metadata {
+        labels = {
+          app = "orders-migrate"
+        }
+      }
+      spec {
+        host_ipc     = false
+        host_network = false
+        host_pid     = false
+        node_selector = {
+          allocationtags = "web"
+        }
+        restart_policy = "Never"
+        container {
+          image      = "123456789012.dkr.ecr.us-west-2.amazonaws.com/orders-api:1.4.2"
+          name       = "migrate"
+          command    = ["/app/migrate", "up"]
+          stdin      = false
+          stdin_once = false
+          tty        = false
+          env {
+            name  = "DB_HOST"
+            value = "orders-db.internal"
+          }
+        }
+      }
+    }
+  }
+  is_any_host_allowed = false
+  allocation_tags     = "web"
+}
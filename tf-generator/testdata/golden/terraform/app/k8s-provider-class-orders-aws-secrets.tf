@@ -0,0 +1,20 @@
+resource "duplocloud_k8_secret_provider_class" "orders_aws_secrets" {
+  tenant_id       = local.tenant_id
+  name            = "orders-aws-secrets"
+  secret_provider = "aws"
+  secret_object {
+    name = "orders-api-key"
+    type = "Opaque"
+    data {
+      key         = "api-key"
+      object_name = "duploservices-dev01-orders-api-key"
+    }
+  }
+  parameters = jsonencode([
+    {
+        "objectName": "duploservices-${local.tenant_name}-orders-api-key",
+        "objectType": "secretsmanager"
+    }
+]
+)
+}
@@ -0,0 +1,12 @@
+resource "duplocloud_k8_secret" "orders_db" {
+  tenant_id   = local.tenant_id
+  secret_name = "orders-db"
+  secret_type = "Opaque"
+  secret_annotations = {
+    owner = "payments"
+  }
+  secret_data = jsonencode({
+    "password" = var.k8_secret_orders_db_secret_data_password
+    "username" = var.k8_secret_orders_db_secret_data_username
+  })
+}
@@ -0,0 +1,33 @@
+resource "duplocloud_duplo_service" "billing_worker" {
+  tenant_id                            = local.tenant_id
+  name                                 = "billing-worker"
+  replicas                             = 1
+  lb_synced_deployment                 = false
+  cloud_creds_from_k8s_service_account = false
+  is_daemonset                         = false
+  agent_platform                       = 0
+  cloud                                = 0
+  other_docker_host_config             = jsonencode({
+    "CapAdd": [
+        "NET_ADMIN"
+    ],
+    "NetworkMode": "host"
+}
+)
+  commands                             = ["/app/worker", "--queue", "billing"]
+  docker_image                         = var.svc_billing_worker_docker_image
+  volumes                              = "[{\"Name\":\"data\",\"Path\":\"/data\",\"HostPath\":\"/mnt/billing\"}]"
+}
+
+resource "duplocloud_duplo_service_lbconfigs" "billing_worker_config" {
+  tenant_id                   = duplocloud_duplo_service.billing_worker.tenant_id
+  replication_controller_name = duplocloud_duplo_service.billing_worker.name
+  lbconfigs {
+    lb_type       = 3
+    is_native     = false
+    is_internal   = true
+    port          = 9090
+    external_port = 9090
+    protocol      = "tcp"
+  }
+}
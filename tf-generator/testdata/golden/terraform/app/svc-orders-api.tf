@@ -0,0 +1,118 @@
+resource "duplocloud_duplo_service" "orders_api" {
+  tenant_id                            = local.tenant_id
+  name                                 = "orders-api"
+  replicas                             = 2
+  lb_synced_deployment                 = true
+  cloud_creds_from_k8s_service_account = false
+  is_daemonset                         = false
+  agent_platform                       = 7
+  cloud                                = 0
+  allocation_tags                      = "web"
+  other_docker_config                  = jsonencode({
+    "Env": [
+        {
+            "Name": "DB_HOST",
+            "Value": "orders-db.internal"
+        },
+        {
+            "Name": "DB_PASSWORD",
+            "ValueFrom": {
+                "SecretKeyRef": {
+                    "Key": "password",
+                    "Name": "${duplocloud_k8_secret.orders_db.secret_name}"
+                }
+            }
+        },
+        {
+            "Name": "LOG_LEVEL",
+            "ValueFrom": {
+                "ConfigMapKeyRef": {
+                    "Key": "log_level",
+                    "Name": "${duplocloud_k8_config_map.orders_config.name}"
+                }
+            }
+        }
+    ],
+    "EnvFrom": [
+        {
+            "ConfigMapRef": {
+                "Name": "${duplocloud_k8_config_map.orders_config.name}"
+            }
+        }
+    ],
+    "Resources": {
+        "limits": {
+            "memory": "512Mi"
+        },
+        "requests": {
+            "cpu": "250m",
+            "memory": "256Mi"
+        }
+    }
+}
+)
+  docker_image                         = var.svc_orders_api_docker_image
+  hpa_specs                            = jsonencode({
+    "maxReplicas": 6,
+    "metrics": [
+        {
+            "resource": {
+                "name": "cpu",
+                "target": {
+                    "averageUtilization": 70,
+                    "type": "Utilization"
+                }
+            },
+            "type": "Resource"
+        }
+    ],
+    "minReplicas": 2
+}
+)
+  volumes                              = jsonencode([
+    {
+        "Name": "config",
+        "Path": "/etc/orders",
+        "Spec": {
+            "ConfigMap": {
+                "Name": "${duplocloud_k8_config_map.orders_config.name}"
+            }
+        }
+    },
+    {
+        "Name": "db",
+        "Path": "/etc/orders-db",
+        "Spec": {
+            "Secret": {
+                "SecretName": "${duplocloud_k8_secret.orders_db.secret_name}"
+            }
+        }
+    }
+]
+)
+}
+
+resource "duplocloud_duplo_service_lbconfigs" "orders_api_config" {
+  tenant_id                   = duplocloud_duplo_service.orders_api.tenant_id
+  replication_controller_name = duplocloud_duplo_service.orders_api.name
+  lbconfigs {
+    lb_type          = 1
+    is_native        = false
+    is_internal      = false
+    port             = 8080
+    external_port    = 443
+    protocol         = "http"
+    health_check_url = "/healthz"
+    certificate_arn  = local.cert_arn
+    backend_protocol = "HTTP1"
+  }
+}
+resource "duplocloud_duplo_service_params" "orders_api_params" {
+  tenant_id                   = duplocloud_duplo_service_lbconfigs.orders_api_config.tenant_id
+  replication_controller_name = duplocloud_duplo_service_lbconfigs.orders_api_config.replication_controller_name
+  dns_prfx                    = "orders-${local.tenant_name}"
+  enable_access_logs          = true
+  drop_invalid_headers        = true
+  http_to_https_redirect      = false
+  idle_timeout                = 120
+}
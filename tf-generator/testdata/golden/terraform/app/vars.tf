@@ -0,0 +1,22 @@
+variable "k8_secret_orders_db_secret_data_password" {
+  description = "Value of secret_data.password of duplocloud_k8_secret.orders_db."
+  type        = string
+  sensitive   = true
+}
+variable "k8_secret_orders_db_secret_data_username" {
+  description = "Value of secret_data.username of duplocloud_k8_secret.orders_db."
+  type        = string
+  sensitive   = true
+}
+variable "region" {
+  default = "us-west-2"
+  type    = string
+}
+variable "svc_billing_worker_docker_image" {
+  default = "duplocloud/billing-worker:2.0.1"
+  type    = string
+}
+variable "svc_orders_api_docker_image" {
+  default = "123456789012.dkr.ecr.us-west-2.amazonaws.com/orders-api:1.4.2"
+  type    = string
+}
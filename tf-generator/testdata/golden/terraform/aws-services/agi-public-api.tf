@@ -0,0 +1,4 @@
+resource "duplocloud_aws_api_gateway_integration" "public_api" {
+  tenant_id = local.tenant_id
+  name      = "public-api"
+}
@@ -0,0 +1,38 @@
+resource "duplocloud_asg_profile" "workers" {
+  tenant_id             = local.tenant_id
+  friendly_name         = "workers"
+  instance_count        = var.asg_workers_instance_count
+  min_instance_count    = var.asg_workers_min_instance_count
+  max_instance_count    = var.asg_workers_max_instance_count
+  image_id              = var.asg_workers_image_id
+  capacity              = var.asg_workers_capacity
+  agent_platform        = 7
+  zone                  = 0
+  is_minion             = true
+  is_cluster_autoscaled = true
+  is_ebs_optimized      = true
+  encrypt_disk          = true
+  allocated_public_ip   = false
+  cloud                 = 0
+  keypair_type          = 0
+  minion_tags {
+    key   = "k8s.io/cluster-autoscaler/enabled"
+    value = "true"
+  }
+  taints {
+    key    = "workload"
+    value  = "batch"
+    effect = "NoExecute"
+  }
+  volume {
+    iops        = 0
+    name        = "/dev/sdg"
+    size        = 100
+    volume_id   = ""
+    volume_type = "gp3"
+  }
+  use_spot_instances = true
+  max_spot_price     = "0.05"
+}
+
+
@@ -0,0 +1,16 @@
+resource "duplocloud_aws_batch_compute_environment" "spot" {
+  tenant_id = local.tenant_id
+  name      = "spot"
+  type      = "MANAGED"
+  state     = "ENABLED"
+  compute_resources {
+    allocation_strategy = "SPOT_CAPACITY_OPTIMIZED"
+    type                = "SPOT"
+    bid_percentage      = 60
+    max_vcpus           = 64
+    instance_type       = ["optimal"]
+  }
+  tags = {
+    team = "data"
+  }
+}
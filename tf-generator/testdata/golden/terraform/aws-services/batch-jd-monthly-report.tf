@@ -0,0 +1,53 @@
+resource "duplocloud_aws_batch_job_definition" "monthly_report" {
+  tenant_id             = local.tenant_id
+  name                  = "monthly-report"
+  type                  = "container"
+  platform_capabilities = ["EC2"]
+  timeout {
+    attempt_duration_seconds = 3600
+  }
+  retry_strategy {
+    attempts = 2
+    evaluate_on_exit {
+      action           = "RETRY"
+      on_status_reason = "Host EC2*"
+    }
+  }
+  parameters = {
+    month = "current"
+  }
+container_properties = <<CONTAINER_PROPERTIES
+ {
+    "Command": [
+        "python",
+        "report.py",
+        "Ref::month"
+    ],
+    "Environment": [
+        {
+            "Name": "STAGE",
+            "Value": "dev"
+        }
+    ],
+    "Image": "123456789012.dkr.ecr.us-west-2.amazonaws.com/reports:2.1.0",
+    "ResourceRequirements": [
+        {
+            "Type": {
+                "Value": "VCPU"
+            },
+            "Value": "2"
+        },
+        {
+            "Type": {
+                "Value": "MEMORY"
+            },
+            "Value": "4096"
+        }
+    ]
+}
+ 
+ CONTAINER_PROPERTIES
+ tags = {
+ team = "data"
+ }
+ }
@@ -0,0 +1,11 @@
+resource "duplocloud_aws_batch_job_queue" "reports" {
+  tenant_id             = local.tenant_id
+  name                  = "reports"
+  state                 = "ENABLED"
+  priority              = 10
+  scheduling_policy_arn = duplocloud_aws_batch_scheduling_policy.fair.arn
+compute_environments = [duplocloud_aws_batch_compute_environment.spot.arn ]
+ tags = {
+ team = "data"
+ }
+ }
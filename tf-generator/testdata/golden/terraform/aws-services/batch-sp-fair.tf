@@ -0,0 +1,15 @@
+resource "duplocloud_aws_batch_scheduling_policy" "fair" {
+  tenant_id = local.tenant_id
+  name      = "fair"
+  fair_share_policy {
+    compute_reservation = 10
+    share_decay_seconds = 3600
+    share_distribution {
+      share_identifier = "reports"
+      weight_factor    = 0.5
+    }
+  }
+  tags = {
+    team = "data"
+  }
+}
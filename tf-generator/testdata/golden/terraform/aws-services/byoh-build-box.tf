@@ -0,0 +1,9 @@
+resource "duplocloud_byoh" "build_box" {
+  tenant_id      = local.tenant_id
+  name           = "build-box"
+  direct_address = "10.30.1.15"
+  agent_platform = 0
+  allocation_tag = "build"
+  username       = "ubuntu"
+  private_key    = var.byoh_build_box_private_key
+}
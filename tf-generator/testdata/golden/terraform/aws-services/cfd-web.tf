@@ -0,0 +1,35 @@
+resource "duplocloud_aws_cloudfront_distribution" "web" {
+  tenant_id           = local.tenant_id
+  comment             = "duploservices-${local.tenant_name}-web"
+  aliases             = ["static.example.com"]
+  default_root_object = "index.html"
+  enabled             = true
+  http_version        = "http2"
+  price_class         = "PriceClass_100"
+  is_ipv6_enabled     = true
+  custom_error_response {
+    error_code            = 404
+    response_code         = 200
+    response_page_path    = "/index.html"
+    error_caching_min_ttl = 10
+  }
+  viewer_certificate {
+    acm_certificate_arn      = "arn:aws:acm:us-east-1:123456789012:certificate/6a5b4c3d-2e1f-4a0b-9c8d-7e6f5a4b3c2d"
+    minimum_protocol_version = "TLSv1.2_2021"
+    ssl_support_method       = "sni-only"
+  }
+  origin {
+    connection_attempts = 3
+    connection_timeout  = 10
+    domain_name         = "${duplocloud_s3_bucket.assets.fullname}.s3.${local.region}.amazonaws.com"
+    origin_id           = "${duplocloud_s3_bucket.assets.fullname}.s3.${local.region}.amazonaws.com"
+  }
+  default_cache_behavior {
+    target_origin_id       = "${duplocloud_s3_bucket.assets.fullname}.s3.${local.region}.amazonaws.com"
+    allowed_methods        = ["GET", "HEAD"]
+    cached_methods         = ["GET", "HEAD"]
+    cache_policy_id        = "658327ea-f89d-4fab-a63d-7e88639e58f6"
+    compress               = true
+    viewer_protocol_policy = "redirect-to-https"
+  }
+}
@@ -0,0 +1,42 @@
+resource "duplocloud_aws_cloudwatch_event_rule" "nightly_cleanup" {
+  tenant_id           = local.tenant_id
+  name                = "duploservices-dev01-nightly-cleanup"
+  description         = "Cleans up expired orders"
+  event_bus_name      = "default"
+  schedule_expression = "cron(0 3 * * ? *)"
+  state               = "ENABLED"
+}
+
+resource "duplocloud_aws_cloudwatch_event_target" "nightly_cleanup_cleanup_lambda" {
+  tenant_id      = local.tenant_id
+  rule_name      = duplocloud_aws_cloudwatch_event_rule.nightly_cleanup.fullname
+  target_arn     = duplocloud_aws_lambda_function.resize_images.arn
+  target_id      = "cleanup-lambda"
+  event_bus_name = "default"
+}
+
+resource "duplocloud_aws_cloudwatch_event_target" "nightly_cleanup_cleanup_queue" {
+  tenant_id      = local.tenant_id
+  rule_name      = duplocloud_aws_cloudwatch_event_rule.nightly_cleanup.fullname
+  target_arn     = duplocloud_aws_sqs_queue.orders_events.arn
+  target_id      = "cleanup-queue"
+  event_bus_name = "default"
+}
+
+resource "duplocloud_aws_cloudwatch_event_target" "nightly_cleanup_cleanup_alerts" {
+  tenant_id      = local.tenant_id
+  rule_name      = duplocloud_aws_cloudwatch_event_rule.nightly_cleanup.fullname
+  target_arn     = duplocloud_aws_sns_topic.alerts.arn
+  target_id      = "cleanup-alerts"
+  event_bus_name = "default"
+}
+
+resource "duplocloud_aws_cloudwatch_event_target" "nightly_cleanup_cleanup_task" {
+  tenant_id      = local.tenant_id
+  rule_name      = duplocloud_aws_cloudwatch_event_rule.nightly_cleanup.fullname
+  target_arn     = "arn:aws:ecs:${local.region}:${data.aws_caller_identity.current.account_id}:cluster/duploservices-${local.tenant_name}"
+  target_id      = "cleanup-task"
+  role_arn       = "arn:aws:iam::123456789012:role/duploservices-dev01"
+  event_bus_name = "default"
+}
+
@@ -0,0 +1,14 @@
+resource "duplocloud_aws_cloudwatch_metric_alarm" "cpuutilization_ec2_1" {
+  tenant_id           = local.tenant_id
+  metric_name         = "CPUUtilization"
+  comparison_operator = "GreaterThanThreshold"
+  evaluation_periods  = 2
+  namespace           = "AWS/EC2"
+  statistic           = "Average"
+  period              = 300
+  threshold           = 80
+  dimension {
+    key   = "InstanceId"
+    value = duplocloud_aws_host.api_node.instance_id
+  }
+}
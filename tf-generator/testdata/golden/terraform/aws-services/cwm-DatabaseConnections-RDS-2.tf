@@ -0,0 +1,14 @@
+resource "duplocloud_aws_cloudwatch_metric_alarm" "databaseconnections_rds_2" {
+  tenant_id           = local.tenant_id
+  metric_name         = "DatabaseConnections"
+  comparison_operator = "GreaterThanOrEqualToThreshold"
+  evaluation_periods  = 5
+  namespace           = "AWS/RDS"
+  statistic           = "Maximum"
+  period              = 60
+  threshold           = 100
+  dimension {
+    key   = "DBInstanceIdentifier"
+    value = duplocloud_rds_instance.reports.fullname	
+  }
+}
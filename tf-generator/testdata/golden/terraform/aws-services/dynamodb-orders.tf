@@ -0,0 +1,35 @@
+resource "duplocloud_aws_dynamodb_table_v2" "orders" {
+  tenant_id                   = local.tenant_id
+  name                        = "orders"
+  deletion_protection_enabled = true
+  is_point_in_time_recovery   = true
+  billing_mode                = "PAY_PER_REQUEST"
+  stream_enabled              = true
+  stream_view_type            = "NEW_AND_OLD_IMAGES"
+  attribute {
+    name = "OrderId"
+    type = "S"
+  }
+  attribute {
+    name = "CreatedAt"
+    type = "N"
+  }
+  attribute {
+    name = "CustomerId"
+    type = "S"
+  }
+  key_schema {
+    attribute_name = "OrderId"
+    key_type       = "HASH"
+  }
+  key_schema {
+    attribute_name = "CreatedAt"
+    key_type       = "RANGE"
+  }
+  global_secondary_index {
+    name               = "by-customer"
+    projection_type    = "INCLUDE"
+    hash_key           = "CustomerId"
+    non_key_attributes = ["Total"]
+  }
+}
@@ -0,0 +1,7 @@
+resource "duplocloud_aws_ecr_repository" "orders_api" {
+  tenant_id                 = local.tenant_id
+  name                      = "orders-api-${local.tenant_name}"
+  enable_scan_image_on_push = true
+  enable_tag_immutability   = true
+  kms_encryption_key        = "AES256"
+}
@@ -0,0 +1,28 @@
+resource "duplocloud_emr_cluster" "analytics" {
+  tenant_id                         = local.tenant_id
+  name                              = "analytics"
+  release_label                     = "emr-6.15.0"
+  log_uri                           = "s3n://duploservices-dev01-assets-123456789012/emr-logs/"
+  termination_protection            = false
+  keep_job_flow_alive_when_no_steps = true
+  visible_to_all_users              = true
+  ebs_root_volume_size              = 20
+  step_concurrency_level            = 1
+  instance_count                    = 3
+  scale_down_behavior               = "TERMINATE_AT_TASK_COMPLETION"
+  applications                      = jsonencode([
+    {
+        "AdditionalInfo": {},
+        "Name": "Spark",
+        "Version": "3.4.1"
+    },
+    {
+        "AdditionalInfo": {},
+        "Name": "Hadoop",
+        "Version": "3.3.6"
+    }
+]
+)
+  master_instance_type              = "m5.xlarge"
+  slave_instance_type               = "m5.2xlarge"
+}
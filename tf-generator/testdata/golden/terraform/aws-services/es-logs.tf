@@ -0,0 +1,17 @@
+resource "duplocloud_aws_elasticsearch" "logs" {
+  tenant_id                      = local.tenant_id
+  name                           = "logs"
+  use_latest_tls_cipher          = true
+  require_ssl                    = true
+  elasticsearch_version          = var.es_logs_elasticsearch_version
+  enable_node_to_node_encryption = true
+  storage_size                   = 20
+  cluster_config {
+    instance_count = 1
+    instance_type  = var.es_logs_instance_type
+  }
+  encrypt_at_rest {
+    kms_key_id = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id
+  }
+  selected_zone = var.es_logs_selected_zone
+}
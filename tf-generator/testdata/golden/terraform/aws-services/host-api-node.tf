@@ -0,0 +1,41 @@
+resource "duplocloud_aws_host" "api_node" {
+  tenant_id           = local.tenant_id
+  friendly_name       = "api-node"
+  image_id            = var.host_api_node_image_id
+  capacity            = var.host_api_node_capacity
+  agent_platform      = 7
+  zone                = 0
+  user_account        = "dev01"
+  is_minion           = true
+  is_ebs_optimized    = false
+  encrypt_disk        = true
+  allocated_public_ip = false
+  cloud               = 0
+  keypair_type        = 0
+  minion_tags {
+    key   = "AllocationTags"
+    value = "api"
+  }
+  metadata {
+    key   = "OsDiskSize"
+    value = "40"
+  }
+  volume {
+    iops        = 3000
+    name        = "/dev/sdf"
+    size        = 50
+    volume_id   = ""
+    volume_type = "gp3"
+  }
+  taints {
+    key    = "dedicated"
+    value  = "api"
+    effect = "NoSchedule"
+  }
+  lifecycle {
+    ignore_changes = ["image_id"]
+  }
+}
+
+
+
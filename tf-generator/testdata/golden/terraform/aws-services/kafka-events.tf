@@ -0,0 +1,9 @@
+resource "duplocloud_aws_kafka_cluster" "events" {
+  tenant_id             = local.tenant_id
+  name                  = "events"
+  storage_size          = var.kafka_events_storage_size
+  instance_type         = var.kafka_events_instance_type
+  kafka_version         = var.kafka_events_kafka_version
+  subnets               = ["subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"]
+  encryption_in_transit = "TLS"
+}
@@ -0,0 +1,5 @@
+data "duplocloud_plan_kms" "nonprod_kms" {
+  plan_id = var.plan_id
+  name    = "nonprod-kms"
+}
+
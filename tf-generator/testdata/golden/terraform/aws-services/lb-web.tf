@@ -0,0 +1,74 @@
+resource "duplocloud_aws_load_balancer" "web" {
+  tenant_id            = local.tenant_id
+  name                 = "web"
+  load_balancer_type   = "Application"
+  enable_access_logs   = true
+  is_internal          = true
+  drop_invalid_headers = true
+  idle_timeout         = 120
+}
+
+resource "duplocloud_aws_load_balancer_listener" "web_listener_80" {
+  tenant_id          = local.tenant_id
+  load_balancer_name = duplocloud_aws_load_balancer.web.name
+  protocol           = "HTTP"
+  port               = 80
+  target_group_arn   = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/duplo3-dev01-web-tg/6d0ecf831eec9f09"
+}
+
+resource "duplocloud_aws_target_group_attributes" "web_listener_80_tg_attributes" {
+  tenant_id        = local.tenant_id
+  target_group_arn = duplocloud_aws_load_balancer_listener.web_listener_80.target_group_arn
+  attribute {
+    key   = "deregistration_delay.timeout_seconds"
+    value = "30"
+  }
+  attribute {
+    key   = "stickiness.enabled"
+    value = "false"
+  }
+}
+resource "duplocloud_aws_lb_listener_rule" "web_listener_80_rule_2" {
+  tenant_id    = local.tenant_id
+  listener_arn = duplocloud_aws_load_balancer_listener.web_listener_80.arn
+  priority     = 10
+  action {
+    type             = "forward"
+    order            = 1
+    target_group_arn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/duplo3-dev01-web-tg/6d0ecf831eec9f09"
+  }
+  condition {
+    path_pattern {
+      values = ["/api/*"]
+    }
+  }
+}
+resource "duplocloud_aws_lb_listener_rule" "web_listener_80_rule_3" {
+  tenant_id    = local.tenant_id
+  listener_arn = duplocloud_aws_load_balancer_listener.web_listener_80.arn
+  priority     = 20
+  action {
+    type  = "authenticate-oidc"
+    order = 1
+    authenticate_oidc {
+      authorization_endpoint     = "https://login.example.com/oauth2/authorize"
+      client_id                  = "orders-admin"
+      client_secret              = var.aws_lb_listener_rule_web_listener_80_rule_3_action_authenticate_oidc_client_secret
+      issuer                     = "https://login.example.com"
+      token_endpoint             = "https://login.example.com/oauth2/token"
+      user_info_endpoint         = "https://login.example.com/oauth2/userinfo"
+      on_unauthenticated_request = "authenticate"
+      scope                      = "openid"
+    }
+  }
+  action {
+    type             = "forward"
+    order            = 2
+    target_group_arn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/duplo3-dev01-web-tg/6d0ecf831eec9f09"
+  }
+  condition {
+    path_pattern {
+      values = ["/admin/*"]
+    }
+  }
+}
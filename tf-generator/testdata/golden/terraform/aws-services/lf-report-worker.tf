@@ -0,0 +1,14 @@
+resource "duplocloud_aws_lambda_function" "report_worker" {
+  tenant_id         = local.tenant_id
+  name              = "report-worker"
+  package_type      = "Image"
+  image_uri         = "123456789012.dkr.ecr.us-west-2.amazonaws.com/report-worker:1.4.0"
+  memory_size       = 1024
+  timeout           = 300
+  ephemeral_storage = 512
+  image_config {
+    command           = ["app.handler"]
+    entry_point       = ["/lambda-entrypoint.sh"]
+    working_directory = "/var/task"
+  }
+}
@@ -0,0 +1,30 @@
+resource "duplocloud_aws_lambda_function" "resize_images" {
+  tenant_id    = local.tenant_id
+  name         = "resize-images"
+  description  = "Resizes uploaded images"
+  package_type = "Zip"
+  s3_bucket    = var.lf_resize_images_s3_bucket
+  s3_key       = var.lf_resize_images_s3_key
+  depends_on   = ["duplocloud_s3_bucket.assets"]
+  memory_size  = 512
+  timeout      = 30
+  handler      = "index.handler"
+  runtime      = "nodejs18.x"
+  environment {
+    variables = {
+      BUCKET    = "duploservices-dev01-assets-123456789012"
+      LOG_LEVEL = "info"
+    }
+  }
+  ephemeral_storage = 1024
+  tracing_config {
+    mode = "Active"
+  }
+}
+resource "duplocloud_aws_lambda_permission" "resize-images-permission0" {
+  tenant_id     = local.tenant_id
+  function_name = duplocloud_aws_lambda_function.resize_images.name
+  action        = "lambda:InvokeFunction"
+  principal     = "s3.amazonaws.com"
+  statement_id  = "s3-invoke"
+}
@@ -0,0 +1,44 @@
+resource "duplocloud_aws_mwaa_environment" "airflow" {
+  tenant_id                       = local.tenant_id
+  name                            = "airflow"
+  source_bucket_arn               = duplocloud_s3_bucket.assets.arn
+  dag_s3_path                     = "dags"
+  kms_key                         = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn
+  schedulers                      = 2
+  max_workers                     = 4
+  airflow_version                 = "2.7.2"
+  weekly_maintenance_window_start = "SUN:03:30"
+  requirements_s3_path            = "requirements.txt"
+  webserver_access_mode           = "PRIVATE_ONLY"
+  environment_class               = "mw1.small"
+  airflow_configuration_options = {
+    "core.default_timezone" = "utc"
+  }
+  logging_configuration {
+    dag_processing_logs {
+      enabled   = true
+      log_level = "WARNING"
+    }
+
+    scheduler_logs {
+      enabled   = false
+      log_level = "INFO"
+    }
+
+    task_logs {
+      enabled   = true
+      log_level = "INFO"
+    }
+
+    webserver_logs {
+      enabled   = false
+      log_level = "INFO"
+    }
+
+    worker_logs {
+      enabled   = false
+      log_level = "INFO"
+    }
+
+  }
+}
@@ -0,0 +1,276 @@
+output "asg_workers_fullname" {
+  value       = duplocloud_asg_profile.workers.fullname
+  description = "The full name of the ASG."
+}
+output "batch_ce_fair_arn" {
+  value       = duplocloud_aws_batch_scheduling_policy.fair.arn
+  description = "The Amazon Resource Name of the scheduling policy."
+}
+output "batch_ce_spot_arn" {
+  value       = duplocloud_aws_batch_compute_environment.spot.arn
+  description = "The Amazon Resource Name (ARN) of the compute environment."
+}
+output "batch_jd_monthly_report_arn" {
+  value       = duplocloud_aws_batch_job_definition.monthly_report.arn
+  description = "The Amazon Resource Name of the job Definition."
+}
+output "batch_q_reports_arn" {
+  value       = duplocloud_aws_batch_job_queue.reports.arn
+  description = "The Amazon Resource Name of the job queue."
+}
+output "byoh_build_box_connection_url" {
+  value       = duplocloud_byoh.build_box.connection_url
+  description = "The connection url for BYOH instance."
+}
+output "byoh_build_box_network_agent_url" {
+  value       = duplocloud_byoh.build_box.network_agent_url
+  description = "The network agent url for BYOH instance."
+}
+output "cfd_web_arn" {
+  value       = duplocloud_aws_cloudfront_distribution.web.arn
+  description = "The ARN for the distribution."
+}
+output "cfd_web_domain_name" {
+  value       = duplocloud_aws_cloudfront_distribution.web.domain_name
+  description = "The domain name corresponding to the distribution."
+}
+output "cfd_web_id" {
+  value       = duplocloud_aws_cloudfront_distribution.web.id
+  description = "The identifier for the distribution."
+}
+output "dynamodb_orders_arn" {
+  value       = duplocloud_aws_dynamodb_table_v2.orders.arn
+  description = "The ARN of the dynamodb table."
+}
+output "dynamodb_orders_stream_arn" {
+  value       = duplocloud_aws_dynamodb_table_v2.orders.stream_arn
+  description = "The Stream ARN of the dynamodb table."
+}
+output "ecr_orders_api_arn" {
+  value       = duplocloud_aws_ecr_repository.orders_api.arn
+  description = "Full ARN of the repository."
+}
+output "ecr_orders_api_registry_id" {
+  value       = duplocloud_aws_ecr_repository.orders_api.registry_id
+  description = "The registry ID where the repository was created."
+}
+output "ecr_orders_api_repository_url" {
+  value       = duplocloud_aws_ecr_repository.orders_api.repository_url
+  description = "The DNS name of the load balancer."
+}
+output "emr_analytics_arn" {
+  value       = duplocloud_emr_cluster.analytics.arn
+  description = "The ARN of the EMR cluster."
+}
+output "emr_analytics_fullname" {
+  value       = duplocloud_emr_cluster.analytics.full_name
+  description = "The full name of the EMR cluster."
+}
+output "emr_analytics_job_flow_id" {
+  value       = duplocloud_emr_cluster.analytics.job_flow_id
+  description = "job flow id."
+}
+output "es_logs_arn" {
+  value       = duplocloud_aws_elasticsearch.logs.arn
+  description = "The ARN of the ElasticSearch instance."
+}
+output "es_logs_domain_id" {
+  value       = duplocloud_aws_elasticsearch.logs.domain_id
+  description = "The domain ID of the ElasticSearch instance."
+}
+output "es_logs_domain_name" {
+  value       = duplocloud_aws_elasticsearch.logs.domain_name
+  description = "The full name of the ElasticSearch instance."
+}
+output "es_logs_endpoints" {
+  value       = duplocloud_aws_elasticsearch.logs.endpoints
+  description = "The endpoints to use when connecting to the ElasticSearch instance."
+}
+output "es_logs_es_vpc_endpoint" {
+  value       = duplocloud_aws_elasticsearch.logs.endpoints["vpc"]
+  description = "ES VPC endpoint."
+}
+output "host_api_node_instance_id" {
+  value       = duplocloud_aws_host.api_node.instance_id
+  description = "The AWS EC2 instance ID of the host."
+}
+output "host_api_node_private_ip_address" {
+  value       = duplocloud_aws_host.api_node.private_ip_address
+  description = "The primary private IP address assigned to the host."
+}
+output "kafka_events_arn" {
+  value       = duplocloud_aws_kafka_cluster.events.arn
+  description = "The ARN of the Kafka cluster."
+}
+output "kafka_events_fullname" {
+  value       = duplocloud_aws_kafka_cluster.events.fullname
+  description = "The full name of the Kakfa cluster."
+}
+output "kafka_events_number_of_broker_nodes" {
+  value       = duplocloud_aws_kafka_cluster.events.number_of_broker_nodes
+  description = "The desired total number of broker nodes in the kafka cluster."
+}
+output "kafka_events_plaintext_bootstrap_broker_string" {
+  value       = duplocloud_aws_kafka_cluster.events.plaintext_bootstrap_broker_string
+  description = "The bootstrap broker connect string for plaintext (unencrypted) connections."
+}
+output "kafka_events_plaintext_zookeeper_connect_string" {
+  value       = duplocloud_aws_kafka_cluster.events.plaintext_zookeeper_connect_string
+  description = "The bootstrap broker connect string for plaintext (unencrypted) connections."
+}
+output "kafka_events_tls_bootstrap_broker_string" {
+  value       = duplocloud_aws_kafka_cluster.events.tls_bootstrap_broker_string
+  description = "The bootstrap broker connect string for TLS (encrypted) connections."
+}
+output "kafka_events_tls_zookeeper_connect_string" {
+  value       = duplocloud_aws_kafka_cluster.events.tls_zookeeper_connect_string
+  description = "he bootstrap broker connect string for plaintext (unencrypted) connections."
+}
+output "lb_web_arn" {
+  value       = duplocloud_aws_load_balancer.web.arn
+  description = "The ARN of the load balancer."
+}
+output "lb_web_dns_name" {
+  value       = duplocloud_aws_load_balancer.web.dns_name
+  description = "The DNS name of the load balancer."
+}
+output "lb_web_fullname" {
+  value       = duplocloud_aws_load_balancer.web.fullname
+  description = "The full name of the load balancer."
+}
+output "lf_report_worker_arn" {
+  value       = duplocloud_aws_lambda_function.report_worker.arn
+  description = "The ARN of the lambda function."
+}
+output "lf_report_worker_fullname" {
+  value       = duplocloud_aws_lambda_function.report_worker.fullname
+  description = "The full name of the lambda function."
+}
+output "lf_report_worker_version" {
+  value       = duplocloud_aws_lambda_function.report_worker.version
+  description = "The version of the lambda function."
+}
+output "lf_resize_images_arn" {
+  value       = duplocloud_aws_lambda_function.resize_images.arn
+  description = "The ARN of the lambda function."
+}
+output "lf_resize_images_fullname" {
+  value       = duplocloud_aws_lambda_function.resize_images.fullname
+  description = "The full name of the lambda function."
+}
+output "lf_resize_images_version" {
+  value       = duplocloud_aws_lambda_function.resize_images.version
+  description = "The version of the lambda function."
+}
+output "mwaa_airflow_arn" {
+  value       = duplocloud_aws_mwaa_environment.airflow.arn
+  description = "The ARN of the Managed Workflows Apache Airflow."
+}
+output "mwaa_airflow_webserver_url" {
+  value       = duplocloud_aws_mwaa_environment.airflow.webserver_url
+  description = "The webserver URL of the MWAA Environment."
+}
+output "rds_orders-reader_arn" {
+  value       = duplocloud_rds_read_replica.orders_reader.arn
+  description = "The ARN of the RDS instance."
+}
+output "rds_orders-reader_endpoint" {
+  value       = duplocloud_rds_read_replica.orders_reader.endpoint
+  description = "The endpoint of the RDS instance."
+}
+output "rds_orders-reader_fullname" {
+  value       = duplocloud_rds_read_replica.orders_reader.identifier
+  description = "The full name of the RDS instance."
+}
+output "rds_orders-reader_host" {
+  value       = duplocloud_rds_read_replica.orders_reader.host
+  description = "The DNS hostname of the RDS instance."
+}
+output "rds_orders-reader_port" {
+  value       = duplocloud_rds_read_replica.orders_reader.port
+  description = "The listening port of the RDS instance."
+}
+output "rds_orders_arn" {
+  value       = duplocloud_rds_instance.orders.arn
+  description = "The ARN of the RDS instance."
+}
+output "rds_orders_endpoint" {
+  value       = duplocloud_rds_instance.orders.endpoint
+  description = "The endpoint of the RDS instance."
+}
+output "rds_orders_fullname" {
+  value       = duplocloud_rds_instance.orders.identifier
+  description = "The full name of the RDS instance."
+}
+output "rds_orders_host" {
+  value       = duplocloud_rds_instance.orders.host
+  description = "The DNS hostname of the RDS instance."
+}
+output "rds_orders_port" {
+  value       = duplocloud_rds_instance.orders.port
+  description = "The listening port of the RDS instance."
+}
+output "rds_reports_arn" {
+  value       = duplocloud_rds_instance.reports.arn
+  description = "The ARN of the RDS instance."
+}
+output "rds_reports_endpoint" {
+  value       = duplocloud_rds_instance.reports.endpoint
+  description = "The endpoint of the RDS instance."
+}
+output "rds_reports_fullname" {
+  value       = duplocloud_rds_instance.reports.identifier
+  description = "The full name of the RDS instance."
+}
+output "rds_reports_host" {
+  value       = duplocloud_rds_instance.reports.host
+  description = "The DNS hostname of the RDS instance."
+}
+output "rds_reports_port" {
+  value       = duplocloud_rds_instance.reports.port
+  description = "The listening port of the RDS instance."
+}
+output "redis_sessions_arn" {
+  value       = duplocloud_ecache_instance.sessions.arn
+  description = "The ARN of the elasticache instance."
+}
+output "redis_sessions_endpoint" {
+  value       = duplocloud_ecache_instance.sessions.endpoint
+  description = "The endpoint of the elasticache instance."
+}
+output "redis_sessions_fullname" {
+  value       = duplocloud_ecache_instance.sessions.identifier
+  description = "The full name of the elasticache instance."
+}
+output "redis_sessions_host" {
+  value       = duplocloud_ecache_instance.sessions.host
+  description = "The DNS hostname of the elasticache instance."
+}
+output "redis_sessions_port" {
+  value       = duplocloud_ecache_instance.sessions.port
+  description = "The listening port of the elasticache instance."
+}
+output "s3_assets_arn" {
+  value       = duplocloud_s3_bucket.assets.arn
+  description = "The ARN of the S3 bucket."
+}
+output "s3_assets_fullname" {
+  value       = duplocloud_s3_bucket.assets.fullname
+  description = "The full name of the S3 bucket."
+}
+output "sns_alerts_arn" {
+  value       = duplocloud_aws_sns_topic.alerts.arn
+  description = "The ARN of the SNS topic."
+}
+output "sqs_orders_events_url" {
+  value       = duplocloud_aws_sqs_queue.orders_events.url
+  description = "The URL for the created Amazon SQS queue."
+}
+output "sqs_payments_url" {
+  value       = duplocloud_aws_sqs_queue.payments.url
+  description = "The URL for the created Amazon SQS queue."
+}
+output "timestream_dbmetrics_arn" {
+  value       = duplocloud_aws_timestreamwrite_database.metrics.arn
+  description = "The ARN that uniquely identifies this database."
+}
@@ -0,0 +1,23 @@
+terraform {
+  required_version = ">= v1.4.7"
+  required_providers {
+    duplocloud = {
+      source  = "duplocloud/duplocloud"
+      version = "~> 0.10.0"
+    }
+    random = {
+      source  = "hashicorp/random"
+      version = "~> 3.3.2"
+    }
+  }
+}
+provider "duplocloud" {
+
+}
+provider "aws" {
+  region = var.region
+
+}
+provider "random" {
+
+}
@@ -0,0 +1,19 @@
+resource "duplocloud_rds_read_replica" "orders_reader" {
+  tenant_id          = local.tenant_id
+  cluster_identifier = duplocloud_rds_instance.orders.cluster_identifier
+  name               = "orders-reader"
+  size               = var.rds_orders-reader_size
+  performance_insights {
+    enabled          = var.rds_orders-reader_performance_insights_enabled
+    retention_period = var.rds_orders-reader_performance_insights_retention_period
+    kms_key_id       = var.rds_orders-reader_performance_insights_kms_key_id
+  }
+  enhanced_monitoring = var.rds_orders-reader_enhanced_monitoring
+  v2_scaling_configuration {
+    min_capacity = var.rds_orders-reader_scaling_config_min_capacity
+    max_capacity = var.rds_orders-reader_scaling_config_max_capacity
+  }
+  lifecycle {
+    ignore_changes = ["engine_version"]
+  }
+}
@@ -0,0 +1,33 @@
+resource "random_password" "orders_password" {
+  length  = 16
+  special = false
+}
+resource "duplocloud_rds_instance" "orders" {
+  tenant_id                       = local.tenant_id
+  name                            = "orders"
+  engine                          = 9
+  engine_version                  = var.rds_orders_engine_version
+  size                            = var.rds_orders_size
+  master_username                 = var.rds_orders_master_username
+  master_password                 = random_password.orders_password.result
+  parameter_group_name            = "default.aurora-postgresql14"
+  store_details_in_secret_manager = true
+  encrypt_storage                 = var.rds_orders_encrypt_storage
+  kms_key_id                      = data.duplocloud_plan_kms.nonprod_kms.arn
+  enable_logging                  = true
+  multi_az                        = false
+  storage_type                    = "aurora"
+  v2_scaling_configuration {
+    min_capacity = var.rds_orders_scaling_config_min_capacity
+    max_capacity = var.rds_orders_scaling_config_max_capacity
+  }
+  performance_insights {
+    enabled          = var.rds_orders_performance_insights_enabled
+    retention_period = var.rds_orders_performance_insights_retention_period
+  }
+  enhanced_monitoring = var.rds_orders_enhanced_monitoring
+  enable_iam_auth     = true
+  lifecycle {
+    ignore_changes = ["engine_version"]
+  }
+}
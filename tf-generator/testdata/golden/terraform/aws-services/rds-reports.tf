@@ -0,0 +1,24 @@
+resource "random_password" "reports_password" {
+  length  = 16
+  special = false
+}
+resource "duplocloud_rds_instance" "reports" {
+  tenant_id                       = local.tenant_id
+  name                            = "reports"
+  engine                          = 0
+  engine_version                  = var.rds_reports_engine_version
+  size                            = var.rds_reports_size
+  master_username                 = var.rds_reports_master_username
+  master_password                 = random_password.reports_password.result
+  store_details_in_secret_manager = false
+  encrypt_storage                 = var.rds_reports_encrypt_storage
+  enable_logging                  = false
+  multi_az                        = true
+  storage_type                    = "gp3"
+  iops                            = 3000
+  enhanced_monitoring             = var.rds_reports_enhanced_monitoring
+  enable_iam_auth                 = false
+  lifecycle {
+    ignore_changes = ["engine_version"]
+  }
+}
@@ -0,0 +1,16 @@
+resource "duplocloud_ecache_instance" "sessions" {
+  tenant_id             = local.tenant_id
+  name                  = "sessions-${local.tenant_name}"
+  cache_type            = 0
+  replicas              = var.redis_sessions_replicas
+  size                  = var.redis_sessions_size
+  encryption_at_rest    = true
+  encryption_in_transit = true
+  auth_token            = "redis-auth-token-value"
+  engine_version        = var.redis_sessions_engine_version
+  lifecycle {
+    ignore_changes = ["engine_version"]
+  }
+  parameter_group_name = "default.redis7"
+  kms_key_id           = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_id
+}
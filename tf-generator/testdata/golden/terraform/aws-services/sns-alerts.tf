@@ -0,0 +1,5 @@
+resource "duplocloud_aws_sns_topic" "alerts" {
+  tenant_id  = local.tenant_id
+  name       = "alerts"
+  kms_key_id = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn
+}
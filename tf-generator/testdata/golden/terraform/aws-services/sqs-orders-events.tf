@@ -0,0 +1,7 @@
+resource "duplocloud_aws_sqs_queue" "orders_events" {
+  tenant_id                  = local.tenant_id
+  name                       = "orders-events"
+  message_retention_seconds  = 345600
+  visibility_timeout_seconds = 30
+  delay_seconds              = 0
+}
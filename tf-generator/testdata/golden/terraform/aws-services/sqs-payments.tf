@@ -0,0 +1,11 @@
+resource "duplocloud_aws_sqs_queue" "payments" {
+  tenant_id                   = local.tenant_id
+  name                        = "payments"
+  fifo_queue                  = true
+  message_retention_seconds   = 86400
+  visibility_timeout_seconds  = 60
+  content_based_deduplication = true
+  deduplication_scope         = "messageGroup"
+  fifo_throughput_limit       = "perMessageGroupId"
+  delay_seconds               = 0
+}
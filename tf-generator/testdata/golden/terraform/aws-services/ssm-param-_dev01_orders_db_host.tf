@@ -0,0 +1,7 @@
+resource "duplocloud_aws_ssm_parameter" "_dev01_orders_db_host" {
+  tenant_id   = local.tenant_id
+  name        = "/dev01/orders/db-host-${local.tenant_name}"
+  type        = "String"
+  value       = "duploorders.abc123.us-west-2.rds.amazonaws.com"
+  description = "Orders database host"
+}
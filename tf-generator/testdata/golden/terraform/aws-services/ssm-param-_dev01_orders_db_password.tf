@@ -0,0 +1,8 @@
+resource "duplocloud_aws_ssm_parameter" "_dev01_orders_db_password" {
+  tenant_id   = local.tenant_id
+  name        = "/dev01/orders/db-password-${local.tenant_name}"
+  type        = "SecureString"
+  value       = var.ssm_dev01_orders_db_password
+  description = "Orders database password"
+  key_id      = "alias/aws/ssm"
+}
@@ -0,0 +1,6 @@
+resource "duplocloud_aws_ssm_parameter" "orders_allowed_hosts" {
+  tenant_id = local.tenant_id
+  name      = "orders-allowed-hosts-${local.tenant_name}"
+  type      = "StringList"
+  value     = "api.example.com,admin.example.com"
+}
@@ -0,0 +1,29 @@
+resource "duplocloud_aws_timestreamwrite_database" "metrics" {
+  tenant_id  = local.tenant_id
+  name       = "metrics"
+  kms_key_id = data.duplocloud_tenant_aws_kms_key.tenant_kms.key_arn
+  tags {
+    key   = "team"
+    value = "data"
+  }
+}
+
+resource "duplocloud_aws_timestreamwrite_table" "metrics_requests" {
+  tenant_id     = local.tenant_id
+  name          = "requests"
+  database_name = duplocloud_aws_timestreamwrite_database.metrics.fullname
+  retention_properties {
+    magnetic_store_retention_period_in_days = 365
+    memory_store_retention_period_in_hours  = 24
+  }
+  magnetic_store_write_properties {
+    enable_magnetic_store_writes = true
+    magnetic_store_rejected_data_location {
+      s3_configuration {
+        bucket_name       = "duploservices-dev01-assets-123456789012"
+        encryption_option = "SSE_S3"
+        object_key_prefix = "timestream-rejected"
+      }
+    }
+  }
+}
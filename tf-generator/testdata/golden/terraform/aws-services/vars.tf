@@ -0,0 +1,189 @@
+variable "asg_workers_capacity" {
+  default = "t3.large"
+  type    = string
+}
+variable "asg_workers_image_id" {
+  default = "ami-0abcdef1234567890"
+  type    = string
+}
+variable "asg_workers_instance_count" {
+  default = 2
+  type    = number
+}
+variable "asg_workers_max_instance_count" {
+  default = 3
+  type    = number
+}
+variable "asg_workers_min_instance_count" {
+  default = 1
+  type    = number
+}
+variable "aws_lb_listener_rule_web_listener_80_rule_3_action_authenticate_oidc_client_secret" {
+  description = "Value of action.authenticate_oidc.client_secret of duplocloud_aws_lb_listener_rule.web_listener_80_rule_3."
+  type        = string
+  sensitive   = true
+}
+variable "byoh_build_box_private_key" {
+  description = "Value of private_key of duplocloud_byoh.build_box."
+  type        = string
+  sensitive   = true
+}
+variable "es_logs_elasticsearch_version" {
+  default = "OpenSearch_2.11"
+  type    = string
+}
+variable "es_logs_instance_type" {
+  default = "t3.small.search"
+  type    = string
+}
+variable "es_logs_selected_zone" {
+  default = 1
+  type    = number
+}
+variable "host_api_node_capacity" {
+  default = "t3.medium"
+  type    = string
+}
+variable "host_api_node_image_id" {
+  default = "ami-0abcdef1234567890"
+  type    = string
+}
+variable "kafka_events_instance_type" {
+  default = "kafka.t3.small"
+  type    = string
+}
+variable "kafka_events_kafka_version" {
+  default = "3.5.1"
+  type    = string
+}
+variable "kafka_events_storage_size" {
+  default = 100
+  type    = number
+}
+variable "lf_resize_images_s3_bucket" {
+  type = string
+}
+variable "lf_resize_images_s3_key" {
+  type = string
+}
+variable "plan_id" {
+  default     = "nonprod"
+  description = "The plan whose KMS keys are used by the tenant."
+  type        = string
+}
+variable "rds_orders-reader_enhanced_monitoring" {
+  default = 60
+  type    = number
+}
+variable "rds_orders-reader_performance_insights_enabled" {
+  default = true
+  type    = bool
+}
+variable "rds_orders-reader_performance_insights_kms_key_id" {
+  type = string
+}
+variable "rds_orders-reader_performance_insights_retention_period" {
+  default = 7
+  type    = number
+}
+variable "rds_orders-reader_scaling_config_max_capacity" {
+  default = 4.000000
+  type    = number
+}
+variable "rds_orders-reader_scaling_config_min_capacity" {
+  default = 0.500000
+  type    = number
+}
+variable "rds_orders-reader_size" {
+  default = "db.r6g.large"
+  type    = string
+}
+variable "rds_orders_encrypt_storage" {
+  default = true
+  type    = bool
+}
+variable "rds_orders_engine_version" {
+  default = "14.6"
+  type    = string
+}
+variable "rds_orders_enhanced_monitoring" {
+  default = 60
+  type    = number
+}
+variable "rds_orders_master_username" {
+  default = "orders_admin"
+  type    = string
+}
+variable "rds_orders_performance_insights_enabled" {
+  default = true
+  type    = bool
+}
+variable "rds_orders_performance_insights_retention_period" {
+  default = 7
+  type    = number
+}
+variable "rds_orders_scaling_config_max_capacity" {
+  default = 4.000000
+  type    = number
+}
+variable "rds_orders_scaling_config_min_capacity" {
+  default = 0.500000
+  type    = number
+}
+variable "rds_orders_size" {
+  default = "db.r6g.large"
+  type    = string
+}
+variable "rds_reports_encrypt_storage" {
+  default = true
+  type    = bool
+}
+variable "rds_reports_engine_version" {
+  default = "8.0.32"
+  type    = string
+}
+variable "rds_reports_enhanced_monitoring" {
+  default = 0
+  type    = number
+}
+variable "rds_reports_master_username" {
+  default = "reports"
+  type    = string
+}
+variable "rds_reports_size" {
+  default = "db.t3.medium"
+  type    = string
+}
+variable "redis_sessions_engine_version" {
+  default = "7.0"
+  type    = string
+}
+variable "redis_sessions_replicas" {
+  default = 2
+  type    = number
+}
+variable "redis_sessions_size" {
+  default = "cache.t3.small"
+  type    = string
+}
+variable "region" {
+  default = "us-west-2"
+  type    = string
+}
+variable "s3_assets_allow_public_access" {
+  default = false
+  type    = bool
+}
+variable "s3_assets_enable_access_logs" {
+  default = true
+  type    = bool
+}
+variable "s3_assets_enable_versioning" {
+  default = true
+  type    = bool
+}
+variable "ssm_dev01_orders_db_password" {
+  description = "Value of duplocloud_aws_ssm_parameter._dev01_orders_db_password."
+  type        = string
+  sensitive   = true
+}